@@ -0,0 +1,34 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"reveal/internal/admincli"
+	"reveal/internal/db"
+
+	"github.com/joho/godotenv"
+	"gorm.io/gorm/logger"
+)
+
+func main() {
+	// Load environment variables
+	if err := godotenv.Load("config/.env"); err != nil {
+		log.Println("No .env file found, using system environment variables")
+	}
+
+	args := os.Args[1:]
+	if !admincli.HelpRequested(args) {
+		// Connect to the same database as the server; the server owns migrations
+		db.Connect()
+		db.DB.Logger = logger.Default.LogMode(logger.Error)
+	}
+
+	err := admincli.New(os.Stdout, os.Stderr).Run(args)
+	if err == admincli.ErrUsage {
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal("revealadmin: ", err)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"expvar"
 	"log"
 	"os"
 	"strings"
@@ -32,6 +33,20 @@ func main() {
 	postHandler := handlers.NewPostHandler()
 	commentHandler := handlers.NewCommentHandler()
 	voteHandler := handlers.NewVoteHandler()
+	adminHandler := handlers.NewAdminHandler()
+	appealHandler := handlers.NewAppealHandler()
+	challengeHandler := handlers.NewChallengeHandler()
+
+	// Rate limit policies per kind of request (see RATE_LIMIT_POLICIES)
+	readLimit := middleware.RateLimitPolicy(middleware.PolicyRead)
+	postLimit := middleware.RateLimitPolicy(middleware.PolicyPost)
+	commentLimit := middleware.RateLimitPolicy(middleware.PolicyComment)
+	voteLimit := middleware.RateLimitPolicy(middleware.PolicyVote)
+	flagLimit := middleware.RateLimitPolicy(middleware.PolicyFlag)
+	appealLimit := middleware.RateLimitPolicy(middleware.PolicyAppeal)
+
+	// Proof-of-work for anonymous writes (see POW_ENABLED)
+	challenge := middleware.RequireChallenge()
 
 	// Setup router
 	router := gin.New()
@@ -52,23 +67,70 @@ func main() {
 	{
 		// Health and utility endpoints
 		api.GET("/health", postHandler.HealthCheck)
-		api.GET("/flag-reasons", postHandler.GetFlagReasons)
+		api.GET("/flag-reasons", readLimit, postHandler.GetFlagReasons)
+		api.GET("/challenge", readLimit, challengeHandler.GetChallenge)
 		
 		// Post endpoints
-		api.POST("/posts", middleware.RateLimit(), postHandler.CreatePost)
-		api.GET("/posts", postHandler.GetPosts)
-		api.POST("/posts/:id/flag", middleware.RateLimit(), postHandler.FlagPost)
+		api.POST("/posts", postLimit, challenge, postHandler.CreatePost)
+		api.GET("/posts", readLimit, postHandler.GetPosts)
+		api.POST("/posts/:id/flag", flagLimit, challenge, postHandler.FlagPost)
 		
 		// Comment endpoints
-		api.POST("/posts/:id/comments", middleware.RateLimit(), commentHandler.CreateComment)
-		api.GET("/posts/:id/comments", commentHandler.GetComments)
-		api.POST("/comments/:id/flag", middleware.RateLimit(), commentHandler.FlagComment)
+		api.POST("/posts/:id/comments", commentLimit, challenge, commentHandler.CreateComment)
+		api.GET("/posts/:id/comments", readLimit, commentHandler.GetComments)
+		api.POST("/comments/:id/flag", flagLimit, challenge, commentHandler.FlagComment)
 		
 		// Vote endpoints (for both posts and comments)
-		api.POST("/posts/:id/vote", middleware.RateLimit(), voteHandler.VoteOnPost)
-		api.GET("/posts/:id/votes", voteHandler.GetPostVotes)
-		api.POST("/comments/:id/vote", middleware.RateLimit(), voteHandler.VoteOnComment)
-		api.GET("/comments/:id/votes", voteHandler.GetCommentVotes)
+		// POST toggles (legacy); PUT sets an explicit state and DELETE clears it
+		api.POST("/posts/:id/vote", voteLimit, voteHandler.VoteOnPost)
+		api.PUT("/posts/:id/vote", voteLimit, voteHandler.SetPostVote)
+		api.DELETE("/posts/:id/vote", voteLimit, voteHandler.RemovePostVote)
+		api.GET("/posts/:id/votes", readLimit, voteHandler.GetPostVotes)
+		api.POST("/comments/:id/vote", voteLimit, voteHandler.VoteOnComment)
+		api.PUT("/comments/:id/vote", voteLimit, voteHandler.SetCommentVote)
+		api.DELETE("/comments/:id/vote", voteLimit, voteHandler.RemoveCommentVote)
+		api.GET("/comments/:id/votes", readLimit, voteHandler.GetCommentVotes)
+		
+		// Appeal endpoints (authorized by the management token issued on creation)
+		api.POST("/posts/:id/appeal", appealLimit, appealHandler.FilePostAppeal)
+		api.GET("/posts/:id/appeal", readLimit, appealHandler.GetPostAppeal)
+		api.POST("/comments/:id/appeal", appealLimit, appealHandler.FileCommentAppeal)
+		api.GET("/comments/:id/appeal", readLimit, appealHandler.GetCommentAppeal)
+	}
+
+	// Moderator endpoints
+	admin := api.Group("/admin", middleware.RequireModerator())
+	{
+		admin.GET("/queue", adminHandler.GetQueue)
+		admin.GET("/metrics", gin.WrapH(expvar.Handler()))
+		admin.GET("/posts/:id", adminHandler.GetPost)
+		admin.POST("/posts/:id/approve", adminHandler.ApprovePost)
+		admin.POST("/posts/:id/remove", adminHandler.RemovePost)
+		admin.PUT("/posts/:id/slow-mode", adminHandler.SetSlowMode)
+		admin.GET("/posts/:id/votes/history", voteHandler.GetPostVoteHistory)
+		admin.GET("/comments/:id", adminHandler.GetComment)
+		admin.POST("/comments/:id/approve", adminHandler.ApproveComment)
+		admin.POST("/comments/:id/remove", adminHandler.RemoveComment)
+		admin.POST("/bulk", adminHandler.Bulk)
+		admin.GET("/flag-thresholds", adminHandler.GetFlagThresholds)
+		admin.PUT("/flag-thresholds", adminHandler.SetFlagThreshold)
+		admin.DELETE("/flag-thresholds", adminHandler.ResetFlagThreshold)
+		admin.GET("/flag-reasons", adminHandler.GetFlagReasons)
+		admin.PUT("/flag-reasons/:key", adminHandler.SetFlagReason)
+		admin.GET("/duplicates", adminHandler.GetDuplicates)
+		admin.GET("/audit", adminHandler.GetAuditLog)
+		admin.GET("/audit/verify", adminHandler.VerifyAuditLog)
+		admin.GET("/bans", adminHandler.GetBans)
+		admin.POST("/bans", adminHandler.CreateBan)
+		admin.DELETE("/bans/:id", adminHandler.RevokeBan)
+		admin.GET("/filter-rules", adminHandler.GetFilterRules)
+		admin.POST("/filter-rules", adminHandler.CreateFilterRule)
+		admin.POST("/filter-rules/test", adminHandler.TestFilterRules)
+		admin.PUT("/filter-rules/:id", adminHandler.UpdateFilterRule)
+		admin.DELETE("/filter-rules/:id", adminHandler.DeleteFilterRule)
+		admin.GET("/appeals", adminHandler.GetAppeals)
+		admin.POST("/appeals/:id/grant", adminHandler.GrantAppeal)
+		admin.POST("/appeals/:id/deny", adminHandler.DenyAppeal)
 	}
 
 	// Fallback to serve React app for client-side routing
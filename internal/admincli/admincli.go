@@ -0,0 +1,400 @@
+// Package admincli implements revealadmin, the command-line moderation tool.
+// Every command goes through the same services as the HTTP moderator API, so
+// actions are validated, audited and fed back into trust exactly the same way.
+package admincli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"reveal/internal/models"
+	"reveal/internal/services"
+
+	"github.com/google/uuid"
+)
+
+// ErrUsage is returned for malformed command lines, after the usage has been printed
+var ErrUsage = errors.New("usage error")
+
+const usage = `Usage: revealadmin <command> [flags] [arguments]
+
+Commands:
+  queue   [-type post|comment] [-limit N] [-json]     List the review queue
+  show    [-json] post|comment <id>                   Show a post or comment with its flags
+  approve [-reason R] [-as NAME] post|comment <id>    Unflag content and dismiss its flags
+  remove  [-reason R] [-as NAME] post|comment <id>    Remove content and uphold its flags
+  ban     [-mode ban|shadow] [-hours H] [-reason R] [-as NAME] hash|post|comment <value>
+                                                      Ban an IP hash or the author of a post or comment
+  bans    [-all] [-json]                              List bans
+  unban   [-reason R] [-as NAME] <ban-id>             Revoke a ban
+  export  [-format csv|json] [-o FILE] [-since T] [-until T] queue|audit|bans
+                                                      Export a report (times in RFC 3339)
+
+Moderator actions are recorded under -as, REVEAL_MODERATOR or cli:$USER.
+`
+
+// CLI runs revealadmin commands against the services layer
+type CLI struct {
+	moderation *services.ModerationService
+	bans       *services.BanService
+	audit      *services.AuditService
+	out        io.Writer
+	errOut     io.Writer
+}
+
+func New(out, errOut io.Writer) *CLI {
+	return &CLI{
+		moderation: services.NewModerationService(),
+		bans:       services.NewBanService(),
+		audit:      services.NewAuditService(),
+		out:        out,
+		errOut:     errOut,
+	}
+}
+
+// HelpRequested reports whether args only ask for the usage, which needs no database
+func HelpRequested(args []string) bool {
+	return len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help"
+}
+
+// Run executes one command. args excludes the program name.
+func (c *CLI) Run(args []string) error {
+	if HelpRequested(args) {
+		fmt.Fprint(c.out, usage)
+		return nil
+	}
+
+	command, args := args[0], args[1:]
+	switch command {
+	case "queue":
+		return c.queue(args)
+	case "show":
+		return c.show(args)
+	case "approve":
+		return c.moderate(args, models.ModerationApprove)
+	case "remove":
+		return c.moderate(args, models.ModerationRemove)
+	case "ban":
+		return c.ban(args)
+	case "bans":
+		return c.listBans(args)
+	case "unban":
+		return c.unban(args)
+	case "export":
+		return c.export(args)
+	default:
+		return c.usageError("unknown command %q", command)
+	}
+}
+
+func (c *CLI) usageError(format string, a ...interface{}) error {
+	fmt.Fprintf(c.errOut, "revealadmin: "+format+"\n\n", a...)
+	fmt.Fprint(c.errOut, usage)
+	return ErrUsage
+}
+
+func (c *CLI) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.errOut)
+	fs.Usage = func() { fmt.Fprint(c.errOut, usage) }
+	return fs
+}
+
+// parseArgs parses flags wherever they appear and returns the positional arguments
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, ErrUsage
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// actor names the moderator an action is recorded under
+func actor(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if name := os.Getenv("REVEAL_MODERATOR"); name != "" {
+		return name
+	}
+	if user := os.Getenv("USER"); user != "" {
+		return "cli:" + user
+	}
+	return "cli"
+}
+
+// contentTarget parses "post|comment <id>"
+func (c *CLI) contentTarget(positional []string) (string, uuid.UUID, error) {
+	if len(positional) != 2 {
+		return "", uuid.Nil, c.usageError("expected post|comment <id>")
+	}
+	contentType := positional[0]
+	if contentType != models.FlagTypePost && contentType != models.FlagTypeComment {
+		return "", uuid.Nil, c.usageError("invalid type %q, must be post or comment", contentType)
+	}
+	id, err := uuid.Parse(positional[1])
+	if err != nil {
+		return "", uuid.Nil, c.usageError("invalid %s ID %q", contentType, positional[1])
+	}
+	return contentType, id, nil
+}
+
+func (c *CLI) writeJSON(v interface{}) error {
+	enc := json.NewEncoder(c.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// excerpt shortens text to a single line for tables
+func excerpt(text string, max int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > max {
+		return string(runes[:max-1]) + "…"
+	}
+	return text
+}
+
+func (c *CLI) queue(args []string) error {
+	fs := c.flagSet("queue")
+	contentType := fs.String("type", "", "post or comment")
+	limit := fs.Int("limit", 50, "maximum items per type")
+	asJSON := fs.Bool("json", false, "print JSON")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *contentType != "" && *contentType != models.FlagTypePost && *contentType != models.FlagTypeComment {
+		return c.usageError("invalid type %q, must be post or comment", *contentType)
+	}
+
+	items, err := c.moderation.GetQueue(*contentType, *limit)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		if items == nil {
+			items = []services.QueueItem{}
+		}
+		return c.writeJSON(items)
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tID\tSTATUS\tFLAGGED\tOPEN FLAGS\tCREATED\tTEXT")
+	for _, item := range items {
+		text := item.Content
+		if item.Title != "" {
+			text = item.Title
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%d\t%s\t%s\n", item.Type, item.ID, item.Status, item.Flagged,
+			item.OpenFlags, item.CreatedAt.Format(time.RFC3339), excerpt(text, 60))
+	}
+	return w.Flush()
+}
+
+func (c *CLI) show(args []string) error {
+	fs := c.flagSet("show")
+	asJSON := fs.Bool("json", false, "print JSON")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	contentType, id, err := c.contentTarget(positional)
+	if err != nil {
+		return err
+	}
+
+	var item *services.QueueItem
+	if contentType == models.FlagTypePost {
+		item, err = c.moderation.GetPost(id)
+	} else {
+		item, err = c.moderation.GetComment(id)
+	}
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return c.writeJSON(item)
+	}
+
+	fmt.Fprintf(c.out, "%s %s\n", item.Type, item.ID)
+	if item.PostID != nil {
+		fmt.Fprintf(c.out, "Post:       %s\n", item.PostID)
+	}
+	fmt.Fprintf(c.out, "Status:     %s (flagged: %t)\n", item.Status, item.Flagged)
+	fmt.Fprintf(c.out, "Author:     %s\n", item.AuthorHash)
+	fmt.Fprintf(c.out, "Created:    %s\n", item.CreatedAt.Format(time.RFC3339))
+	if item.Title != "" {
+		fmt.Fprintf(c.out, "Title:      %s\n", item.Title)
+	}
+	fmt.Fprintf(c.out, "Content:\n%s\n", item.Content)
+	if item.Appeal != nil {
+		fmt.Fprintf(c.out, "Appeal:     %s: %s\n", item.Appeal.Status, excerpt(item.Appeal.Statement, 80))
+	}
+
+	fmt.Fprintf(c.out, "\nFlags (%d open):\n", item.OpenFlags)
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REASON\tWEIGHT\tRESOLUTION\tCREATED\tDETAILS")
+	for _, flag := range item.Flags {
+		weight := "-"
+		if flag.Weight != nil {
+			weight = strconv.FormatFloat(*flag.Weight, 'f', 2, 64)
+		}
+		resolution := flag.Resolution
+		if resolution == models.FlagResolutionPending {
+			resolution = "pending"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", flag.Reason, weight, resolution,
+			flag.CreatedAt.Format(time.RFC3339), excerpt(flag.Details, 60))
+	}
+	return w.Flush()
+}
+
+func (c *CLI) moderate(args []string, action string) error {
+	fs := c.flagSet(action)
+	reason := fs.String("reason", "", "reason recorded in the audit log")
+	as := fs.String("as", "", "moderator name")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	contentType, id, err := c.contentTarget(positional)
+	if err != nil {
+		return err
+	}
+
+	moderator := actor(*as)
+	switch {
+	case contentType == models.FlagTypePost && action == models.ModerationApprove:
+		err = c.moderation.ApprovePost(id, moderator, *reason)
+	case contentType == models.FlagTypePost:
+		err = c.moderation.RemovePost(id, moderator, *reason)
+	case action == models.ModerationApprove:
+		err = c.moderation.ApproveComment(id, moderator, *reason)
+	default:
+		err = c.moderation.RemoveComment(id, moderator, *reason)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.out, "%s %s: %s\n", contentType, id, action)
+	return nil
+}
+
+func (c *CLI) ban(args []string) error {
+	fs := c.flagSet("ban")
+	mode := fs.String("mode", models.BanModeBan, "ban or shadow")
+	hours := fs.Float64("hours", 0, "ban duration in hours (0 is permanent)")
+	reason := fs.String("reason", "", "reason recorded with the ban")
+	as := fs.String("as", "", "moderator name")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return c.usageError("expected hash|post|comment <value>")
+	}
+
+	var target services.BanTarget
+	switch positional[0] {
+	case "hash":
+		target.IPHash = positional[1]
+	case models.FlagTypePost, models.FlagTypeComment:
+		id, err := uuid.Parse(positional[1])
+		if err != nil {
+			return c.usageError("invalid %s ID %q", positional[0], positional[1])
+		}
+		if positional[0] == models.FlagTypePost {
+			target.PostID = &id
+		} else {
+			target.CommentID = &id
+		}
+	default:
+		return c.usageError("invalid ban target %q, must be hash, post or comment", positional[0])
+	}
+
+	ban, err := c.bans.CreateBan(target, *mode, *reason, time.Duration(*hours*float64(time.Hour)), actor(*as))
+	if err != nil {
+		return err
+	}
+
+	expires := "never"
+	if ban.ExpiresAt != nil {
+		expires = ban.ExpiresAt.Format(time.RFC3339)
+	}
+	fmt.Fprintf(c.out, "ban %s: %s %s (expires %s)\n", ban.ID, ban.Mode, ban.IPHash, expires)
+	return nil
+}
+
+func (c *CLI) listBans(args []string) error {
+	fs := c.flagSet("bans")
+	all := fs.Bool("all", false, "include expired and revoked bans")
+	asJSON := fs.Bool("json", false, "print JSON")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	bans, err := c.bans.ListBans(*all)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		if bans == nil {
+			bans = []models.Ban{}
+		}
+		return c.writeJSON(bans)
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tMODE\tIP HASH\tBY\tCREATED\tEXPIRES\tREVOKED\tREASON")
+	for _, ban := range bans {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", ban.ID, ban.Mode, ban.IPHash, ban.CreatedBy,
+			ban.CreatedAt.Format(time.RFC3339), formatTime(ban.ExpiresAt, "-"), formatTime(ban.RevokedAt, "-"), excerpt(ban.Reason, 40))
+	}
+	return w.Flush()
+}
+
+func (c *CLI) unban(args []string) error {
+	fs := c.flagSet("unban")
+	reason := fs.String("reason", "", "reason recorded in the audit log")
+	as := fs.String("as", "", "moderator name")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return c.usageError("expected <ban-id>")
+	}
+	banID, err := uuid.Parse(positional[0])
+	if err != nil {
+		return c.usageError("invalid ban ID %q", positional[0])
+	}
+
+	if err := c.bans.RevokeBan(banID, actor(*as), *reason); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "ban %s: revoked\n", banID)
+	return nil
+}
+
+// formatTime formats an optional time, printing missing when it is unset
+func formatTime(t *time.Time, missing string) string {
+	if t == nil {
+		return missing
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,196 @@
+package admincli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"reveal/internal/models"
+	"reveal/internal/services"
+)
+
+// export writes a report of the review queue, the audit log or the bans as CSV or JSON
+func (c *CLI) export(args []string) error {
+	fs := c.flagSet("export")
+	format := fs.String("format", "csv", "csv or json")
+	output := fs.String("o", "", "output file (default stdout)")
+	since := fs.String("since", "", "audit entries from this time (RFC 3339)")
+	until := fs.String("until", "", "audit entries before this time (RFC 3339)")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return c.usageError("expected queue, audit or bans")
+	}
+	if *format != "csv" && *format != "json" {
+		return c.usageError("invalid format %q, must be csv or json", *format)
+	}
+
+	var filter services.AuditFilter
+	if filter.Since, err = parseTime(*since); err != nil {
+		return c.usageError("invalid -since %q", *since)
+	}
+	if filter.Until, err = parseTime(*until); err != nil {
+		return c.usageError("invalid -until %q", *until)
+	}
+
+	var header []string
+	var rows [][]string
+	var records interface{}
+	switch positional[0] {
+	case "queue":
+		items, err := c.queueItems()
+		if err != nil {
+			return err
+		}
+		records, header, rows = items, queueHeader, queueRows(items)
+	case "audit":
+		entries, err := c.auditEntries(filter)
+		if err != nil {
+			return err
+		}
+		records, header, rows = entries, auditHeader, auditRows(entries)
+	case "bans":
+		bans, err := c.bans.ListBans(true)
+		if err != nil {
+			return err
+		}
+		if bans == nil {
+			bans = []models.Ban{}
+		}
+		records, header, rows = bans, banHeader, banRows(bans)
+	default:
+		return c.usageError("unknown report %q, must be queue, audit or bans", positional[0])
+	}
+
+	out := c.out
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	}
+	return writeCSV(out, header, rows)
+}
+
+// auditEntries pages through the audit log, newest first
+func (c *CLI) auditEntries(filter services.AuditFilter) ([]models.AuditEntry, error) {
+	const pageSize = 500
+
+	entries := []models.AuditEntry{}
+	filter.Limit = pageSize
+	for {
+		page, err := c.audit.List(filter)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, page...)
+		if len(page) < pageSize {
+			return entries, nil
+		}
+		filter.BeforeID = page[len(page)-1].ID
+	}
+}
+
+// queueItems pages through the whole review queue, newest first
+func (c *CLI) queueItems() ([]services.QueueItem, error) {
+	const pageSize = 500
+
+	items := []services.QueueItem{}
+	var after *services.QueueCursor
+	for {
+		page, err := c.moderation.GetQueuePage("", after, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page...)
+		if len(page) < pageSize {
+			return items, nil
+		}
+		last := page[len(page)-1]
+		after = &services.QueueCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+}
+
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func writeCSV(out io.Writer, header []string, rows [][]string) error {
+	w := csv.NewWriter(out)
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return err
+	}
+	return w.Error()
+}
+
+// The queue report has one row per flag, so reports can be tallied by reason
+var queueHeader = []string{"type", "id", "post_id", "status", "flagged", "open_flags", "created_at",
+	"flag_reason", "flag_details", "flag_weight", "flag_resolution", "flag_created_at"}
+
+func queueRows(items []services.QueueItem) [][]string {
+	var rows [][]string
+	for _, item := range items {
+		postID := ""
+		if item.PostID != nil {
+			postID = item.PostID.String()
+		}
+		base := []string{item.Type, item.ID.String(), postID, item.Status, strconv.FormatBool(item.Flagged),
+			strconv.FormatInt(item.OpenFlags, 10), item.CreatedAt.Format(time.RFC3339)}
+
+		if len(item.Flags) == 0 {
+			rows = append(rows, append(base, "", "", "", "", ""))
+			continue
+		}
+		for _, flag := range item.Flags {
+			weight := ""
+			if flag.Weight != nil {
+				weight = strconv.FormatFloat(*flag.Weight, 'f', 2, 64)
+			}
+			row := append(append([]string{}, base...), flag.Reason, flag.Details, weight, flag.Resolution,
+				flag.CreatedAt.Format(time.RFC3339))
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+var auditHeader = []string{"id", "created_at", "actor", "action", "target_type", "target_id", "reason", "details", "hash"}
+
+func auditRows(entries []models.AuditEntry) [][]string {
+	rows := make([][]string, 0, len(entries))
+	for _, e := range entries {
+		rows = append(rows, []string{strconv.FormatUint(e.ID, 10), e.CreatedAt.Format(time.RFC3339Nano), e.Actor,
+			e.Action, e.TargetType, e.TargetID, e.Reason, e.Details, e.Hash})
+	}
+	return rows
+}
+
+var banHeader = []string{"id", "mode", "ip_hash", "reason", "created_by", "created_at", "expires_at", "revoked_by", "revoked_at"}
+
+func banRows(bans []models.Ban) [][]string {
+	rows := make([][]string, 0, len(bans))
+	for _, b := range bans {
+		rows = append(rows, []string{b.ID.String(), b.Mode, b.IPHash, b.Reason, b.CreatedBy,
+			b.CreatedAt.Format(time.RFC3339), formatTime(b.ExpiresAt, ""), b.RevokedBy, formatTime(b.RevokedAt, "")})
+	}
+	return rows
+}
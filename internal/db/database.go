@@ -41,12 +41,78 @@ func Connect() {
 	log.Println("Connected to PostgreSQL database")
 }
 
+// Models returns every model Migrate creates a table for
+func Models() []interface{} {
+	return []interface{}{&models.Post{}, &models.Flag{}, &models.Comment{}, &models.Vote{}, &models.PostVoteRollup{}, &models.Identity{}, &models.FlagThreshold{}, &models.AuditEntry{}, &models.Ban{}, &models.FilterRule{}, &models.Appeal{}, &models.FlagReason{}, &models.RateLimitBucket{}, &models.UsedChallenge{}}
+}
+
 func Migrate() {
-	err := DB.AutoMigrate(&models.Post{}, &models.Flag{}, &models.Comment{}, &models.Vote{})
+	hadRollups := DB.Migrator().HasTable(&models.PostVoteRollup{})
+	hadFingerprintBands := DB.Migrator().HasColumn(&models.Post{}, "fingerprint_band0")
+
+	err := DB.AutoMigrate(Models()...)
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
+
+	// Backfill the hourly vote rollup from existing votes the first time it is created
+	if !hadRollups {
+		err = DB.Exec(`
+			INSERT INTO post_vote_rollups (post_id, bucket_start, upvotes, downvotes)
+			SELECT post_id,
+				date_trunc('hour', created_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC',
+				COUNT(*) FILTER (WHERE vote_type = 'upvote'),
+				COUNT(*) FILTER (WHERE vote_type = 'downvote')
+			FROM votes
+			WHERE post_id IS NOT NULL
+			GROUP BY 1, 2
+		`).Error
+		if err != nil {
+			log.Printf("Warning: Failed to backfill vote rollups: %v", err)
+		} else {
+			log.Println("Backfilled post vote rollups")
+		}
+	}
 	
+	// Split existing fingerprints into the indexed bands used for duplicate lookups.
+	// The plain fingerprint index cannot answer distance queries.
+	if !hadFingerprintBands {
+		err = DB.Exec(`
+			UPDATE posts SET
+				fingerprint_band0 = fingerprint & 65535,
+				fingerprint_band1 = (fingerprint >> 16) & 65535,
+				fingerprint_band2 = (fingerprint >> 32) & 65535,
+				fingerprint_band3 = (fingerprint >> 48) & 65535
+			WHERE fingerprint <> 0
+		`).Error
+		if err != nil {
+			log.Printf("Warning: Failed to backfill fingerprint bands: %v", err)
+		}
+		if DB.Migrator().HasIndex(&models.Post{}, "idx_posts_fingerprint") {
+			if err = DB.Migrator().DropIndex(&models.Post{}, "idx_posts_fingerprint"); err != nil {
+				log.Printf("Warning: Failed to drop fingerprint index: %v", err)
+			}
+		}
+	}
+
+	// Make the audit log append-only at the database level
+	for _, stmt := range []string{
+		`CREATE OR REPLACE FUNCTION audit_entries_append_only() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'audit_entries is append-only';
+		END;
+		$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS audit_entries_append_only ON audit_entries`,
+		`CREATE TRIGGER audit_entries_append_only
+			BEFORE UPDATE OR DELETE ON audit_entries
+			FOR EACH ROW EXECUTE FUNCTION audit_entries_append_only()`,
+	} {
+		if err = DB.Exec(stmt).Error; err != nil {
+			log.Printf("Warning: Failed to protect audit log: %v", err)
+			break
+		}
+	}
+
 	// Drop old user_flags table if it exists
 	if DB.Migrator().HasTable("user_flags") {
 		log.Println("Dropping old user_flags table...")
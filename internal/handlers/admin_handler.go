@@ -0,0 +1,847 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"reveal/internal/models"
+	"reveal/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AdminHandler struct {
+	moderationService *services.ModerationService
+	flagPolicyService *services.FlagPolicyService
+	auditService      *services.AuditService
+	banService        *services.BanService
+	contentFilter     *services.ContentFilter
+	appealService     *services.AppealService
+	flagReasonService *services.FlagReasonService
+	duplicates        *services.DuplicateDetector
+	slowModeService   *services.SlowModeService
+}
+
+func NewAdminHandler() *AdminHandler {
+	return &AdminHandler{
+		moderationService: services.NewModerationService(),
+		flagPolicyService: services.NewFlagPolicyService(),
+		auditService:      services.NewAuditService(),
+		banService:        services.NewBanService(),
+		contentFilter:     services.NewContentFilter(),
+		appealService:     services.NewAppealService(),
+		flagReasonService: services.NewFlagReasonService(),
+		duplicates:        services.NewDuplicateDetector(),
+		slowModeService:   services.NewSlowModeService(),
+	}
+}
+
+type ModerationActionRequest struct {
+	Reason string `json:"reason"`
+}
+
+type BulkModerationRequest struct {
+	Action string                      `json:"action" binding:"required"`
+	Reason string                      `json:"reason"`
+	Items  []services.ModerationTarget `json:"items" binding:"required,min=1,max=200"`
+}
+
+// GET /api/admin/queue - List flagged and heavily reported content (?type=post|comment&limit=N)
+func (h *AdminHandler) GetQueue(c *gin.Context) {
+	contentType := c.Query("type")
+	if contentType != "" && contentType != models.FlagTypePost && contentType != models.FlagTypeComment {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid type. Must be 'post' or 'comment'",
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	items, err := h.moderationService.GetQueue(contentType, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch review queue",
+		})
+		return
+	}
+
+	if items == nil {
+		items = []services.QueueItem{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+	})
+}
+
+// GET /api/admin/posts/{id} - Show a post with its flags
+func (h *AdminHandler) GetPost(c *gin.Context) {
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid post ID format",
+		})
+		return
+	}
+
+	item, err := h.moderationService.GetPost(postID)
+	if err != nil {
+		if err.Error() == "post not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Post not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch post",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+// GET /api/admin/comments/{id} - Show a comment with its flags
+func (h *AdminHandler) GetComment(c *gin.Context) {
+	commentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid comment ID format",
+		})
+		return
+	}
+
+	item, err := h.moderationService.GetComment(commentID)
+	if err != nil {
+		if err.Error() == "comment not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Comment not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch comment",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+// POST /api/admin/posts/{id}/approve - Unflag a post and dismiss its flags
+func (h *AdminHandler) ApprovePost(c *gin.Context) {
+	h.moderate(c, models.FlagTypePost, models.ModerationApprove)
+}
+
+// POST /api/admin/posts/{id}/remove - Remove a post and uphold its flags
+func (h *AdminHandler) RemovePost(c *gin.Context) {
+	h.moderate(c, models.FlagTypePost, models.ModerationRemove)
+}
+
+// POST /api/admin/comments/{id}/approve - Unflag a comment and dismiss its flags
+func (h *AdminHandler) ApproveComment(c *gin.Context) {
+	h.moderate(c, models.FlagTypeComment, models.ModerationApprove)
+}
+
+// POST /api/admin/comments/{id}/remove - Remove a comment and uphold its flags
+func (h *AdminHandler) RemoveComment(c *gin.Context) {
+	h.moderate(c, models.FlagTypeComment, models.ModerationRemove)
+}
+
+// POST /api/admin/bulk - Approve or remove many posts and comments at once
+func (h *AdminHandler) Bulk(c *gin.Context) {
+	var req BulkModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	results, err := h.moderationService.Bulk(req.Action, req.Items, c.GetString("moderator"), req.Reason)
+	if err != nil {
+		if err.Error() == "invalid action" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid action. Must be 'approve' or 'remove'",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to apply bulk action",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results": results,
+	})
+}
+
+type FlagThresholdRequest struct {
+	ContentType string  `json:"content_type" binding:"required"`
+	Reason      string  `json:"reason"` // Empty for the weighted total of all flags
+	HideAt      float64 `json:"hide_at"`
+	HoldAt      float64 `json:"hold_at"`
+}
+
+// GET /api/admin/flag-thresholds - Effective hide and hold thresholds per content type
+func (h *AdminHandler) GetFlagThresholds(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"post":    h.flagPolicyService.Thresholds(models.FlagTypePost),
+		"comment": h.flagPolicyService.Thresholds(models.FlagTypeComment),
+	})
+}
+
+// PUT /api/admin/flag-thresholds - Override a threshold for a content type and reason
+func (h *AdminHandler) SetFlagThreshold(c *gin.Context) {
+	var req FlagThresholdRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	threshold, err := h.flagPolicyService.SetThreshold(req.ContentType, req.Reason, req.HideAt, req.HoldAt, c.GetString("moderator"))
+	if err != nil {
+		if err.Error() == "invalid content type" || err.Error() == "invalid threshold" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid threshold. Content type must be 'post' or 'comment' and values non-negative",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update threshold",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, threshold)
+}
+
+// DELETE /api/admin/flag-thresholds?content_type=post&reason=spam - Restore the default threshold
+func (h *AdminHandler) ResetFlagThreshold(c *gin.Context) {
+	err := h.flagPolicyService.ResetThreshold(c.Query("content_type"), c.Query("reason"), c.GetString("moderator"))
+	if err != nil {
+		if err.Error() == "threshold not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "No override for this content type and reason",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to reset threshold",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Threshold reset to default",
+	})
+}
+
+type FlagReasonRequest struct {
+	Labels          map[string]string `json:"labels" binding:"required"` // Must include "en"
+	Severity        int               `json:"severity" binding:"required"`
+	RequiresDetails bool              `json:"requires_details"`
+	Weight          *float64          `json:"weight"`  // Defaults to 1
+	Enabled         *bool             `json:"enabled"` // Defaults to true
+}
+
+// GET /api/admin/flag-reasons - The flag reason taxonomy, including disabled reasons
+func (h *AdminHandler) GetFlagReasons(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"reasons": h.flagReasonService.List(true),
+	})
+}
+
+// PUT /api/admin/flag-reasons/{key} - Add or replace a flag reason; disable it to retire it
+func (h *AdminHandler) SetFlagReason(c *gin.Context) {
+	var req FlagReasonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	reason := &models.FlagReason{
+		Key:             c.Param("key"),
+		Labels:          req.Labels,
+		Severity:        req.Severity,
+		RequiresDetails: req.RequiresDetails,
+		Weight:          1,
+		Enabled:         true,
+	}
+	if req.Weight != nil {
+		reason.Weight = *req.Weight
+	}
+	if req.Enabled != nil {
+		reason.Enabled = *req.Enabled
+	}
+
+	saved, err := h.flagReasonService.SetReason(reason, c.GetString("moderator"))
+	if err != nil {
+		switch err.Error() {
+		case "invalid flag reason key":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid key. Use up to 50 lowercase letters, digits and underscores",
+			})
+		case "english label required":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "An English ('en') label is required",
+			})
+		case "invalid severity", "invalid weight":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Severity must be between 1 and 5 and weight non-negative",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to save flag reason",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, saved)
+}
+
+// GET /api/admin/duplicates - Near-duplicate post clusters (?days=7&limit=50)
+func (h *AdminHandler) GetDuplicates(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if err != nil || days <= 0 || days > 90 {
+		days = 7
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	clusters, err := h.duplicates.Clusters(time.Now().AddDate(0, 0, -days), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch duplicate clusters",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"clusters": clusters,
+	})
+}
+
+type SlowModeRequest struct {
+	Enabled bool    `json:"enabled"`
+	Seconds int     `json:"seconds"` // Minimum time between comments per identity
+	Minutes float64 `json:"minutes"` // How long slow mode lasts
+	Reason  string  `json:"reason"`
+}
+
+// PUT /api/admin/posts/{id}/slow-mode - Switch slow mode on or off for a thread
+func (h *AdminHandler) SetSlowMode(c *gin.Context) {
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid post ID format",
+		})
+		return
+	}
+
+	var req SlowModeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	post, err := h.slowModeService.SetSlowMode(postID, req.Enabled, time.Duration(req.Seconds)*time.Second,
+		time.Duration(req.Minutes*float64(time.Minute)), c.GetString("moderator"), req.Reason)
+	if err != nil {
+		switch err.Error() {
+		case "invalid slow mode":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Slow mode needs at least 1 second between comments and a positive duration in minutes",
+			})
+		case "post not found":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Post not found",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to update slow mode",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"post_id":           post.ID,
+		"slow_mode_until":   post.SlowModeUntil,
+		"slow_mode_seconds": post.SlowModeSeconds,
+	})
+}
+
+// GET /api/admin/audit - Moderation audit log, newest first
+// (?actor=&action=&target_type=&target_id=&since=&until=&before=&limit=)
+func (h *AdminHandler) GetAuditLog(c *gin.Context) {
+	filter := services.AuditFilter{
+		Actor:      c.Query("actor"),
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+		TargetID:   c.Query("target_id"),
+	}
+
+	var err error
+	if since := c.Query("since"); since != "" {
+		if filter.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid since. Must be an RFC 3339 timestamp",
+			})
+			return
+		}
+	}
+	if until := c.Query("until"); until != "" {
+		if filter.Until, err = time.Parse(time.RFC3339, until); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid until. Must be an RFC 3339 timestamp",
+			})
+			return
+		}
+	}
+	if before := c.Query("before"); before != "" {
+		if filter.BeforeID, err = strconv.ParseUint(before, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid before. Must be an entry ID",
+			})
+			return
+		}
+	}
+	if limit, err := strconv.Atoi(c.DefaultQuery("limit", "100")); err == nil {
+		filter.Limit = limit
+	}
+
+	entries, err := h.auditService.List(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch audit log",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+	})
+}
+
+// GET /api/admin/audit/verify - Check the audit log hash chain for tampering
+func (h *AdminHandler) VerifyAuditLog(c *gin.Context) {
+	result, err := h.auditService.Verify()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to verify audit log",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+type CreateBanRequest struct {
+	services.BanTarget
+	Mode          string  `json:"mode" binding:"required"`
+	Reason        string  `json:"reason"`
+	DurationHours float64 `json:"duration_hours"` // 0 for a permanent ban
+}
+
+// GET /api/admin/bans - Active bans (?all=true includes expired and revoked ones)
+func (h *AdminHandler) GetBans(c *gin.Context) {
+	bans, err := h.banService.ListBans(c.Query("all") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch bans",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"bans": bans,
+	})
+}
+
+// POST /api/admin/bans - Ban or shadowban an IP hash or the author of a post or comment
+func (h *AdminHandler) CreateBan(c *gin.Context) {
+	var req CreateBanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	duration := time.Duration(req.DurationHours * float64(time.Hour))
+	ban, err := h.banService.CreateBan(req.BanTarget, req.Mode, req.Reason, duration, c.GetString("moderator"))
+	if err != nil {
+		switch err.Error() {
+		case "invalid ban mode":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid mode. Must be 'ban' or 'shadow'",
+			})
+		case "invalid ban duration":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Duration cannot be negative",
+			})
+		case "invalid ban target":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "One of ip_hash, post_id or comment_id is required",
+			})
+		case "post not found", "comment not found":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Not found",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to create ban",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, ban)
+}
+
+// DELETE /api/admin/bans/{id} - Revoke a ban
+func (h *AdminHandler) RevokeBan(c *gin.Context) {
+	banID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid ban ID format",
+		})
+		return
+	}
+
+	// The body is optional; it only carries the moderator's reason
+	var req ModerationActionRequest
+	_ = c.ShouldBindJSON(&req)
+
+	if err := h.banService.RevokeBan(banID, c.GetString("moderator"), req.Reason); err != nil {
+		if err.Error() == "ban not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Ban not found or already revoked",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to revoke ban",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Ban revoked",
+	})
+}
+
+type FilterRuleRequest struct {
+	Name      string `json:"name" binding:"required,max=100"`
+	MatchType string `json:"match_type" binding:"required"`
+	Pattern   string `json:"pattern" binding:"required"`
+	Field     string `json:"field"`
+	Action    string `json:"action" binding:"required"`
+	Warning   string `json:"warning" binding:"max=255"`
+	Enabled   *bool  `json:"enabled"` // Defaults to true
+}
+
+func (r *FilterRuleRequest) toModel() *models.FilterRule {
+	enabled := r.Enabled == nil || *r.Enabled
+	return &models.FilterRule{
+		Name:      r.Name,
+		MatchType: r.MatchType,
+		Pattern:   r.Pattern,
+		Field:     r.Field,
+		Action:    r.Action,
+		Warning:   r.Warning,
+		Enabled:   enabled,
+	}
+}
+
+type FilterDryRunRequest struct {
+	Title   string             `json:"title"`
+	Content string             `json:"content"`
+	Rule    *FilterRuleRequest `json:"rule"` // Test this rule only instead of the stored rules
+}
+
+// GET /api/admin/filter-rules - List content filter rules
+func (h *AdminHandler) GetFilterRules(c *gin.Context) {
+	rules, err := h.contentFilter.ListRules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch filter rules",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rules": rules,
+	})
+}
+
+// POST /api/admin/filter-rules - Add a content filter rule
+func (h *AdminHandler) CreateFilterRule(c *gin.Context) {
+	var req FilterRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	rule := req.toModel()
+	if err := h.contentFilter.CreateRule(rule, c.GetString("moderator")); err != nil {
+		h.filterRuleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// PUT /api/admin/filter-rules/{id} - Replace a content filter rule
+func (h *AdminHandler) UpdateFilterRule(c *gin.Context) {
+	ruleID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid rule ID format",
+		})
+		return
+	}
+
+	var req FilterRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	rule := req.toModel()
+	if err := h.contentFilter.UpdateRule(ruleID, rule, c.GetString("moderator")); err != nil {
+		h.filterRuleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// DELETE /api/admin/filter-rules/{id} - Delete a content filter rule
+func (h *AdminHandler) DeleteFilterRule(c *gin.Context) {
+	ruleID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid rule ID format",
+		})
+		return
+	}
+
+	if err := h.contentFilter.DeleteRule(ruleID, c.GetString("moderator")); err != nil {
+		h.filterRuleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Filter rule deleted",
+	})
+}
+
+// POST /api/admin/filter-rules/test - Dry-run the filter rules against sample text
+func (h *AdminHandler) TestFilterRules(c *gin.Context) {
+	var req FilterDryRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var candidate *models.FilterRule
+	if req.Rule != nil {
+		candidate = req.Rule.toModel()
+	}
+
+	result, err := h.contentFilter.DryRun(candidate, req.Title, req.Content)
+	if err != nil {
+		h.filterRuleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *AdminHandler) filterRuleError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "filter rule not found":
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Filter rule not found",
+		})
+	case "invalid match type", "invalid field", "invalid action", "invalid pattern":
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid rule: " + err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update filter rules",
+		})
+	}
+}
+
+type AppealDecisionRequest struct {
+	Decision string `json:"decision"` // Note shown to the author
+}
+
+// GET /api/admin/appeals - Appeals by status (?status=pending|granted|denied|all, default pending)
+func (h *AdminHandler) GetAppeals(c *gin.Context) {
+	status := c.DefaultQuery("status", models.AppealPending)
+	switch status {
+	case "all":
+		status = ""
+	case models.AppealPending, models.AppealGranted, models.AppealDenied:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid status. Must be 'pending', 'granted', 'denied' or 'all'",
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	appeals, err := h.appealService.ListAppeals(status, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch appeals",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"appeals": appeals,
+	})
+}
+
+// POST /api/admin/appeals/{id}/grant - Restore the appealed content
+func (h *AdminHandler) GrantAppeal(c *gin.Context) {
+	h.decideAppeal(c, true)
+}
+
+// POST /api/admin/appeals/{id}/deny - Keep the appealed content hidden
+func (h *AdminHandler) DenyAppeal(c *gin.Context) {
+	h.decideAppeal(c, false)
+}
+
+func (h *AdminHandler) decideAppeal(c *gin.Context, grant bool) {
+	appealID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid appeal ID format",
+		})
+		return
+	}
+
+	// The body is optional; it only carries the decision note
+	var req AppealDecisionRequest
+	_ = c.ShouldBindJSON(&req)
+
+	actor := c.GetString("moderator")
+	if grant {
+		err = h.appealService.GrantAppeal(appealID, actor, req.Decision)
+	} else {
+		err = h.appealService.DenyAppeal(appealID, actor, req.Decision)
+	}
+
+	if err != nil {
+		switch err.Error() {
+		case "appeal not found", "post not found", "comment not found":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Appeal not found",
+			})
+		case "appeal already decided":
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Appeal has already been decided",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to decide appeal",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Appeal decided",
+	})
+}
+
+func (h *AdminHandler) moderate(c *gin.Context, contentType, action string) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid " + contentType + " ID format",
+		})
+		return
+	}
+
+	// The body is optional; it only carries the moderator's reason
+	var req ModerationActionRequest
+	_ = c.ShouldBindJSON(&req)
+
+	actor := c.GetString("moderator")
+	switch {
+	case contentType == models.FlagTypePost && action == models.ModerationApprove:
+		err = h.moderationService.ApprovePost(id, actor, req.Reason)
+	case contentType == models.FlagTypePost:
+		err = h.moderationService.RemovePost(id, actor, req.Reason)
+	case action == models.ModerationApprove:
+		err = h.moderationService.ApproveComment(id, actor, req.Reason)
+	default:
+		err = h.moderationService.RemoveComment(id, actor, req.Reason)
+	}
+
+	if err != nil {
+		if err.Error() == contentType+" not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to apply moderation action",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Moderation action applied",
+		"action":  action,
+	})
+}
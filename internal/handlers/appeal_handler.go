@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"net/http"
+
+	"reveal/internal/models"
+	"reveal/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ManageTokenHeader carries the management token returned when a post or comment is created
+const ManageTokenHeader = "X-Manage-Token"
+
+type AppealHandler struct {
+	appealService *services.AppealService
+}
+
+func NewAppealHandler() *AppealHandler {
+	return &AppealHandler{
+		appealService: services.NewAppealService(),
+	}
+}
+
+type FileAppealRequest struct {
+	Statement string `json:"statement" binding:"required"`
+}
+
+// POST /api/posts/{id}/appeal - Appeal the flags hiding your post
+func (h *AppealHandler) FilePostAppeal(c *gin.Context) {
+	h.fileAppeal(c, models.FlagTypePost)
+}
+
+// GET /api/posts/{id}/appeal - Status of the appeal on your post
+func (h *AppealHandler) GetPostAppeal(c *gin.Context) {
+	h.getAppeal(c, models.FlagTypePost)
+}
+
+// POST /api/comments/{id}/appeal - Appeal the flags hiding your comment
+func (h *AppealHandler) FileCommentAppeal(c *gin.Context) {
+	h.fileAppeal(c, models.FlagTypeComment)
+}
+
+// GET /api/comments/{id}/appeal - Status of the appeal on your comment
+func (h *AppealHandler) GetCommentAppeal(c *gin.Context) {
+	h.getAppeal(c, models.FlagTypeComment)
+}
+
+func (h *AppealHandler) fileAppeal(c *gin.Context, contentType string) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid " + contentType + " ID format",
+		})
+		return
+	}
+
+	var req FileAppealRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	appeal, err := h.appealService.FileAppeal(contentType, id, c.GetHeader(ManageTokenHeader), req.Statement)
+	if err != nil {
+		switch err.Error() {
+		case "statement cannot be empty", "statement too long":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Statement must be between 1 and 500 characters",
+			})
+		case "content not hidden":
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Only content hidden by flags can be appealed",
+			})
+		case "appeal already filed":
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "This " + contentType + " has already been appealed",
+			})
+		default:
+			h.appealError(c, contentType, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, appeal)
+}
+
+func (h *AppealHandler) getAppeal(c *gin.Context, contentType string) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid " + contentType + " ID format",
+		})
+		return
+	}
+
+	appeal, err := h.appealService.GetAppeal(contentType, id, c.GetHeader(ManageTokenHeader))
+	if err != nil {
+		if err.Error() == "appeal not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "No appeal has been filed",
+			})
+			return
+		}
+		h.appealError(c, contentType, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, appeal)
+}
+
+// appealError reports the errors shared by the appeal endpoints
+func (h *AppealHandler) appealError(c *gin.Context, contentType string, err error) {
+	switch err.Error() {
+	case contentType + " not found":
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Not found",
+		})
+	case "invalid management token":
+		// Deliberately vague: the token is the only proof of authorship
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "A valid " + ManageTokenHeader + " header is required",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to process appeal",
+		})
+	}
+}
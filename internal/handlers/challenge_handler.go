@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+
+	"reveal/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ChallengeHandler struct {
+	challengeService *services.ChallengeService
+}
+
+func NewChallengeHandler() *ChallengeHandler {
+	return &ChallengeHandler{
+		challengeService: services.NewChallengeService(),
+	}
+}
+
+// GET /api/challenge - Issue a proof-of-work challenge for the next write
+func (h *ChallengeHandler) GetChallenge(c *gin.Context) {
+	if !h.challengeService.Enabled() {
+		c.JSON(http.StatusOK, gin.H{
+			"required": false,
+		})
+		return
+	}
+
+	challenge, err := h.challengeService.Issue(c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to issue challenge",
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"required":   true,
+		"token":      challenge.Token,
+		"algorithm":  challenge.Algorithm,
+		"difficulty": challenge.Difficulty,
+		"expires_at": challenge.ExpiresAt,
+	})
+}
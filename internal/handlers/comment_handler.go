@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 
-	"reveal/internal/models"
+	"reveal/internal/middleware"
 	"reveal/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -20,13 +24,20 @@ func NewCommentHandler() *CommentHandler {
 	}
 }
 
+// maxCommentBodyBytes bounds a create comment request, with room for JSON escaping
+const maxCommentBodyBytes = 2 * services.MaxBytesPerCharacter * services.MaxCommentLength
+
 type CreateCommentRequest struct {
-	Content string `json:"content" binding:"required,max=1000"`
+	Content string `json:"content" binding:"required"` // At most services.MaxCommentLength characters
 }
 
 type CreateCommentResponse struct {
 	ID        uuid.UUID `json:"id"`
 	CreatedAt string    `json:"created_at"`
+	Status    string    `json:"status"` // "pending" when held for moderator approval
+
+	// Shown only once; send it as X-Manage-Token to appeal if the comment is hidden
+	ManageToken string `json:"manage_token"`
 }
 
 // POST /api/posts/{id}/comments - Submit a comment on a post
@@ -41,7 +52,15 @@ func (h *CommentHandler) CreateComment(c *gin.Context) {
 	}
 
 	var req CreateCommentRequest
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxCommentBodyBytes)
 	if err := c.ShouldBindJSON(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": "Comment is too long",
+			})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Invalid request format",
 			"details": err.Error(),
@@ -52,6 +71,46 @@ func (h *CommentHandler) CreateComment(c *gin.Context) {
 	clientIP := c.ClientIP()
 	comment, err := h.commentService.CreateComment(postID, req.Content, clientIP)
 	if err != nil {
+		if err.Error() == "identity banned" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "This identity has been banned",
+			})
+			return
+		}
+		var piiErr *services.PIIError
+		if errors.As(err, &piiErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":    "Your comment appears to contain personal information. Please remove it and submit again.",
+				"code":     "pii_detected",
+				"findings": piiErr.Findings,
+			})
+			return
+		}
+		var linkErr *services.LinkError
+		if errors.As(err, &linkErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": "Your comment contains links that are not allowed. Please remove them and submit again.",
+				"code":  "links_not_allowed",
+				"links": linkErr.Links,
+			})
+			return
+		}
+		if err.Error() == "content rejected" {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": "Your comment contains content that is not allowed",
+			})
+			return
+		}
+		var slowErr *services.SlowModeError
+		if errors.As(err, &slowErr) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(slowErr.RetryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":           "This thread is in slow mode. Please wait before commenting again.",
+				"code":            "slow_mode",
+				"slow_mode_until": slowErr.Until,
+			})
+			return
+		}
 		if err.Error() == "rate limit exceeded" {
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"error": "You're commenting too frequently. Please wait a moment before commenting again.",
@@ -64,9 +123,16 @@ func (h *CommentHandler) CreateComment(c *gin.Context) {
 			})
 			return
 		}
-		if err.Error() == "comment too long (max 1000 characters)" {
+		if err.Error() == "content cannot be empty" || err.Error() == "comment cannot be empty" {
+			// Only invisible characters or whitespace, which normalizing removes
 			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Comment too long (max 1000 characters)",
+				"error": "Comment cannot be empty",
+			})
+			return
+		}
+		if err.Error() == "comment too long" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Comment must be at most %d characters long", services.MaxCommentLength),
 			})
 			return
 		}
@@ -78,14 +144,16 @@ func (h *CommentHandler) CreateComment(c *gin.Context) {
 	}
 
 	response := CreateCommentResponse{
-		ID:        comment.ID,
-		CreatedAt: comment.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		ID:          comment.ID,
+		CreatedAt:   comment.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		Status:      comment.Status,
+		ManageToken: comment.ManageToken,
 	}
 
 	c.JSON(http.StatusCreated, response)
 }
 
-// GET /api/posts/{id}/comments - Get comments for a post
+// GET /api/posts/{id}/comments - Get comments for a post (?sort=old|new|top|best)
 func (h *CommentHandler) GetComments(c *gin.Context) {
 	postIDStr := c.Param("id")
 	postID, err := uuid.Parse(postIDStr)
@@ -96,8 +164,16 @@ func (h *CommentHandler) GetComments(c *gin.Context) {
 		return
 	}
 
+	sort := c.DefaultQuery("sort", services.SortOld)
+	if !services.IsValidCommentSort(sort) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid sort. Must be 'old', 'new', 'top' or 'best'",
+		})
+		return
+	}
+
 	clientIP := c.ClientIP()
-	comments, err := h.commentService.GetCommentsByPostID(postID, clientIP)
+	comments, err := h.commentService.GetComments(postID, clientIP, sort)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to fetch comments",
@@ -105,6 +181,10 @@ func (h *CommentHandler) GetComments(c *gin.Context) {
 		return
 	}
 
+	if !middleware.IsModerator(c) {
+		h.commentService.ApplyVoteDisplay(comments)
+	}
+
 	c.JSON(http.StatusOK, comments)
 }
 
@@ -133,43 +213,24 @@ func (h *CommentHandler) FlagComment(c *gin.Context) {
 		return
 	}
 
-	// Use default reason if none provided
-	if req.Reason == "" {
-		req.Reason = "reported"
-	}
-
-	// Only validate reason if it's not the default
-	if req.Reason != "reported" {
-		validReasons := models.GetValidFlagReasons()
-		isValidReason := false
-		for _, validReason := range validReasons {
-			if req.Reason == validReason {
-				isValidReason = true
-				break
-			}
-		}
-		
-		if !isValidReason {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Invalid flag reason",
+	clientIP := c.ClientIP()
+	err = h.commentService.FlagComment(commentID, clientIP, req.Reason, req.Details)
+	if err != nil {
+		if err.Error() == "comment not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Comment not found",
 			})
 			return
 		}
-
-		if req.Reason == "other" && req.Details == "" {
+		if err.Error() == "invalid flag reason" {
 			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Details required when reason is 'other'",
+				"error": "Invalid flag reason",
 			})
 			return
 		}
-	}
-
-	clientIP := c.ClientIP()
-	err = h.commentService.FlagComment(commentID, clientIP, req.Reason, req.Details)
-	if err != nil {
-		if err.Error() == "comment not found" {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": "Comment not found",
+		if err.Error() == "details required" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Details required when reason is '" + req.Reason + "'",
 			})
 			return
 		}
@@ -1,40 +1,60 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"reveal/internal/middleware"
 	"reveal/internal/services"
-	"reveal/internal/models"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 type PostHandler struct {
-	postService *services.PostService
+	postService       *services.PostService
+	flagReasonService *services.FlagReasonService
 }
 
 func NewPostHandler() *PostHandler {
 	return &PostHandler{
-		postService: services.NewPostService(),
+		postService:       services.NewPostService(),
+		flagReasonService: services.NewFlagReasonService(),
 	}
 }
 
+// maxPostBodyBytes bounds a create post request, with room for JSON escaping
+const maxPostBodyBytes = 2 * services.MaxBytesPerCharacter * (services.MaxTitleLength + services.MaxContentLength)
+
 type CreatePostRequest struct {
-	Title   string `json:"title" binding:"required,max=255"`
-	Content string `json:"content" binding:"required,max=5000"`
+	Title   string `json:"title" binding:"required"`   // At most services.MaxTitleLength characters
+	Content string `json:"content" binding:"required"` // services.MinContentLength to services.MaxContentLength characters
 }
 
 type CreatePostResponse struct {
 	ID        uuid.UUID `json:"id"`
 	CreatedAt string    `json:"created_at"`
+	Status    string    `json:"status"` // "pending" when held for moderator approval
+
+	// Shown only once; send it as X-Manage-Token to appeal if the post is hidden
+	ManageToken string `json:"manage_token"`
 }
 
 // POST /api/posts - Submit a secret anonymously
 func (h *PostHandler) CreatePost(c *gin.Context) {
 	var req CreatePostRequest
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxPostBodyBytes)
 	if err := c.ShouldBindJSON(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": "Post is too long",
+			})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Invalid request format",
 			"details": err.Error(),
@@ -42,17 +62,73 @@ func (h *PostHandler) CreatePost(c *gin.Context) {
 		return
 	}
 
-	// Basic content validation
-	if len(req.Content) < 10 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Content must be at least 10 characters long",
-		})
-		return
-	}
-
 	clientIP := c.ClientIP()
 	post, err := h.postService.CreatePost(req.Title, req.Content, clientIP)
 	if err != nil {
+		switch err.Error() {
+		case "title cannot be empty":
+			// Only invisible characters, which normalizing removes
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Title cannot be empty",
+			})
+			return
+		case "content cannot be empty":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Content cannot be empty",
+			})
+			return
+		case "title too long":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Title must be at most %d characters long", services.MaxTitleLength),
+			})
+			return
+		case "content too short":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Content must be at least %d characters long", services.MinContentLength),
+			})
+			return
+		case "content too long":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Content must be at most %d characters long", services.MaxContentLength),
+			})
+			return
+		}
+		if err.Error() == "identity banned" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "This identity has been banned",
+			})
+			return
+		}
+		var piiErr *services.PIIError
+		if errors.As(err, &piiErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":    "Your post appears to contain personal information. Please remove it and submit again.",
+				"code":     "pii_detected",
+				"findings": piiErr.Findings,
+			})
+			return
+		}
+		var linkErr *services.LinkError
+		if errors.As(err, &linkErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": "Your post contains links that are not allowed. Please remove them and submit again.",
+				"code":  "links_not_allowed",
+				"links": linkErr.Links,
+			})
+			return
+		}
+		if err.Error() == "duplicate post" {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "This looks like a repost of a recent post",
+			})
+			return
+		}
+		if err.Error() == "content rejected" {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": "Your post contains content that is not allowed",
+			})
+			return
+		}
 		if err.Error() == "rate limit exceeded" {
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"error": "You're posting too frequently. Please wait a moment before posting again.",
@@ -67,14 +143,17 @@ func (h *PostHandler) CreatePost(c *gin.Context) {
 	}
 
 	response := CreatePostResponse{
-		ID:        post.ID,
-		CreatedAt: post.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		ID:          post.ID,
+		CreatedAt:   post.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		Status:      post.Status,
+		ManageToken: post.ManageToken,
 	}
 
 	c.JSON(http.StatusCreated, response)
 }
 
-// GET /api/posts - List recent public posts
+// GET /api/posts - List public posts (?sort=new|top|best|bayesian).
+// Authors' own pending posts are included; X-Manage-Token may list several tokens, comma-separated.
 func (h *PostHandler) GetPosts(c *gin.Context) {
 	limitStr := c.DefaultQuery("limit", "50")
 	limit, err := strconv.Atoi(limitStr)
@@ -82,8 +161,17 @@ func (h *PostHandler) GetPosts(c *gin.Context) {
 		limit = 50 // Default limit
 	}
 
+	sort := c.DefaultQuery("sort", services.SortNew)
+	if !services.IsValidPostSort(sort) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid sort. Must be 'new', 'top', 'best' or 'bayesian'",
+		})
+		return
+	}
+
 	clientIP := c.ClientIP()
-	posts, err := h.postService.GetRecentPosts(clientIP, limit)
+	manageTokens := strings.Split(c.GetHeader(ManageTokenHeader), ",")
+	posts, err := h.postService.GetPosts(clientIP, limit, sort, manageTokens...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to fetch posts",
@@ -91,6 +179,10 @@ func (h *PostHandler) GetPosts(c *gin.Context) {
 		return
 	}
 
+	if !middleware.IsModerator(c) {
+		h.postService.ApplyVoteDisplay(posts)
+	}
+
 	c.JSON(http.StatusOK, posts)
 }
 
@@ -119,43 +211,24 @@ func (h *PostHandler) FlagPost(c *gin.Context) {
 		return
 	}
 
-	// Use default reason if none provided
-	if req.Reason == "" {
-		req.Reason = "reported"
-	}
-
-	// Only validate reason if it's not the default
-	if req.Reason != "reported" {
-		validReasons := models.GetValidFlagReasons()
-		isValidReason := false
-		for _, validReason := range validReasons {
-			if req.Reason == validReason {
-				isValidReason = true
-				break
-			}
-		}
-		
-		if !isValidReason {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Invalid flag reason",
+	clientIP := c.ClientIP()
+	err = h.postService.FlagPost(postID, clientIP, req.Reason, req.Details)
+	if err != nil {
+		if err.Error() == "post not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Post not found",
 			})
 			return
 		}
-
-		if req.Reason == "other" && req.Details == "" {
+		if err.Error() == "invalid flag reason" {
 			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Details required when reason is 'other'",
+				"error": "Invalid flag reason",
 			})
 			return
 		}
-	}
-
-	clientIP := c.ClientIP()
-	err = h.postService.FlagPost(postID, clientIP, req.Reason, req.Details)
-	if err != nil {
-		if err.Error() == "post not found" {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": "Post not found",
+		if err.Error() == "details required" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Details required when reason is '" + req.Reason + "'",
 			})
 			return
 		}
@@ -177,21 +250,48 @@ func (h *PostHandler) FlagPost(c *gin.Context) {
 	})
 }
 
-// GET /api/flag-reasons - Get available flag reasons
+// FlagReasonResponse is a flag reason with its label in the requested language
+type FlagReasonResponse struct {
+	Key             string `json:"key"`
+	Label           string `json:"label"`
+	Severity        int    `json:"severity"`
+	RequiresDetails bool   `json:"requires_details"`
+}
+
+// GET /api/flag-reasons - Get available flag reasons (?lang=de, else Accept-Language)
 func (h *PostHandler) GetFlagReasons(c *gin.Context) {
-	reasons := map[string]string{
-		"spam": "Spam or unwanted content",
-		"inappropriate": "Inappropriate content",
-		"hate_speech": "Hate speech or discrimination",
-		"harassment": "Harassment or bullying",
-		"other": "Other (please specify)",
+	lang := c.Query("lang")
+	if lang == "" {
+		lang = preferredLanguage(c.GetHeader("Accept-Language"))
+	}
+
+	reasons := h.flagReasonService.List(false)
+	labels := make(map[string]string, len(reasons))
+	taxonomy := make([]FlagReasonResponse, 0, len(reasons))
+	for i := range reasons {
+		label := reasons[i].Label(lang)
+		labels[reasons[i].Key] = label
+		taxonomy = append(taxonomy, FlagReasonResponse{
+			Key:             reasons[i].Key,
+			Label:           label,
+			Severity:        reasons[i].Severity,
+			RequiresDetails: reasons[i].RequiresDetails,
+		})
 	}
-	
+
 	c.JSON(http.StatusOK, gin.H{
-		"reasons": reasons,
+		"reasons":  labels,
+		"taxonomy": taxonomy, // Most severe first
 	})
 }
 
+// preferredLanguage returns the first language of an Accept-Language header
+func preferredLanguage(header string) string {
+	first, _, _ := strings.Cut(header, ",")
+	lang, _, _ := strings.Cut(first, ";")
+	return strings.TrimSpace(lang)
+}
+
 // GET /api/health - Health check endpoint
 func (h *PostHandler) HealthCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
@@ -1,8 +1,12 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
 
+	"reveal/internal/middleware"
+	"reveal/internal/models"
 	"reveal/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -28,15 +32,76 @@ type VoteResponse struct {
 	Downvotes    int64  `json:"downvotes"`
 	UserVote     string `json:"user_vote"`
 	Score        int64  `json:"score"` // upvotes - downvotes
+	VotesHidden  bool   `json:"votes_hidden"`
 }
 
-// POST /api/posts/{id}/vote - Vote on a post
-func (h *VoteHandler) VoteOnPost(c *gin.Context) {
-	postIDStr := c.Param("id")
-	postID, err := uuid.Parse(postIDStr)
+// postVoteResponse builds a vote response, applying the display policy for non-moderators
+func (h *VoteHandler) postVoteResponse(c *gin.Context, postID uuid.UUID, upvotes, downvotes int64, userVote string) VoteResponse {
+	hidden := false
+	if !middleware.IsModerator(c) {
+		upvotes, downvotes, hidden = h.voteService.DisplayPostVotes(postID, upvotes, downvotes)
+	}
+	return VoteResponse{
+		Upvotes:     upvotes,
+		Downvotes:   downvotes,
+		UserVote:    userVote,
+		Score:       upvotes - downvotes,
+		VotesHidden: hidden,
+	}
+}
+
+// commentVoteResponse builds a vote response, applying the display policy for non-moderators
+func (h *VoteHandler) commentVoteResponse(c *gin.Context, commentID uuid.UUID, upvotes, downvotes int64, userVote string) VoteResponse {
+	hidden := false
+	if !middleware.IsModerator(c) {
+		upvotes, downvotes, hidden = h.voteService.DisplayCommentVotes(commentID, upvotes, downvotes)
+	}
+	return VoteResponse{
+		Upvotes:     upvotes,
+		Downvotes:   downvotes,
+		UserVote:    userVote,
+		Score:       upvotes - downvotes,
+		VotesHidden: hidden,
+	}
+}
+
+// voteCounts responds with the vote counts of a post or comment as seen by the client
+func (h *VoteHandler) voteCounts(c *gin.Context, target string, id uuid.UUID, clientIP string) {
+	if target == models.FlagTypeComment {
+		upvotes, downvotes, userVote, err := h.voteService.GetCommentVotes(id, clientIP)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to fetch vote counts",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, h.commentVoteResponse(c, id, upvotes, downvotes, userVote))
+		return
+	}
+
+	upvotes, downvotes, userVote, err := h.voteService.GetPostVotes(id, clientIP)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch vote counts",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, h.postVoteResponse(c, id, upvotes, downvotes, userVote))
+}
+
+// castVote parses and validates a vote on a post or comment, applies it with
+// vote and responds with the updated counts. allowNone accepts "none" to clear
+// the vote, for setting an explicit state.
+func (h *VoteHandler) castVote(c *gin.Context, target string, allowNone bool, vote func(id uuid.UUID, voteType, clientIP string) error) {
+	label := "Post"
+	if target == models.FlagTypeComment {
+		label = "Comment"
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid post ID format",
+			"error": fmt.Sprintf("Invalid %s ID format", target),
 		})
 		return
 	}
@@ -44,13 +109,20 @@ func (h *VoteHandler) VoteOnPost(c *gin.Context) {
 	var req VoteRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request format",
+			"error":   "Invalid request format",
 			"details": err.Error(),
 		})
 		return
 	}
 
-	if req.VoteType != "upvote" && req.VoteType != "downvote" {
+	if allowNone {
+		if req.VoteType != "upvote" && req.VoteType != "downvote" && req.VoteType != "none" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid vote type. Must be 'upvote', 'downvote' or 'none'",
+			})
+			return
+		}
+	} else if req.VoteType != "upvote" && req.VoteType != "downvote" {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Invalid vote type. Must be 'upvote' or 'downvote'",
 		})
@@ -58,28 +130,49 @@ func (h *VoteHandler) VoteOnPost(c *gin.Context) {
 	}
 
 	clientIP := c.ClientIP()
-	err = h.voteService.VoteOnPost(postID, req.VoteType, clientIP)
-	if err != nil {
-		if err.Error() == "post not found" {
+	if err := vote(id, req.VoteType, clientIP); err != nil {
+		switch err.Error() {
+		case target + " not found":
 			c.JSON(http.StatusNotFound, gin.H{
-				"error": "Post not found",
+				"error": label + " not found",
 			})
-			return
-		}
-		if err.Error() == "rate limit exceeded" {
+		case "identity banned":
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "This identity has been banned",
+			})
+		case "rate limit exceeded":
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"error": "You're voting too frequently. Please wait a moment.",
 			})
-			return
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to process vote",
+			})
 		}
-		
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to process vote",
-		})
 		return
 	}
 
 	// Return updated vote counts
+	h.voteCounts(c, target, id, clientIP)
+}
+
+// POST /api/posts/{id}/vote - Vote on a post
+func (h *VoteHandler) VoteOnPost(c *gin.Context) {
+	h.castVote(c, models.FlagTypePost, false, h.voteService.VoteOnPost)
+}
+
+// GET /api/posts/{id}/votes - Get vote counts for a post
+func (h *VoteHandler) GetPostVotes(c *gin.Context) {
+	postIDStr := c.Param("id")
+	postID, err := uuid.Parse(postIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid post ID format",
+		})
+		return
+	}
+
+	clientIP := c.ClientIP()
 	upvotes, downvotes, userVote, err := h.voteService.GetPostVotes(postID, clientIP)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -88,16 +181,12 @@ func (h *VoteHandler) VoteOnPost(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, VoteResponse{
-		Upvotes:   upvotes,
-		Downvotes: downvotes,
-		UserVote:  userVote,
-		Score:     upvotes - downvotes,
-	})
+	c.JSON(http.StatusOK, h.postVoteResponse(c, postID, upvotes, downvotes, userVote))
 }
 
-// GET /api/posts/{id}/votes - Get vote counts for a post
-func (h *VoteHandler) GetPostVotes(c *gin.Context) {
+// GET /api/admin/posts/{id}/votes/history - Vote counts bucketed by hour or day (?bucket=hour|day&days=N)
+// Exact per-bucket counts would undo the public vote display policy, so this is moderator only.
+func (h *VoteHandler) GetPostVoteHistory(c *gin.Context) {
 	postIDStr := c.Param("id")
 	postID, err := uuid.Parse(postIDStr)
 	if err != nil {
@@ -107,25 +196,53 @@ func (h *VoteHandler) GetPostVotes(c *gin.Context) {
 		return
 	}
 
-	clientIP := c.ClientIP()
-	upvotes, downvotes, userVote, err := h.voteService.GetPostVotes(postID, clientIP)
+	bucket := c.DefaultQuery("bucket", models.VoteBucketHour)
+	if bucket != models.VoteBucketHour && bucket != models.VoteBucketDay {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid bucket. Must be 'hour' or 'day'",
+		})
+		return
+	}
+
+	// Hourly history covers at most a month, daily history at most a year
+	defaultDays, maxDays := 7, 31
+	if bucket == models.VoteBucketDay {
+		defaultDays, maxDays = 30, 365
+	}
+	days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(defaultDays)))
+	if err != nil || days <= 0 || days > maxDays {
+		days = defaultDays
+	}
+
+	history, err := h.voteService.GetPostVoteHistory(postID, bucket, days)
 	if err != nil {
+		if err.Error() == "post not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Post not found",
+			})
+			return
+		}
+
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to fetch vote counts",
+			"error": "Failed to fetch vote history",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, VoteResponse{
-		Upvotes:   upvotes,
-		Downvotes: downvotes,
-		UserVote:  userVote,
-		Score:     upvotes - downvotes,
+	c.JSON(http.StatusOK, gin.H{
+		"post_id": postID,
+		"bucket":  bucket,
+		"buckets": history,
 	})
 }
 
 // POST /api/comments/{id}/vote - Vote on a comment
 func (h *VoteHandler) VoteOnComment(c *gin.Context) {
+	h.castVote(c, models.FlagTypeComment, false, h.voteService.VoteOnComment)
+}
+
+// GET /api/comments/{id}/votes - Get vote counts for a comment
+func (h *VoteHandler) GetCommentVotes(c *gin.Context) {
 	commentIDStr := c.Param("id")
 	commentID, err := uuid.Parse(commentIDStr)
 	if err != nil {
@@ -135,63 +252,67 @@ func (h *VoteHandler) VoteOnComment(c *gin.Context) {
 		return
 	}
 
-	var req VoteRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request format",
-			"details": err.Error(),
+	clientIP := c.ClientIP()
+	upvotes, downvotes, userVote, err := h.voteService.GetCommentVotes(commentID, clientIP)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch vote counts",
 		})
 		return
 	}
 
-	if req.VoteType != "upvote" && req.VoteType != "downvote" {
+	c.JSON(http.StatusOK, h.commentVoteResponse(c, commentID, upvotes, downvotes, userVote))
+}
+
+// PUT /api/posts/{id}/vote - Set an explicit vote state on a post (upvote, downvote or none)
+func (h *VoteHandler) SetPostVote(c *gin.Context) {
+	h.castVote(c, models.FlagTypePost, true, h.voteService.SetPostVote)
+}
+
+// DELETE /api/posts/{id}/vote - Clear the user's vote on a post
+func (h *VoteHandler) RemovePostVote(c *gin.Context) {
+	postIDStr := c.Param("id")
+	postID, err := uuid.Parse(postIDStr)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid vote type. Must be 'upvote' or 'downvote'",
+			"error": "Invalid post ID format",
 		})
 		return
 	}
 
 	clientIP := c.ClientIP()
-	err = h.voteService.VoteOnComment(commentID, req.VoteType, clientIP)
-	if err != nil {
-		if err.Error() == "comment not found" {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": "Comment not found",
+	err = h.voteService.RemoveVoteFromPost(postID, clientIP)
+	// A missing vote is not an error: DELETE is idempotent
+	if err != nil && err.Error() != "vote not found" {
+		if err.Error() == "identity banned" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "This identity has been banned",
 			})
 			return
 		}
-		if err.Error() == "rate limit exceeded" {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error": "You're voting too frequently. Please wait a moment.",
+		if err.Error() == "post not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Post not found",
 			})
 			return
 		}
-		
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to process vote",
-		})
-		return
-	}
 
-	// Return updated vote counts
-	upvotes, downvotes, userVote, err := h.voteService.GetCommentVotes(commentID, clientIP)
-	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to fetch vote counts",
+			"error": "Failed to remove vote",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, VoteResponse{
-		Upvotes:   upvotes,
-		Downvotes: downvotes,
-		UserVote:  userVote,
-		Score:     upvotes - downvotes,
-	})
+	h.voteCounts(c, models.FlagTypePost, postID, clientIP)
 }
 
-// GET /api/comments/{id}/votes - Get vote counts for a comment
-func (h *VoteHandler) GetCommentVotes(c *gin.Context) {
+// PUT /api/comments/{id}/vote - Set an explicit vote state on a comment (upvote, downvote or none)
+func (h *VoteHandler) SetCommentVote(c *gin.Context) {
+	h.castVote(c, models.FlagTypeComment, true, h.voteService.SetCommentVote)
+}
+
+// DELETE /api/comments/{id}/vote - Clear the user's vote on a comment
+func (h *VoteHandler) RemoveCommentVote(c *gin.Context) {
 	commentIDStr := c.Param("id")
 	commentID, err := uuid.Parse(commentIDStr)
 	if err != nil {
@@ -202,18 +323,27 @@ func (h *VoteHandler) GetCommentVotes(c *gin.Context) {
 	}
 
 	clientIP := c.ClientIP()
-	upvotes, downvotes, userVote, err := h.voteService.GetCommentVotes(commentID, clientIP)
-	if err != nil {
+	err = h.voteService.RemoveVoteFromComment(commentID, clientIP)
+	// A missing vote is not an error: DELETE is idempotent
+	if err != nil && err.Error() != "vote not found" {
+		if err.Error() == "identity banned" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "This identity has been banned",
+			})
+			return
+		}
+		if err.Error() == "comment not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Comment not found",
+			})
+			return
+		}
+
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to fetch vote counts",
+			"error": "Failed to remove vote",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, VoteResponse{
-		Upvotes:   upvotes,
-		Downvotes: downvotes,
-		UserVote:  userVote,
-		Score:     upvotes - downvotes,
-	})
-} 
\ No newline at end of file
+	h.voteCounts(c, models.FlagTypeComment, commentID, clientIP)
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+
+	"reveal/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChallengeSolutionHeader carries a solved proof-of-work challenge ("token:counter")
+const ChallengeSolutionHeader = "X-Challenge-Solution"
+
+// RequireChallenge rejects write requests without a valid proof-of-work
+// solution when challenges are enabled (POW_ENABLED=true)
+func RequireChallenge() gin.HandlerFunc {
+	challenges := services.NewChallengeService()
+
+	return gin.HandlerFunc(func(c *gin.Context) {
+		if !challenges.Enabled() {
+			c.Next()
+			return
+		}
+
+		err := challenges.Verify(c.ClientIP(), c.GetHeader(ChallengeSolutionHeader))
+		if err == nil {
+			c.Next()
+			return
+		}
+
+		switch err.Error() {
+		case "challenge required":
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Please solve a challenge from /api/challenge and send it in the " + ChallengeSolutionHeader + " header",
+				"code":  "challenge_required",
+			})
+		case "challenge expired", "challenge already used":
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "This challenge has expired or was already used. Please request a new one.",
+				"code":  "challenge_expired",
+			})
+		case "challenge check failed":
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": "Failed to verify challenge. Please try again.",
+			})
+		default:
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Invalid challenge solution",
+				"code":  "challenge_invalid",
+			})
+		}
+		c.Abort()
+	})
+}
@@ -1,13 +1,15 @@
 package middleware
 
 import (
+	"expvar"
 	"log"
 	"net/http"
-	"sync"
+	"strconv"
 	"time"
 
+	"reveal/internal/services"
+
 	"github.com/gin-gonic/gin"
-	"golang.org/x/time/rate"
 )
 
 // CORS middleware
@@ -15,8 +17,9 @@ func CORS() gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-Moderator-Token, X-Manage-Token, X-Challenge-Solution, accept, origin, Cache-Control, X-Requested-With")
 		c.Header("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
+		c.Header("Access-Control-Expose-Headers", "RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -46,29 +49,58 @@ func Logger() gin.HandlerFunc {
 	})
 }
 
-// Rate limiting middleware using token bucket per IP
-var limiters = make(map[string]*rate.Limiter)
-var mu sync.Mutex
+// init publishes the size of the rate limit stores under /api/admin/metrics.
+// The stores themselves are only built on first use, after the environment is loaded.
+func init() {
+	metrics := expvar.NewMap("rate_limiter")
+	metrics.Set("clients", expvar.Func(func() interface{} { return LimiterCount() }))
+	metrics.Set("write_buckets", expvar.Func(func() interface{} { return services.WriteRateLimitCount() }))
+	metrics.Set("evictions", expvar.Func(func() interface{} {
+		if store, ok := services.SharedRateLimitStore().(*services.MemoryRateLimitStore); ok {
+			return store.Evictions()
+		}
+		return 0
+	}))
+}
 
-func getLimiter(ip string) *rate.Limiter {
-	mu.Lock()
-	defer mu.Unlock()
+// RateLimit limits each client (IPv4 address or IPv6 /64) by the default
+// policy, in the shared rate limit store. Responses carry RateLimit-Limit,
+// RateLimit-Remaining and RateLimit-Reset headers, and Retry-After once the
+// limit is reached.
+func RateLimit() gin.HandlerFunc {
+	return RateLimitPolicy(PolicyDefault)
+}
 
-	limiter, exists := limiters[ip]
-	if !exists {
-		// Allow 5 requests per minute per IP for API endpoints
-		limiter = rate.NewLimiter(rate.Every(time.Minute/5), 5)
-		limiters[ip] = limiter
+// RateLimitPolicy applies the named policy (see RateLimitPolicies). Each policy
+// counts its own requests, so voting does not use up the posting allowance.
+func RateLimitPolicy(name string) gin.HandlerFunc {
+	rate, ok := RateLimitPolicies()[name]
+	if !ok {
+		log.Printf("Warning: Unknown rate limit policy %q, using %q", name, PolicyDefault)
+		rate = RateLimitPolicies()[PolicyDefault]
 	}
-	return limiter
-}
+	store := services.SharedRateLimitStore()
 
-func RateLimit() gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
-		ip := c.ClientIP()
-		limiter := getLimiter(ip)
-		
-		if !limiter.Allow() {
+		if rate.Requests <= 0 {
+			c.Next()
+			return
+		}
+
+		key := "api:" + name + ":" + services.RateLimitKey(c.ClientIP())
+		result, err := store.Allow(key, rate)
+		if err != nil {
+			// Fail open rather than locking everyone out when the store is down
+			log.Printf("Warning: Rate limit check failed: %v", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"error": "Rate limit exceeded. Please wait a moment before trying again.",
 			})
@@ -79,11 +111,23 @@ func RateLimit() gin.HandlerFunc {
 	})
 }
 
+// ceilSeconds rounds a duration up to whole seconds for the rate limit headers
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
+
 // ResetLimiters clears all rate limiters (useful for testing)
 func ResetLimiters() {
-	mu.Lock()
-	defer mu.Unlock()
-	limiters = make(map[string]*rate.Limiter)
+	services.ResetRateLimits()
+}
+
+// LimiterCount returns how many buckets the in-memory rate limit store holds
+// (-1 when the store is shared in the database)
+func LimiterCount() int {
+	if store, ok := services.SharedRateLimitStore().(*services.MemoryRateLimitStore); ok {
+		return store.Len()
+	}
+	return -1
 }
 
 // Security headers middleware
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ModeratorTokenHeader carries a moderator credential on API requests
+const ModeratorTokenHeader = "X-Moderator-Token"
+
+// moderatorTokens parses MODERATOR_TOKENS ("name:token,name:token") into a token -> name map
+func moderatorTokens() map[string]string {
+	tokens := make(map[string]string)
+	for _, entry := range strings.Split(os.Getenv("MODERATOR_TOKENS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, token, found := strings.Cut(entry, ":")
+		if !found || name == "" || token == "" {
+			continue
+		}
+		tokens[token] = name
+	}
+	return tokens
+}
+
+// ModeratorName returns the moderator identified by the request credentials, if any
+func ModeratorName(c *gin.Context) (string, bool) {
+	provided := c.GetHeader(ModeratorTokenHeader)
+	if provided == "" {
+		if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			provided = strings.TrimPrefix(auth, "Bearer ")
+		}
+	}
+	if provided == "" {
+		return "", false
+	}
+
+	for token, name := range moderatorTokens() {
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1 {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// IsModerator reports whether the request carries valid moderator credentials
+func IsModerator(c *gin.Context) bool {
+	_, ok := ModeratorName(c)
+	return ok
+}
+
+// RequireModerator rejects requests without valid moderator credentials and
+// stores the moderator's name in the context under "moderator"
+func RequireModerator() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		name, ok := ModeratorName(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Moderator credentials required",
+			})
+			c.Abort()
+			return
+		}
+		c.Set("moderator", name)
+		c.Next()
+	})
+}
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"reveal/internal/services"
+)
+
+// Rate limit policy names used by the routes
+const (
+	PolicyDefault = "default"
+	PolicyRead    = "read"
+	PolicyPost    = "post"
+	PolicyComment = "comment"
+	PolicyVote    = "vote"
+	PolicyFlag    = "flag"
+	PolicyAppeal  = "appeal"
+)
+
+// defaultPolicies fit how often each kind of request is made: voting is
+// frequent, posting rare, and reads allow browsing but not scraping
+var defaultPolicies = map[string]services.Rate{
+	PolicyRead:    {Requests: 120, Window: time.Minute},
+	PolicyPost:    {Requests: 3, Window: time.Minute},
+	PolicyComment: {Requests: 10, Window: time.Minute},
+	PolicyVote:    {Requests: 60, Window: time.Minute},
+	PolicyFlag:    {Requests: 10, Window: time.Minute},
+	PolicyAppeal:  {Requests: 3, Window: 10 * time.Minute},
+}
+
+// RateLimitPolicies returns the named rate limit policies. The built-in
+// defaults are overridden by the lines of the RATE_LIMIT_CONFIG file, and
+// those by RATE_LIMIT_POLICIES, both as name=requests/window entries such as
+// "vote=60/1m" (comma-separated in the environment). 0 requests switches a
+// policy off. The default policy also follows RATE_LIMIT_REQUESTS and
+// RATE_LIMIT_WINDOW (seconds).
+func RateLimitPolicies() map[string]services.Rate {
+	policies := map[string]services.Rate{
+		PolicyDefault: {
+			Requests: envInt("RATE_LIMIT_REQUESTS", 5),
+			Window:   time.Duration(envInt("RATE_LIMIT_WINDOW", 60)) * time.Second,
+		},
+	}
+	if policies[PolicyDefault].Window <= 0 {
+		policies[PolicyDefault] = services.Rate{Requests: policies[PolicyDefault].Requests, Window: time.Minute}
+	}
+	for name, rate := range defaultPolicies {
+		policies[name] = rate
+	}
+
+	if path := os.Getenv("RATE_LIMIT_CONFIG"); path != "" {
+		if err := loadPolicyFile(path, policies); err != nil {
+			log.Printf("Warning: Failed to load rate limit policies from %s: %v", path, err)
+		}
+	}
+	for _, entry := range strings.Split(os.Getenv("RATE_LIMIT_POLICIES"), ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		if err := addPolicy(entry, policies); err != nil {
+			log.Printf("Warning: Ignoring rate limit policy %q: %v", entry, err)
+		}
+	}
+	return policies
+}
+
+// loadPolicyFile reads one policy per line; blank lines and # comments are skipped
+func loadPolicyFile(path string, policies map[string]services.Rate) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		if err := addPolicy(entry, policies); err != nil {
+			log.Printf("Warning: Ignoring rate limit policy on line %d of %s: %v", line, path, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// addPolicy parses a name=requests/window entry into policies
+func addPolicy(entry string, policies map[string]services.Rate) error {
+	name, spec, found := strings.Cut(entry, "=")
+	name = strings.TrimSpace(name)
+	if !found || name == "" {
+		return fmt.Errorf("expected name=requests/window")
+	}
+	requests, window, found := strings.Cut(strings.TrimSpace(spec), "/")
+	if !found {
+		return fmt.Errorf("expected name=requests/window")
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(requests))
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid request count")
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(window))
+	if err != nil || d <= 0 {
+		return fmt.Errorf("invalid window")
+	}
+	// Requests are spaced window/requests apart, which must not round to zero
+	if n > 0 && d/time.Duration(n) == 0 {
+		return fmt.Errorf("window too short for %d requests", n)
+	}
+
+	policies[name] = services.Rate{Requests: n, Window: d}
+	return nil
+}
+
+// envInt reads an integer setting from the environment, falling back when unset or invalid
+func envInt(key string, fallback int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return v
+	}
+	return fallback
+}
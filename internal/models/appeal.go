@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Appeal statuses
+const (
+	AppealPending = "pending"
+	AppealGranted = "granted" // Content was restored
+	AppealDenied  = "denied"  // Content stays hidden
+)
+
+// Appeal is an author's request to restore a post or comment hidden by flags.
+// Each post or comment can be appealed once.
+type Appeal struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	ContentType string     `gorm:"type:varchar(20);not null;uniqueIndex:idx_appeal_target" json:"content_type"` // "post" or "comment"
+	TargetID    uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_appeal_target" json:"target_id"`
+	Statement   string     `gorm:"type:text;not null" json:"statement"`
+	Status      string     `gorm:"type:varchar(20);not null;default:'pending';index" json:"status"`
+	Decision    string     `gorm:"type:text" json:"decision,omitempty"` // Moderator's note to the author
+	DecidedBy   string     `gorm:"type:varchar(100)" json:"-"`
+	DecidedAt   *time.Time `json:"decided_at,omitempty"`
+	CreatedAt   time.Time  `gorm:"not null" json:"created_at"`
+}
+
+func (a *Appeal) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Audited actions
+const (
+	AuditApprove        = "approve"         // Moderator approved content
+	AuditRemove         = "remove"          // Moderator removed content
+	AuditAutoFlag       = "auto_flag"       // Content was globally flagged by the flag thresholds
+	AuditAutoHold       = "auto_hold"       // Content was held for review by the flag thresholds
+	AuditThresholdSet   = "threshold_set"   // Moderator overrode a flag threshold
+	AuditThresholdReset = "threshold_reset" // Moderator restored a default flag threshold
+	AuditBan            = "ban"             // Moderator banned or shadowbanned an identity
+	AuditUnban          = "unban"           // Moderator revoked a ban
+	AuditFilterCreate   = "filter_create"   // Moderator added a content filter rule
+	AuditFilterUpdate   = "filter_update"   // Moderator changed a content filter rule
+	AuditFilterDelete   = "filter_delete"   // Moderator deleted a content filter rule
+	AuditAppealGrant    = "appeal_grant"    // Moderator restored content on appeal
+	AuditAppealDeny     = "appeal_deny"     // Moderator upheld hiding content on appeal
+
+	AuditFlagReasonCreate = "flag_reason_create" // Moderator added a flag reason
+	AuditFlagReasonUpdate = "flag_reason_update" // Moderator changed a flag reason
+
+	AuditSlowModeOn  = "slow_mode_on"  // Slow mode was switched on for a thread
+	AuditSlowModeOff = "slow_mode_off" // Moderator switched slow mode off for a thread
+)
+
+// AuditActorSystem is the actor recorded for automatic decisions
+const AuditActorSystem = "system"
+
+// AuditEntry is one record in the append-only moderation audit log. Entries
+// form a hash chain: each Hash covers the entry's fields and the previous
+// entry's Hash, so editing or deleting a row breaks verification of every
+// entry after it.
+type AuditEntry struct {
+	ID         uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	Actor      string    `gorm:"type:varchar(100);not null;index" json:"actor"`
+	Action     string    `gorm:"type:varchar(50);not null;index" json:"action"`
+	TargetType string    `gorm:"type:varchar(20);not null;index:idx_audit_target" json:"target_type"` // "post", "comment", "threshold"
+	TargetID   string    `gorm:"type:varchar(100);not null;index:idx_audit_target" json:"target_id"`
+	Reason     string    `gorm:"type:text" json:"reason"`
+	Details    string    `gorm:"type:text" json:"details,omitempty"`
+	CreatedAt  time.Time `gorm:"not null;index" json:"created_at"`
+	PrevHash   string    `gorm:"type:varchar(64);not null" json:"prev_hash"`
+	Hash       string    `gorm:"type:varchar(64);not null;uniqueIndex" json:"hash"`
+}
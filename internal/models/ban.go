@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Ban modes
+const (
+	BanModeBan    = "ban"    // Posting, commenting and voting are refused
+	BanModeShadow = "shadow" // Activity appears to succeed but is visible only to its author
+)
+
+// Ban restricts an identity, keyed on the same salted IP hash as its content
+type Ban struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	IPHash    string     `gorm:"type:varchar(64);not null;index" json:"ip_hash"`
+	Mode      string     `gorm:"type:varchar(20);not null" json:"mode"`
+	Reason    string     `gorm:"type:text" json:"reason"`
+	CreatedBy string     `gorm:"type:varchar(100);not null" json:"created_by"`
+	CreatedAt time.Time  `gorm:"not null" json:"created_at"`
+	ExpiresAt *time.Time `gorm:"index" json:"expires_at"` // nil for a permanent ban
+	RevokedBy string     `gorm:"type:varchar(100)" json:"revoked_by,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+func (b *Ban) BeforeCreate(tx *gorm.DB) error {
+	if b.ID == uuid.Nil {
+		b.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsValidBanMode checks if the provided ban mode is valid
+func IsValidBanMode(mode string) bool {
+	return mode == BanModeBan || mode == BanModeShadow
+}
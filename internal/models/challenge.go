@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// UsedChallenge records a spent proof-of-work challenge by its nonce until the
+// challenge expires, so that no server instance accepts it a second time
+type UsedChallenge struct {
+	Nonce     string    `gorm:"type:varchar(32);primaryKey" json:"nonce"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+}
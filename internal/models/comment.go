@@ -14,11 +14,28 @@ type Comment struct {
 	CreatedAt time.Time `gorm:"not null" json:"created_at"`
 	IPHash    string    `gorm:"type:varchar(64);not null" json:"-"`
 	Flagged   bool      `gorm:"default:false" json:"flagged"`
+	Status    string    `gorm:"type:varchar(20);not null;default:'visible';index" json:"status"`
+	Shadowed  bool      `gorm:"not null;default:false" json:"-"` // Author is shadowbanned; only they see it
+
+	// Set by content filter rules with the warn action
+	ContentWarning string `gorm:"type:varchar(255)" json:"content_warning,omitempty"`
+
+	// Links found in the stored text
+	Links []Link `gorm:"type:text;serializer:json" json:"links,omitempty"`
+
+	// Toxicity classifier output, shown to moderators only
+	ToxicityScore  float64  `gorm:"not null;default:0" json:"-"`
+	ToxicityLabels []string `gorm:"type:text;serializer:json" json:"-"`
+
+	// SHA-256 of the management token handed to the author on creation
+	ManageTokenHash string `gorm:"type:varchar(64)" json:"-"`
+	ManageToken     string `gorm:"-" json:"-"` // Plain token, only set on the freshly created item
 	
 	// Vote counts - populated by service layer, not stored in DB
 	Upvotes     int64  `gorm:"-" json:"upvotes"`
 	Downvotes   int64  `gorm:"-" json:"downvotes"`
 	UserVote    string `gorm:"-" json:"user_vote"`
+	VotesHidden bool   `gorm:"-" json:"votes_hidden"`
 	
 	// Foreign key relationship
 	Post Post `gorm:"foreignKey:PostID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Filter rule match types
+const (
+	FilterMatchKeyword = "keyword" // Pattern is a comma-separated list of words or phrases
+	FilterMatchRegex   = "regex"   // Pattern is a regular expression
+)
+
+// Fields a filter rule applies to
+const (
+	FilterFieldTitle   = "title"
+	FilterFieldContent = "content"
+	FilterFieldBoth    = "both"
+)
+
+// Filter rule actions
+const (
+	FilterActionReject = "reject" // Refuse the post or comment
+	FilterActionMask   = "mask"   // Replace the matched text with asterisks
+	FilterActionHold   = "hold"   // Store it pending moderator approval
+	FilterActionFlag   = "flag"   // Store it globally flagged
+	FilterActionWarn   = "warn"   // Store it with a content warning
+)
+
+// FilterRule is a moderator-managed keyword or regex rule applied to new posts and comments
+type FilterRule struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	Name      string    `gorm:"type:varchar(100);not null" json:"name" binding:"required,max=100"`
+	MatchType string    `gorm:"type:varchar(20);not null" json:"match_type" binding:"required"`
+	Pattern   string    `gorm:"type:text;not null" json:"pattern" binding:"required"`
+	Field     string    `gorm:"type:varchar(20);not null;default:'both'" json:"field"`
+	Action    string    `gorm:"type:varchar(20);not null" json:"action" binding:"required"`
+	Warning   string    `gorm:"type:varchar(255)" json:"warning,omitempty"` // Label shown for the warn action
+	Enabled   bool      `gorm:"not null" json:"enabled"`
+	UpdatedBy string    `gorm:"type:varchar(100)" json:"updated_by"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+	UpdatedAt time.Time `gorm:"not null" json:"updated_at"`
+}
+
+func (r *FilterRule) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsValidFilterAction checks if the provided filter action is valid
+func IsValidFilterAction(action string) bool {
+	switch action {
+	case FilterActionReject, FilterActionMask, FilterActionHold, FilterActionFlag, FilterActionWarn:
+		return true
+	}
+	return false
+}
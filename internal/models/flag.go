@@ -13,7 +13,7 @@ const (
 	FlagTypeComment = "comment"
 )
 
-// Flag reasons
+// Built-in flag reasons; the full taxonomy lives in the flag_reasons table
 const (
 	FlagReasonSpam         = "spam"
 	FlagReasonInappropriate = "inappropriate"
@@ -21,6 +21,7 @@ const (
 	FlagReasonHateSpeech   = "hate_speech"
 	FlagReasonViolence     = "violence"
 	FlagReasonOther        = "other"
+	FlagReasonReported     = "reported" // Recorded when a flag has no reason
 )
 
 type Flag struct {
@@ -33,6 +34,14 @@ type Flag struct {
 	Details   string     `gorm:"type:text" json:"details"`
 	CreatedAt time.Time  `gorm:"not null" json:"created_at"`
 	
+	// Moderator ruling on this flag (empty while pending)
+	Resolution string     `gorm:"type:varchar(20);not null;default:'';index" json:"resolution"`
+	ResolvedBy string     `gorm:"type:varchar(100)" json:"resolved_by,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+
+	// Weight the flag currently adds after accuracy, age and reason weighting (moderator views only)
+	Weight *float64 `gorm:"-" json:"weight,omitempty"`
+	
 	// Foreign key relationships
 	Post    Post    `gorm:"foreignKey:PostID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
 	Comment Comment `gorm:"foreignKey:CommentID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
@@ -52,17 +61,6 @@ func GetValidFlagTypes() []string {
 	}
 }
 
-func GetValidFlagReasons() []string {
-	return []string{
-		FlagReasonSpam,
-		FlagReasonInappropriate,
-		FlagReasonHateSpeech,
-		FlagReasonHarassment,
-		FlagReasonViolence,
-		FlagReasonOther,
-	}
-}
-
 // Helper methods for creating flags
 func NewPostFlag(postID uuid.UUID, ipHash, reason, details string) *Flag {
 	return &Flag{
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// DefaultLanguage is the language every flag reason must have a label in
+const DefaultLanguage = "en"
+
+// FlagReason is one entry of the moderator-managed flag reason taxonomy. It
+// drives /api/flag-reasons, the validation of new flags, and how much a flag
+// with this reason counts towards the flag thresholds.
+type FlagReason struct {
+	Key             string            `gorm:"type:varchar(50);primaryKey" json:"key"`
+	Labels          map[string]string `gorm:"type:text;not null;serializer:json" json:"labels"` // Language code to label
+	Severity        int               `gorm:"not null;default:1" json:"severity"`               // 1 (minor) to 5 (most severe)
+	RequiresDetails bool              `gorm:"not null;default:false" json:"requires_details"`
+	Weight          float64           `gorm:"not null;default:1" json:"weight"` // Threshold weight of one flag
+	Enabled         bool              `gorm:"not null" json:"enabled"`
+	UpdatedBy       string            `gorm:"type:varchar(100)" json:"updated_by,omitempty"`
+	CreatedAt       time.Time         `gorm:"not null" json:"created_at"`
+	UpdatedAt       time.Time         `gorm:"not null" json:"updated_at"`
+}
+
+// Label returns the label for a language such as "de" or "pt-BR", falling
+// back to the base language, then English, then the key itself
+func (r *FlagReason) Label(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if label, ok := r.Labels[lang]; ok {
+		return label
+	}
+	if base, _, found := strings.Cut(lang, "-"); found {
+		if label, ok := r.Labels[base]; ok {
+			return label
+		}
+	}
+	if label, ok := r.Labels[DefaultLanguage]; ok {
+		return label
+	}
+	return r.Key
+}
+
+// DefaultFlagReasons is the taxonomy seeded into an empty database. Flags
+// submitted without a reason are recorded as FlagReasonReported.
+func DefaultFlagReasons() []FlagReason {
+	return []FlagReason{
+		{Key: FlagReasonSpam, Severity: 1, Weight: 1, Enabled: true, Labels: map[string]string{
+			"en": "Spam or unwanted content",
+			"es": "Spam o contenido no deseado",
+			"fr": "Spam ou contenu indésirable",
+			"de": "Spam oder unerwünschte Inhalte",
+		}},
+		{Key: FlagReasonInappropriate, Severity: 2, Weight: 1, Enabled: true, Labels: map[string]string{
+			"en": "Inappropriate content",
+			"es": "Contenido inapropiado",
+			"fr": "Contenu inapproprié",
+			"de": "Unangemessene Inhalte",
+		}},
+		{Key: FlagReasonHarassment, Severity: 3, Weight: 1, Enabled: true, Labels: map[string]string{
+			"en": "Harassment or bullying",
+			"es": "Acoso o intimidación",
+			"fr": "Harcèlement ou intimidation",
+			"de": "Belästigung oder Mobbing",
+		}},
+		{Key: FlagReasonHateSpeech, Severity: 4, Weight: 1, Enabled: true, Labels: map[string]string{
+			"en": "Hate speech or discrimination",
+			"es": "Discurso de odio o discriminación",
+			"fr": "Discours haineux ou discrimination",
+			"de": "Hassrede oder Diskriminierung",
+		}},
+		{Key: FlagReasonViolence, Severity: 5, Weight: 1, Enabled: true, Labels: map[string]string{
+			"en": "Violence or threats",
+			"es": "Violencia o amenazas",
+			"fr": "Violence ou menaces",
+			"de": "Gewalt oder Drohungen",
+		}},
+		{Key: FlagReasonOther, Severity: 1, Weight: 1, Enabled: true, RequiresDetails: true, Labels: map[string]string{
+			"en": "Other (please specify)",
+			"es": "Otro (especifique)",
+			"fr": "Autre (veuillez préciser)",
+			"de": "Sonstiges (bitte angeben)",
+		}},
+		{Key: FlagReasonReported, Severity: 1, Weight: 1, Enabled: true, Labels: map[string]string{
+			"en": "Report without a specific reason",
+			"es": "Denuncia sin motivo específico",
+			"fr": "Signalement sans motif précis",
+			"de": "Meldung ohne konkreten Grund",
+		}},
+	}
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// FlagThreshold overrides when flagged content is held for review or hidden.
+// A row with an empty Reason applies to the weighted total of all flags on an
+// item; a row with a Reason applies to the flags with that reason only.
+// Thresholds without a row fall back to the environment defaults.
+type FlagThreshold struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	ContentType string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_flag_threshold_scope" json:"content_type"`
+	Reason      string    `gorm:"type:varchar(100);not null;default:'';uniqueIndex:idx_flag_threshold_scope" json:"reason"`
+	HideAt      float64   `gorm:"not null;default:0" json:"hide_at"` // 0 disables hiding
+	HoldAt      float64   `gorm:"not null;default:0" json:"hold_at"` // 0 disables holding
+	UpdatedBy   string    `gorm:"type:varchar(100)" json:"updated_by"`
+	UpdatedAt   time.Time `gorm:"not null" json:"updated_at"`
+}
+
+func (t *FlagThreshold) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// Identity tracks the activity of an anonymous voter identity (a salted IP hash).
+// It never stores the IP itself and is only used to derive a trust score.
+type Identity struct {
+	IPHash        string    `gorm:"type:varchar(64);primaryKey" json:"-"`
+	FirstSeenAt   time.Time `gorm:"not null" json:"first_seen_at"`
+	LastSeenAt    time.Time `gorm:"not null" json:"last_seen_at"`
+	PostCount     int64     `gorm:"not null;default:0" json:"post_count"`
+	CommentCount  int64     `gorm:"not null;default:0" json:"comment_count"`
+	VoteCount     int64     `gorm:"not null;default:0" json:"vote_count"`
+	FlagCount     int64     `gorm:"not null;default:0" json:"flag_count"`
+	FlagsUpheld   int64     `gorm:"not null;default:0" json:"flags_upheld"`
+	FlagsRejected int64     `gorm:"not null;default:0" json:"flags_rejected"`
+}
+
+// Identity activity types
+const (
+	ActivityPost    = "post"
+	ActivityComment = "comment"
+	ActivityVote    = "vote"
+	ActivityFlag    = "flag"
+)
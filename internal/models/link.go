@@ -0,0 +1,12 @@
+package models
+
+// Link is a URL found in the text of a post or comment. Clients render links
+// from these instead of autolinking the raw text themselves.
+type Link struct {
+	URL    string `json:"url"`    // Absolute URL to link to
+	Text   string `json:"text"`   // The link as written in the text
+	Domain string `json:"domain"` // Lowercased host name
+	Field  string `json:"field"`  // "title" or "content"
+	Start  int    `json:"start"`  // Offset of the link text in the field, in Unicode code points
+	End    int    `json:"end"`
+}
@@ -0,0 +1,23 @@
+package models
+
+// Content statuses for posts and comments. Globally flagged content keeps its
+// status and is hidden through the Flagged column instead.
+const (
+	StatusVisible = "visible"
+	StatusHeld    = "held"    // Still shown, but waiting in the review queue
+	StatusPending = "pending" // Hidden until a moderator approves it
+	StatusRemoved = "removed" // Removed by a moderator
+)
+
+// Flag resolutions recorded when a moderator rules on flagged content
+const (
+	FlagResolutionPending   = ""
+	FlagResolutionUpheld    = "upheld"    // Content was removed
+	FlagResolutionDismissed = "dismissed" // Content was approved
+)
+
+// Moderation actions
+const (
+	ModerationApprove = "approve"
+	ModerationRemove  = "remove"
+)
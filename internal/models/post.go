@@ -9,21 +9,66 @@ import (
 
 type Post struct {
 	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
-	Title     string    `gorm:"type:varchar(255);not null" json:"title" binding:"required,max=255"`
-	Content   string    `gorm:"type:text;not null" json:"content" binding:"required,max=5000"`
-	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+	Title     string    `gorm:"type:text;not null" json:"title"` // Limited to 255 grapheme clusters, which may be more code points
+	Content   string    `gorm:"type:text;not null" json:"content"`
+	CreatedAt time.Time `gorm:"not null;index" json:"created_at"`
 	IPHash    string    `gorm:"type:varchar(64);not null" json:"-"`
 	Flagged   bool      `gorm:"default:false" json:"flagged"`
+	Status    string    `gorm:"type:varchar(20);not null;default:'visible';index" json:"status"`
+	Shadowed  bool      `gorm:"not null;default:false" json:"-"` // Author is shadowbanned; only they see it
+
+	// Set by content filter rules with the warn action
+	ContentWarning string `gorm:"type:varchar(255)" json:"content_warning,omitempty"`
+
+	// Links found in the stored text
+	Links []Link `gorm:"type:text;serializer:json" json:"links,omitempty"`
+
+	// Toxicity classifier output, shown to moderators only
+	ToxicityScore  float64  `gorm:"not null;default:0" json:"-"`
+	ToxicityLabels []string `gorm:"type:text;serializer:json" json:"-"`
+
+	// SHA-256 of the management token handed to the author on creation
+	ManageTokenHash string `gorm:"type:varchar(64)" json:"-"`
+	ManageToken     string `gorm:"-" json:"-"` // Plain token, only set on the freshly created item
+
+	// Slow mode: until SlowModeUntil each identity may comment once every
+	// SlowModeSeconds. Switching it off keeps it from restarting automatically
+	// until SlowModeSuppressedUntil.
+	SlowModeUntil           *time.Time `json:"slow_mode_until,omitempty"`
+	SlowModeSeconds         int        `gorm:"not null;default:0" json:"slow_mode_seconds,omitempty"`
+	SlowModeSuppressedUntil *time.Time `json:"-"`
+
+	// SimHash of the normalized text, and the first post of its near-duplicate cluster
+	Fingerprint int64      `gorm:"not null;default:0" json:"-"`
+	DuplicateOf *uuid.UUID `gorm:"type:uuid;index" json:"-"`
+
+	// The fingerprint split into 16-bit bands, indexed for near-duplicate lookups
+	FingerprintBand0 int32 `gorm:"not null;default:0;index" json:"-"`
+	FingerprintBand1 int32 `gorm:"not null;default:0;index" json:"-"`
+	FingerprintBand2 int32 `gorm:"not null;default:0;index" json:"-"`
+	FingerprintBand3 int32 `gorm:"not null;default:0;index" json:"-"`
 	
 	// Vote counts - populated by service layer, not stored in DB
 	Upvotes     int64  `gorm:"-" json:"upvotes"`
 	Downvotes   int64  `gorm:"-" json:"downvotes"`
 	UserVote    string `gorm:"-" json:"user_vote"`
+	VotesHidden bool   `gorm:"-" json:"votes_hidden"`
+}
+
+// FingerprintBands splits a fingerprint into its four 16-bit bands, lowest first
+func FingerprintBands(fingerprint int64) [4]int32 {
+	var bands [4]int32
+	for i := range bands {
+		bands[i] = int32(uint64(fingerprint) >> (16 * uint(i)) & 0xFFFF)
+	}
+	return bands
 }
 
 func (p *Post) BeforeCreate(tx *gorm.DB) error {
 	if p.ID == uuid.Nil {
 		p.ID = uuid.New()
 	}
+	bands := FingerprintBands(p.Fingerprint)
+	p.FingerprintBand0, p.FingerprintBand1, p.FingerprintBand2, p.FingerprintBand3 = bands[0], bands[1], bands[2], bands[3]
 	return nil
 } 
\ No newline at end of file
@@ -0,0 +1,9 @@
+package models
+
+// RateLimitBucket holds the rate limit state of one client and action, shared
+// by all server instances. TAT is the GCRA theoretical arrival time in Unix
+// nanoseconds; a bucket whose TAT has passed is full and can be deleted.
+type RateLimitBucket struct {
+	Key string `gorm:"type:varchar(128);primaryKey" json:"key"`
+	TAT int64  `gorm:"column:tat;not null;index" json:"tat"`
+}
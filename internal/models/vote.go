@@ -14,6 +14,7 @@ type Vote struct {
 	VoteType  string     `gorm:"type:varchar(10);not null" json:"vote_type"` // "upvote" or "downvote"
 	IPHash    string     `gorm:"type:varchar(64);not null" json:"-"`
 	CreatedAt time.Time  `gorm:"not null" json:"created_at"`
+	Shadowed  bool       `gorm:"not null;default:false" json:"-"` // Voter is shadowbanned; not counted for others
 	
 	// Foreign key relationships
 	Post    Post    `gorm:"foreignKey:PostID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
@@ -31,6 +32,7 @@ func (v *Vote) BeforeCreate(tx *gorm.DB) error {
 const (
 	VoteTypeUpvote   = "upvote"
 	VoteTypeDownvote = "downvote"
+	VoteTypeNone     = "none" // Used by explicit vote updates to clear a vote
 )
 
 // ValidVoteTypes defines the allowed vote types
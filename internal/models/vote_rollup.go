@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PostVoteRollup holds hourly vote counts for a post, keyed by the UTC start of the hour.
+// It is maintained by the vote service alongside the votes table so that vote
+// history can be served without scanning every vote.
+type PostVoteRollup struct {
+	PostID      uuid.UUID `gorm:"type:uuid;primaryKey" json:"post_id"`
+	BucketStart time.Time `gorm:"primaryKey" json:"bucket_start"`
+	Upvotes     int64     `gorm:"not null;default:0" json:"upvotes"`
+	Downvotes   int64     `gorm:"not null;default:0" json:"downvotes"`
+
+	// Foreign key relationship
+	Post Post `gorm:"foreignKey:PostID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// Vote history bucket sizes
+const (
+	VoteBucketHour = "hour"
+	VoteBucketDay  = "day"
+)
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"reveal/internal/db"
+	"reveal/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxAppealStatement is the longest statement an author can attach to an appeal
+const maxAppealStatement = 500
+
+// AppealService lets authors appeal hidden content and moderators decide on appeals
+type AppealService struct {
+	moderation *ModerationService
+	audit      *AuditService
+}
+
+func NewAppealService() *AppealService {
+	return &AppealService{
+		moderation: NewModerationService(),
+		audit:      NewAuditService(),
+	}
+}
+
+// authorize checks the management token for a post or comment and reports whether it is hidden by flags
+func (s *AppealService) authorize(contentType string, id uuid.UUID, token string) (hidden bool, err error) {
+	var tokenHash string
+	switch contentType {
+	case models.FlagTypePost:
+		var post models.Post
+		if err := db.DB.First(&post, "id = ?", id).Error; err != nil {
+			return false, fmt.Errorf("post not found")
+		}
+		tokenHash, hidden = post.ManageTokenHash, post.Flagged && post.Status != models.StatusRemoved
+	case models.FlagTypeComment:
+		var comment models.Comment
+		if err := db.DB.First(&comment, "id = ?", id).Error; err != nil {
+			return false, fmt.Errorf("comment not found")
+		}
+		tokenHash, hidden = comment.ManageTokenHash, comment.Flagged && comment.Status != models.StatusRemoved
+	default:
+		return false, fmt.Errorf("invalid content type")
+	}
+
+	if !manageTokenMatches(tokenHash, token) {
+		return false, fmt.Errorf("invalid management token")
+	}
+	return hidden, nil
+}
+
+// FileAppeal records the author's appeal against flags hiding their post or comment
+func (s *AppealService) FileAppeal(contentType string, id uuid.UUID, token, statement string) (*models.Appeal, error) {
+	statement = strings.TrimSpace(statement)
+	if statement == "" {
+		return nil, fmt.Errorf("statement cannot be empty")
+	}
+	if len([]rune(statement)) > maxAppealStatement {
+		return nil, fmt.Errorf("statement too long")
+	}
+
+	hidden, err := s.authorize(contentType, id, token)
+	if err != nil {
+		return nil, err
+	}
+	if !hidden {
+		return nil, fmt.Errorf("content not hidden")
+	}
+
+	var count int64
+	db.DB.Model(&models.Appeal{}).Where("content_type = ? AND target_id = ?", contentType, id).Count(&count)
+	if count > 0 {
+		return nil, fmt.Errorf("appeal already filed")
+	}
+
+	appeal := &models.Appeal{
+		ContentType: contentType,
+		TargetID:    id,
+		Statement:   statement,
+		Status:      models.AppealPending,
+		CreatedAt:   time.Now(),
+	}
+	if err := db.DB.Create(appeal).Error; err != nil {
+		return nil, err
+	}
+	return appeal, nil
+}
+
+// GetAppeal returns the appeal on a post or comment to the holder of its management token
+func (s *AppealService) GetAppeal(contentType string, id uuid.UUID, token string) (*models.Appeal, error) {
+	if _, err := s.authorize(contentType, id, token); err != nil {
+		return nil, err
+	}
+
+	var appeal models.Appeal
+	if err := db.DB.Where("content_type = ? AND target_id = ?", contentType, id).First(&appeal).Error; err != nil {
+		return nil, fmt.Errorf("appeal not found")
+	}
+	return &appeal, nil
+}
+
+// ListAppeals returns appeals with the given status ("" for all), oldest first
+func (s *AppealService) ListAppeals(status string, limit int) ([]models.Appeal, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	query := db.DB.Order("created_at ASC").Limit(limit)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var appeals []models.Appeal
+	err := query.Find(&appeals).Error
+	return appeals, err
+}
+
+// GrantAppeal restores the appealed content. Its flags are dismissed, so the
+// flags that hid it can no longer count towards hiding it again.
+func (s *AppealService) GrantAppeal(appealID uuid.UUID, actor, decision string) error {
+	appeal, err := s.pendingAppeal(appealID)
+	if err != nil {
+		return err
+	}
+
+	return s.moderation.moderate(appeal.ContentType, appeal.TargetID, models.ModerationApprove, actor, decision,
+		func(tx *gorm.DB) error {
+			return s.decide(tx, appeal, models.AppealGranted, models.AuditAppealGrant, actor, decision)
+		})
+}
+
+// DenyAppeal keeps the appealed content hidden
+func (s *AppealService) DenyAppeal(appealID uuid.UUID, actor, decision string) error {
+	appeal, err := s.pendingAppeal(appealID)
+	if err != nil {
+		return err
+	}
+
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		return s.decide(tx, appeal, models.AppealDenied, models.AuditAppealDeny, actor, decision)
+	})
+}
+
+func (s *AppealService) pendingAppeal(appealID uuid.UUID) (*models.Appeal, error) {
+	var appeal models.Appeal
+	if err := db.DB.First(&appeal, "id = ?", appealID).Error; err != nil {
+		return nil, fmt.Errorf("appeal not found")
+	}
+	if appeal.Status != models.AppealPending {
+		return nil, fmt.Errorf("appeal already decided")
+	}
+	return &appeal, nil
+}
+
+// decide records the outcome of a pending appeal. The status condition keeps
+// two moderators from deciding the same appeal.
+func (s *AppealService) decide(tx *gorm.DB, appeal *models.Appeal, status, auditAction, actor, decision string) error {
+	now := time.Now()
+	result := tx.Model(&models.Appeal{}).
+		Where("id = ? AND status = ?", appeal.ID, models.AppealPending).
+		Updates(map[string]interface{}{
+			"status":     status,
+			"decision":   decision,
+			"decided_by": actor,
+			"decided_at": &now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("appeal already decided")
+	}
+
+	return s.audit.Record(tx, actor, auditAction, appeal.ContentType, appeal.TargetID.String(), decision, appeal.ID.String())
+}
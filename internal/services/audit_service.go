@@ -0,0 +1,170 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"reveal/internal/db"
+	"reveal/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// auditLockKey is the postgres advisory lock that serializes appends to the audit chain.
+// It is held until the transaction ends, so a transaction may record several entries.
+// SQLite allows a single writer, so a stale read of the chain head fails to
+// commit rather than forking the chain.
+const auditLockKey = 7340021
+
+// AuditFilter narrows an audit log query. Zero values match everything.
+type AuditFilter struct {
+	Actor      string
+	Action     string
+	TargetType string
+	TargetID   string
+	Since      time.Time
+	Until      time.Time
+	BeforeID   uint64 // Return entries older than this ID, for paging
+	Limit      int
+}
+
+// AuditVerification is the result of checking the audit hash chain
+type AuditVerification struct {
+	Valid    bool   `json:"valid"`
+	Entries  int64  `json:"entries"`
+	BrokenAt uint64 `json:"broken_at,omitempty"` // First entry whose hash does not match
+}
+
+// AuditService appends to and reads the moderation audit log
+type AuditService struct{}
+
+func NewAuditService() *AuditService {
+	return &AuditService{}
+}
+
+// Record appends an entry to the audit log. Pass the transaction that applies
+// the audited change so the entry is written if and only if the change is.
+func (s *AuditService) Record(tx *gorm.DB, actor, action, targetType, targetID, reason, details string) error {
+	if tx.Dialector.Name() == "postgres" {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", auditLockKey).Error; err != nil {
+			return err
+		}
+	}
+
+	var last models.AuditEntry
+	prevHash := ""
+	if err := tx.Order("id DESC").Limit(1).Find(&last).Error; err != nil {
+		return err
+	}
+	if last.ID != 0 {
+		prevHash = last.Hash
+	}
+
+	entry := models.AuditEntry{
+		Actor:      actor,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Reason:     reason,
+		Details:    details,
+		// Postgres keeps microseconds; truncate so the stored value hashes the same
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+		PrevHash:  prevHash,
+	}
+	entry.Hash = auditHash(&entry)
+
+	return tx.Create(&entry).Error
+}
+
+// List returns audit entries matching the filter, newest first
+func (s *AuditService) List(filter AuditFilter) ([]models.AuditEntry, error) {
+	if filter.Limit <= 0 || filter.Limit > 500 {
+		filter.Limit = 100
+	}
+
+	query := db.DB.Model(&models.AuditEntry{})
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until)
+	}
+	if filter.BeforeID > 0 {
+		query = query.Where("id < ?", filter.BeforeID)
+	}
+
+	var entries []models.AuditEntry
+	err := query.Order("id DESC").Limit(filter.Limit).Find(&entries).Error
+	return entries, err
+}
+
+// Verify walks the whole audit log and checks every link of the hash chain
+func (s *AuditService) Verify() (*AuditVerification, error) {
+	const batchSize = 1000
+
+	result := &AuditVerification{Valid: true}
+	prevHash := ""
+	var lastID uint64
+
+	for {
+		var batch []models.AuditEntry
+		if err := db.DB.Where("id > ?", lastID).Order("id ASC").Limit(batchSize).Find(&batch).Error; err != nil {
+			return nil, err
+		}
+
+		for i := range batch {
+			entry := &batch[i]
+			if entry.PrevHash != prevHash || entry.Hash != auditHash(entry) {
+				result.Valid = false
+				result.BrokenAt = entry.ID
+				return result, nil
+			}
+			prevHash = entry.Hash
+			lastID = entry.ID
+			result.Entries++
+		}
+
+		if len(batch) < batchSize {
+			return result, nil
+		}
+	}
+}
+
+// auditHash computes the chained hash of an entry
+func auditHash(entry *models.AuditEntry) string {
+	fields := []string{
+		entry.PrevHash,
+		entry.Actor,
+		entry.Action,
+		entry.TargetType,
+		entry.TargetID,
+		entry.Reason,
+		entry.Details,
+		entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x1f")))
+	return hex.EncodeToString(sum[:])
+}
+
+// flagOutcomeReason describes which threshold an automatic decision crossed
+func flagOutcomeReason(outcome FlagOutcome) string {
+	if outcome.Trigger != "" {
+		return fmt.Sprintf("%q flags reached their threshold", outcome.Trigger)
+	}
+	return fmt.Sprintf("weighted flag total reached %.2f", outcome.Weight)
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"reveal/internal/db"
+	"reveal/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BanTarget identifies the identity to ban: an IP hash directly, or the
+// author of a post or comment. Exactly one field should be set.
+type BanTarget struct {
+	IPHash    string     `json:"ip_hash"`
+	PostID    *uuid.UUID `json:"post_id"`
+	CommentID *uuid.UUID `json:"comment_id"`
+}
+
+// BanService manages bans and shadowbans and enforces them on new activity
+type BanService struct {
+	audit *AuditService
+}
+
+func NewBanService() *BanService {
+	return &BanService{
+		audit: NewAuditService(),
+	}
+}
+
+// activeBans restricts a query to bans that are neither revoked nor expired
+func activeBans(query *gorm.DB) *gorm.DB {
+	return query.Where("revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", time.Now())
+}
+
+// Check enforces bans on an identity about to post, comment or vote. It
+// returns an "identity banned" error for a ban, and shadow=true when the new
+// activity must be stored shadowed.
+func (s *BanService) Check(ipHash string) (shadow bool, err error) {
+	var bans []models.Ban
+	if err := activeBans(db.DB.Where("ip_hash = ?", ipHash)).Find(&bans).Error; err != nil {
+		return false, err
+	}
+
+	for _, ban := range bans {
+		if ban.Mode == models.BanModeBan {
+			return false, fmt.Errorf("identity banned")
+		}
+		shadow = true
+	}
+	return shadow, nil
+}
+
+// ResolveTarget returns the IP hash a ban target refers to
+func (s *BanService) ResolveTarget(target BanTarget) (string, error) {
+	switch {
+	case target.IPHash != "":
+		return target.IPHash, nil
+	case target.PostID != nil:
+		var post models.Post
+		if err := db.DB.Select("ip_hash").First(&post, "id = ?", *target.PostID).Error; err != nil {
+			return "", fmt.Errorf("post not found")
+		}
+		return post.IPHash, nil
+	case target.CommentID != nil:
+		var comment models.Comment
+		if err := db.DB.Select("ip_hash").First(&comment, "id = ?", *target.CommentID).Error; err != nil {
+			return "", fmt.Errorf("comment not found")
+		}
+		return comment.IPHash, nil
+	}
+	return "", fmt.Errorf("invalid ban target")
+}
+
+// CreateBan bans or shadowbans an identity. A zero duration bans permanently.
+func (s *BanService) CreateBan(target BanTarget, mode, reason string, duration time.Duration, actor string) (*models.Ban, error) {
+	if !models.IsValidBanMode(mode) {
+		return nil, fmt.Errorf("invalid ban mode")
+	}
+	if duration < 0 {
+		return nil, fmt.Errorf("invalid ban duration")
+	}
+
+	ipHash, err := s.ResolveTarget(target)
+	if err != nil {
+		return nil, err
+	}
+
+	ban := &models.Ban{
+		IPHash:    ipHash,
+		Mode:      mode,
+		Reason:    reason,
+		CreatedBy: actor,
+		CreatedAt: time.Now(),
+	}
+	details := "permanent"
+	if duration > 0 {
+		expiresAt := ban.CreatedAt.Add(duration)
+		ban.ExpiresAt = &expiresAt
+		details = "expires " + expiresAt.UTC().Format(time.RFC3339)
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(ban).Error; err != nil {
+			return err
+		}
+		return s.audit.Record(tx, actor, models.AuditBan, "identity", ipHash, reason, mode+", "+details)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ban, nil
+}
+
+// RevokeBan lifts a ban before it expires
+func (s *BanService) RevokeBan(banID uuid.UUID, actor, reason string) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		var ban models.Ban
+		if err := tx.First(&ban, "id = ?", banID).Error; err != nil || ban.RevokedAt != nil {
+			return fmt.Errorf("ban not found")
+		}
+
+		now := time.Now()
+		if err := tx.Model(&ban).Updates(map[string]interface{}{
+			"revoked_by": actor,
+			"revoked_at": &now,
+		}).Error; err != nil {
+			return err
+		}
+		return s.audit.Record(tx, actor, models.AuditUnban, "identity", ban.IPHash, reason, ban.Mode)
+	})
+}
+
+// ListBans returns bans newest first; only active ones unless includeInactive is set
+func (s *BanService) ListBans(includeInactive bool) ([]models.Ban, error) {
+	query := db.DB.Order("created_at DESC")
+	if !includeInactive {
+		query = activeBans(query)
+	}
+
+	var bans []models.Ban
+	err := query.Find(&bans).Error
+	return bans, err
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math"
+	"math/bits"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"reveal/internal/db"
+	"reveal/internal/models"
+
+	"gorm.io/gorm/clause"
+)
+
+// ChallengeAlgorithm names the hash clients must use to solve a challenge
+const ChallengeAlgorithm = "sha256"
+
+// Challenge is a hashcash-style proof-of-work puzzle. A client solves it by
+// finding a counter such that sha256(token + ":" + counter) starts with
+// Difficulty zero bits, and sends "token:counter" with its write request.
+type Challenge struct {
+	Token      string    `json:"token"`
+	Algorithm  string    `json:"algorithm"`
+	Difficulty int       `json:"difficulty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// ChallengeService issues and verifies proof-of-work challenges for anonymous
+// writes. Challenges are signed and bound to the client, so verifying one needs
+// no stored state beyond a replay cache: the used_challenges table, which every
+// instance shares and which keeps each nonce until its challenge has expired.
+type ChallengeService struct {
+	enabled bool
+	secret  []byte
+	ttl     time.Duration
+	trust   *TrustService
+
+	loadMu   sync.Mutex
+	loadBits int
+	loadAt   time.Time
+}
+
+func NewChallengeService() *ChallengeService {
+	secret := os.Getenv("POW_SECRET")
+	if secret == "" {
+		secret = os.Getenv("SALT_KEY")
+	}
+	if secret == "" {
+		secret = "default_salt_change_in_production"
+	}
+
+	return &ChallengeService{
+		enabled: os.Getenv("POW_ENABLED") == "true",
+		secret:  []byte(secret),
+		ttl:     time.Duration(envFloat("POW_TTL_MINUTES", 5) * float64(time.Minute)),
+		trust:   NewTrustService(),
+	}
+}
+
+var challengeJanitorOnce sync.Once
+
+// startChallengeJanitor deletes expired nonces from the replay cache every
+// minute, once per process
+func startChallengeJanitor() {
+	challengeJanitorOnce.Do(func() {
+		startJanitor(time.Minute, func(now time.Time) {
+			if err := db.DB.Where("expires_at < ?", now).Delete(&models.UsedChallenge{}).Error; err != nil {
+				log.Printf("Warning: Failed to sweep used challenges: %v", err)
+			}
+		})
+	})
+}
+
+// Enabled reports whether write requests must carry a solved challenge (POW_ENABLED)
+func (s *ChallengeService) Enabled() bool {
+	return s.enabled
+}
+
+// Issue creates a challenge for the client. Its difficulty starts at
+// POW_DIFFICULTY bits, gains a bit for every POW_LOAD_STEP posts and comments
+// in the last POW_LOAD_WINDOW_MINUTES, moves by up to POW_TRUST_BITS in either
+// direction with the identity's trust score, and stays within
+// POW_MIN_DIFFICULTY and POW_MAX_DIFFICULTY.
+func (s *ChallengeService) Issue(clientIP string) (*Challenge, error) {
+	difficulty := envInt("POW_DIFFICULTY", 16) + s.loadDifficulty()
+	if s.trust.Enabled() {
+		// Fully trusted identities solve easier puzzles, unknown ones harder
+		score := s.trust.TrustScore(hashClientIP(clientIP))
+		difficulty += int(math.Round(envFloat("POW_TRUST_BITS", 2) * (1 - 2*score)))
+	}
+	difficulty = max(envInt("POW_MIN_DIFFICULTY", 8), min(envInt("POW_MAX_DIFFICULTY", 22), difficulty))
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(s.ttl).Truncate(time.Second)
+	payload := fmt.Sprintf("%d.%d.%s", expiresAt.Unix(), difficulty, hex.EncodeToString(nonce))
+	return &Challenge{
+		Token:      payload + "." + s.sign(payload, RateLimitKey(clientIP)),
+		Algorithm:  ChallengeAlgorithm,
+		Difficulty: difficulty,
+		ExpiresAt:  expiresAt,
+	}, nil
+}
+
+// Verify checks a "token:counter" solution sent by the client. A challenge
+// can be used once; the replay cache remembers it until it has expired. If the
+// cache cannot be checked the solution is refused.
+func (s *ChallengeService) Verify(clientIP, solution string) error {
+	if solution == "" {
+		return fmt.Errorf("challenge required")
+	}
+
+	token, counter, found := strings.Cut(solution, ":")
+	parts := strings.Split(token, ".")
+	if !found || counter == "" || len(counter) > 64 || len(parts) != 4 {
+		return fmt.Errorf("invalid challenge")
+	}
+
+	payload := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(parts[3]), []byte(s.sign(payload, RateLimitKey(clientIP)))) {
+		return fmt.Errorf("invalid challenge")
+	}
+
+	expires, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return fmt.Errorf("challenge expired")
+	}
+	difficulty, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return fmt.Errorf("invalid challenge")
+	}
+
+	if leadingZeroBits(sha256.Sum256([]byte(solution))) < difficulty {
+		return fmt.Errorf("insufficient work")
+	}
+
+	// Check for replays last, so that failed attempts do not use up the challenge
+	startChallengeJanitor()
+	result := db.DB.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.UsedChallenge{Nonce: parts[2], ExpiresAt: time.Unix(expires, 0)})
+	if result.Error != nil {
+		log.Printf("Warning: Failed to record used challenge: %v", result.Error)
+		return fmt.Errorf("challenge check failed")
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("challenge already used")
+	}
+	return nil
+}
+
+// sign returns the MAC binding a challenge payload to a client, by the same
+// key rate limits use, so IPv6 clients may change address within their /64
+func (s *ChallengeService) sign(payload, clientKey string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload + "." + clientKey))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// loadDifficulty returns the extra bits for recent write volume across all
+// instances, recounted at most every 10 seconds
+func (s *ChallengeService) loadDifficulty() int {
+	s.loadMu.Lock()
+	defer s.loadMu.Unlock()
+
+	if time.Since(s.loadAt) < 10*time.Second {
+		return s.loadBits
+	}
+
+	step := envInt("POW_LOAD_STEP", 200)
+	if step <= 0 {
+		return 0
+	}
+	since := time.Now().Add(-time.Duration(envFloat("POW_LOAD_WINDOW_MINUTES", 10) * float64(time.Minute)))
+	var posts, comments int64
+	db.DB.Model(&models.Post{}).Where("created_at > ?", since).Count(&posts)
+	db.DB.Model(&models.Comment{}).Where("created_at > ?", since).Count(&comments)
+
+	s.loadBits = int((posts + comments) / int64(step))
+	s.loadAt = time.Now()
+	return s.loadBits
+}
+
+// leadingZeroBits counts the zero bits at the start of a hash
+func leadingZeroBits(sum [sha256.Size]byte) int {
+	zeros := 0
+	for _, b := range sum {
+		if b != 0 {
+			return zeros + bits.LeadingZeros8(b)
+		}
+		zeros += 8
+	}
+	return zeros
+}
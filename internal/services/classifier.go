@@ -0,0 +1,393 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Classifier backends (CLASSIFIER)
+const (
+	ClassifierLexicon = "lexicon" // Built-in word lists and heuristics, fully offline (default)
+	ClassifierHTTP    = "http"    // A model server at CLASSIFIER_URL
+	ClassifierOff     = "off"
+)
+
+// Labels of the built-in lexicon
+const (
+	LabelProfanity = "profanity"
+	LabelInsult    = "insult"
+	LabelThreat    = "threat"
+	LabelSelfHarm  = "self_harm"
+	LabelSexual    = "sexual"
+)
+
+// Classification is a classifier's verdict on a submission
+type Classification struct {
+	Score  float64  `json:"score"`  // 0 (harmless) to 1 (certainly toxic)
+	Labels []string `json:"labels"` // Categories found, most significant first
+}
+
+// ContentClassifier scores submitted text for toxicity. Implementations must
+// be safe for concurrent use.
+type ContentClassifier interface {
+	Classify(title, content string) (*Classification, error)
+}
+
+// NewContentClassifier returns the classifier selected by CLASSIFIER, or nil
+// when classification is switched off
+func NewContentClassifier() ContentClassifier {
+	switch strings.ToLower(os.Getenv("CLASSIFIER")) {
+	case ClassifierOff:
+		return nil
+	case ClassifierHTTP:
+		timeout := time.Duration(envInt("CLASSIFIER_TIMEOUT_MS", 2000)) * time.Millisecond
+		return NewHTTPClassifier(os.Getenv("CLASSIFIER_URL"), timeout, NewLexiconClassifier())
+	default:
+		return NewLexiconClassifier()
+	}
+}
+
+// classifyContent runs a classifier without letting it block submissions:
+// when there is none or it fails, the text counts as harmless
+func classifyContent(classifier ContentClassifier, title, content string) *Classification {
+	if classifier == nil {
+		return &Classification{}
+	}
+	result, err := classifier.Classify(title, content)
+	if err != nil {
+		log.Printf("Classifier failed: %v", err)
+		return &Classification{}
+	}
+	return result
+}
+
+// HoldReason returns why the classified text should be held for review, or
+// "" unless its score is above CLASSIFIER_HOLD_SCORE (0 disables holding).
+// The default needs more than one lexicon signal: a lone threat phrase such
+// as "this heat will kill you" scores 0.8, or 0.86 when aimed at "you".
+func (c *Classification) HoldReason() string {
+	threshold := envFloat("CLASSIFIER_HOLD_SCORE", 0.9)
+	if threshold <= 0 || c.Score <= threshold {
+		return ""
+	}
+	return fmt.Sprintf("classifier score %.2f (%s)", c.Score, strings.Join(c.Labels, ", "))
+}
+
+// labelWarnings are the content warnings shown for the built-in labels
+var labelWarnings = map[string]string{
+	LabelProfanity: "Strong language",
+	LabelInsult:    "Insults",
+	LabelThreat:    "Threats of violence",
+	LabelSelfHarm:  "Self-harm",
+	LabelSexual:    "Sexual content",
+}
+
+// Warnings returns the content warnings for the classified text, if its
+// score reaches CLASSIFIER_WARN_SCORE (0 disables warnings)
+func (c *Classification) Warnings() []string {
+	threshold := envFloat("CLASSIFIER_WARN_SCORE", 0.5)
+	if threshold <= 0 || c.Score < threshold {
+		return nil
+	}
+
+	var warnings []string
+	for _, label := range c.Labels {
+		warning, ok := labelWarnings[label]
+		if !ok {
+			warning = strings.ReplaceAll(label, "_", " ")
+		}
+		warnings = append(warnings, warning)
+	}
+	return warnings
+}
+
+// mergeWarnings adds warnings to a comma-separated content warning, keeping
+// it within the 255 characters of the stored column
+func mergeWarnings(warning string, extra []string) string {
+	var warnings []string
+	if warning != "" {
+		warnings = strings.Split(warning, ", ")
+	}
+	for _, w := range extra {
+		if !containsString(warnings, w) {
+			warnings = append(warnings, w)
+		}
+	}
+
+	merged := strings.Join(warnings, ", ")
+	if runes := []rune(merged); len(runes) > 255 {
+		merged = string(runes[:255])
+	}
+	return merged
+}
+
+// lexiconCategory is one label of the lexicon with the terms that indicate it
+type lexiconCategory struct {
+	label  string
+	weight float64 // Contribution of one matching term to the score
+	terms  []string
+	re     *regexp.Regexp
+}
+
+// defaultLexicon is the built-in lexicon. Terms are matched as words on the
+// homoglyph-folded skeleton of the text, with common inflections.
+var defaultLexicon = []lexiconCategory{
+	{label: LabelProfanity, weight: 0.25, terms: []string{
+		"fuck", "shit", "bitch", "bastard", "asshole", "dick", "piss", "crap", "bullshit", "motherfucker",
+	}},
+	{label: LabelInsult, weight: 0.45, terms: []string{
+		"idiot", "moron", "stupid", "loser", "dumbass", "pathetic", "worthless", "imbecile", "scum",
+		"useless", "ugly", "disgusting", "freak", "clown", "shut up",
+	}},
+	{label: LabelThreat, weight: 0.8, terms: []string{
+		"kill you", "i will kill", "hurt you", "beat you up", "shoot you", "stab you",
+		"find where you live", "know where you live", "you will die", "you are dead", "watch your back",
+	}},
+	{label: LabelSelfHarm, weight: 0.6, terms: []string{
+		"kill myself", "end my life", "want to die", "suicide", "cut myself", "hurt myself",
+	}},
+	{label: LabelSexual, weight: 0.35, terms: []string{
+		"porn", "nude", "nsfw", "horny", "sexting", "blowjob",
+	}},
+}
+
+// secondPersonPattern matches words addressing the reader, which make
+// insults and threats personal
+var secondPersonPattern = regexp.MustCompile(`\b(?:you|your|youre|yourself|u|ur)\b`)
+
+// Weights of the heuristics that raise the lexicon score
+const (
+	targetedWeight = 0.3 // Insults or threats aimed at "you"
+	shoutingWeight = 0.1 // Mostly capital letters
+)
+
+// LexiconClassifier scores text with word lists and simple heuristics. It
+// needs no network access. CLASSIFIER_LEXICON names an optional file of
+// extra "label,weight,term" lines.
+type LexiconClassifier struct {
+	categories []lexiconCategory
+}
+
+func NewLexiconClassifier() *LexiconClassifier {
+	categories := make([]lexiconCategory, len(defaultLexicon))
+	copy(categories, defaultLexicon)
+
+	if path := os.Getenv("CLASSIFIER_LEXICON"); path != "" {
+		extra, err := readLexicon(path)
+		if err != nil {
+			log.Printf("Classifier: ignoring lexicon %s: %v", path, err)
+		}
+		categories = mergeLexicon(categories, extra)
+	}
+
+	for i := range categories {
+		categories[i].re = compileLexiconTerms(categories[i].terms)
+	}
+	return &LexiconClassifier{categories: categories}
+}
+
+// readLexicon parses a lexicon file. Blank lines and lines starting with #
+// are skipped; terms may contain spaces.
+func readLexicon(path string) ([]lexiconCategory, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var categories []lexiconCategory
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(text, ",", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("line %d: expected label,weight,term", line)
+		}
+		weight, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil || weight <= 0 || weight >= 1 {
+			return nil, fmt.Errorf("line %d: weight must be between 0 and 1", line)
+		}
+		categories = mergeLexicon(categories, []lexiconCategory{{
+			label:  strings.TrimSpace(parts[0]),
+			weight: weight,
+			terms:  []string{strings.TrimSpace(parts[2])},
+		}})
+	}
+	return categories, scanner.Err()
+}
+
+// mergeLexicon adds the terms of extra to the categories with the same
+// label, taking over their weight, and appends new labels
+func mergeLexicon(categories, extra []lexiconCategory) []lexiconCategory {
+	for _, e := range extra {
+		merged := false
+		for i := range categories {
+			if categories[i].label == e.label {
+				categories[i].weight = e.weight
+				categories[i].terms = append(append([]string{}, categories[i].terms...), e.terms...)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			categories = append(categories, e)
+		}
+	}
+	return categories
+}
+
+// compileLexiconTerms builds a pattern matching any of the terms as whole
+// words, allowing common suffixes and any whitespace between words
+func compileLexiconTerms(terms []string) *regexp.Regexp {
+	alternatives := make([]string, 0, len(terms))
+	for _, term := range terms {
+		folded, _ := skeleton(term)
+		var words []string
+		for _, word := range strings.Fields(folded) {
+			words = append(words, regexp.QuoteMeta(word))
+		}
+		if len(words) > 0 {
+			alternatives = append(alternatives, strings.Join(words, `\s+`))
+		}
+	}
+	if len(alternatives) == 0 {
+		return nil
+	}
+	return regexp.MustCompile(wordPattern(`(?:` + strings.Join(alternatives, "|") + `)(?:s|es|ed|ing|er|ers|y)?`))
+}
+
+// Classify combines the evidence as independent signals: each matched term
+// and heuristic adds its weight to the score as 1 - Π(1 - weight). Repeats of
+// a category count up to three times.
+func (c *LexiconClassifier) Classify(title, content string) (*Classification, error) {
+	text := title + "\n" + content
+	folded, _ := skeleton(text)
+
+	type found struct {
+		label string
+		score float64
+	}
+	var matches []found
+	remaining := 1.0
+	personal := false
+	for _, category := range c.categories {
+		if category.re == nil {
+			continue
+		}
+		hits := len(findWords(category.re, folded, 3))
+		if hits == 0 {
+			continue
+		}
+		share := 1 - math.Pow(1-category.weight, float64(hits))
+		remaining *= 1 - share
+		matches = append(matches, found{label: category.label, score: share})
+		if category.label == LabelInsult || category.label == LabelThreat {
+			personal = true
+		}
+	}
+
+	if personal && secondPersonPattern.MatchString(folded) {
+		remaining *= 1 - targetedWeight
+	}
+	if len(matches) > 0 && isShouting(text) {
+		remaining *= 1 - shoutingWeight
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	labels := make([]string, 0, len(matches))
+	for _, m := range matches {
+		labels = append(labels, m.label)
+	}
+	return &Classification{Score: math.Round((1-remaining)*1000) / 1000, Labels: labels}, nil
+}
+
+// isShouting reports whether most letters of a text of some length are capitals
+func isShouting(text string) bool {
+	letters, upper := 0, 0
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	return letters >= 12 && float64(upper) >= 0.7*float64(letters)
+}
+
+// HTTPClassifier asks a model server for a classification. It posts
+// {"title": ..., "content": ...} to the URL and expects
+// {"score": 0.0-1.0, "labels": [...]} back. When the server cannot be reached
+// the fallback classifier is used, so submissions keep being scored.
+type HTTPClassifier struct {
+	url      string
+	client   *http.Client
+	fallback ContentClassifier
+}
+
+func NewHTTPClassifier(url string, timeout time.Duration, fallback ContentClassifier) *HTTPClassifier {
+	return &HTTPClassifier{
+		url:      url,
+		client:   &http.Client{Timeout: timeout},
+		fallback: fallback,
+	}
+}
+
+func (c *HTTPClassifier) Classify(title, content string) (*Classification, error) {
+	result, err := c.request(title, content)
+	if err != nil && c.fallback != nil {
+		log.Printf("Classifier: %s unavailable, using fallback: %v", c.url, err)
+		return c.fallback.Classify(title, content)
+	}
+	return result, err
+}
+
+func (c *HTTPClassifier) request(title, content string) (*Classification, error) {
+	if c.url == "" {
+		return nil, fmt.Errorf("CLASSIFIER_URL not set")
+	}
+
+	body, err := json.Marshal(map[string]string{"title": title, "content": content})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Post(c.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("model server returned %s", resp.Status)
+	}
+
+	var result Classification
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid model server response: %v", err)
+	}
+	if math.IsNaN(result.Score) {
+		return nil, fmt.Errorf("invalid model server response: score is NaN")
+	}
+	result.Score = math.Max(0, math.Min(1, result.Score))
+	if result.Labels == nil {
+		result.Labels = []string{}
+	}
+	return &result, nil
+}
@@ -1,10 +1,7 @@
 package services
 
 import (
-	"crypto/sha256"
 	"fmt"
-	"net"
-	"os"
 	"strings"
 	"time"
 
@@ -12,15 +9,51 @@ import (
 	"reveal/internal/models"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
-type CommentService struct{}
+type CommentService struct {
+	limits        RateLimitStore
+	displayPolicy *VoteDisplayPolicy
+	trust         *TrustService
+	flagPolicy    *FlagPolicyService
+	audit         *AuditService
+	bans          *BanService
+	filter        *ContentFilter
+	pii           *PIIScanner
+	reasons       *FlagReasonService
+	links         *LinkPolicy
+	normalizer    *TextNormalizer
+	classifier    ContentClassifier
+	slowMode      *SlowModeService
+}
 
 func NewCommentService() *CommentService {
-	return &CommentService{}
+	return &CommentService{
+		limits:        writeRateLimits(),
+		displayPolicy: NewVoteDisplayPolicy(),
+		trust:         NewTrustService(),
+		flagPolicy:    NewFlagPolicyService(),
+		audit:         NewAuditService(),
+		bans:          NewBanService(),
+		filter:        NewContentFilter(),
+		pii:           NewPIIScanner(),
+		reasons:       NewFlagReasonService(),
+		links:         NewLinkPolicy(),
+		normalizer:    NewTextNormalizer(),
+		classifier:    NewContentClassifier(),
+		slowMode:      NewSlowModeService(),
+	}
 }
 
 func (s *CommentService) CreateComment(postID uuid.UUID, content, clientIP string) (*models.Comment, error) {
+	if len(content) > MaxBytesPerCharacter*MaxCommentLength {
+		return nil, fmt.Errorf("comment too long")
+	}
+
+	// Undo Unicode tricks before anything else looks at the text
+	content = s.normalizer.Normalize(content)
+
 	// Validate input
 	if content == "" {
 		return nil, fmt.Errorf("content cannot be empty")
@@ -31,47 +64,131 @@ func (s *CommentService) CreateComment(postID uuid.UUID, content, clientIP strin
 		return nil, fmt.Errorf("comment cannot be empty")
 	}
 	
-	if len(content) > 1000 {
-		return nil, fmt.Errorf("comment too long (max 1000 characters)")
+	if GraphemeCount(content) > MaxCommentLength {
+		return nil, fmt.Errorf("comment too long")
 	}
 
 	// Verify post exists
 	var post models.Post
-	if err := db.DB.First(&post, postID).Error; err != nil {
+	if err := db.DB.First(&post, postID).Error; err != nil || post.Status == models.StatusRemoved || post.Status == models.StatusPending {
 		return nil, fmt.Errorf("post not found")
 	}
 
 	// Hash the IP address for privacy and spam prevention
 	ipHash := s.hashIP(clientIP)
 
-	// Check for spam (basic rate limiting per IP)
-	if s.isSpamming(ipHash) {
+	shadow, err := s.bans.Check(ipHash)
+	if err != nil {
+		return nil, err
+	}
+
+	// Heated threads enter slow mode, which limits how often each identity comments
+	if err := s.slowMode.Evaluate(&post); err != nil {
+		return nil, err
+	}
+	if err := s.slowMode.Check(&post, ipHash); err != nil {
+		return nil, err
+	}
+
+	// Keep personal information out of storage
+	_, content, err = s.pii.Apply("", strings.TrimSpace(content))
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply the moderator-defined filter rules; comments have no title
+	filtered := s.filter.Evaluate("", content)
+	if filtered.Reject {
+		return nil, fmt.Errorf("content rejected")
+	}
+
+	// Enforce the link policy and hold link-heavy comments from new identities
+	var links []models.Link
+	_, filtered.Content, links, err = s.links.Apply("", filtered.Content)
+	if err != nil {
+		return nil, err
+	}
+	linkHold := s.links.HoldReason(ipHash, links)
+
+	// Score the text for toxicity; the classifier may hold or label the comment
+	classification := classifyContent(s.classifier, "", filtered.Content)
+	classifierHold := classification.HoldReason()
+	filtered.Warning = mergeWarnings(filtered.Warning, classification.Warnings())
+
+	// Check for spam (basic rate limiting per client); only comments that would
+	// otherwise be accepted count against the limit
+	if s.isSpamming(clientIP) {
 		return nil, fmt.Errorf("rate limit exceeded")
 	}
 
+	status := models.StatusVisible
+	if filtered.Hold || linkHold != "" || classifierHold != "" {
+		status = models.StatusPending
+	}
+
+	// The author keeps the token to manage the comment later, e.g. to appeal
+	manageToken, manageTokenHash, err := newManageToken()
+	if err != nil {
+		return nil, err
+	}
+
 	comment := &models.Comment{
-		ID:        uuid.New(),
-		PostID:    postID,
-		Content:   strings.TrimSpace(content),
-		CreatedAt: time.Now(),
-		IPHash:    ipHash,
-		Flagged:   false,
+		ID:              uuid.New(),
+		PostID:          postID,
+		Content:         filtered.Content,
+		CreatedAt:       time.Now(),
+		IPHash:          ipHash,
+		Flagged:         filtered.Flag,
+		Status:          status,
+		Shadowed:        shadow,
+		ContentWarning:  filtered.Warning,
+		Links:           links,
+		ToxicityScore:   classification.Score,
+		ToxicityLabels:  classification.Labels,
+		ManageTokenHash: manageTokenHash,
+		ManageToken:     manageToken,
 	}
 
-	result := db.DB.Create(comment)
-	if result.Error != nil {
-		return nil, result.Error
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(comment).Error; err != nil {
+			return err
+		}
+		for _, reason := range []string{linkHold, classifierHold} {
+			if reason == "" {
+				continue
+			}
+			if err := s.audit.Record(tx, models.AuditActorSystem, models.AuditAutoHold, models.FlagTypeComment, comment.ID.String(),
+				reason, ""); err != nil {
+				return err
+			}
+		}
+		return s.filter.recordFilterDecisions(tx, filtered, models.FlagTypeComment, comment.ID)
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	s.trust.RecordActivity(ipHash, models.ActivityComment)
+
 	return comment, nil
 }
 
 func (s *CommentService) GetCommentsByPostID(postID uuid.UUID, clientIP string) ([]models.Comment, error) {
+	return s.GetComments(postID, clientIP, SortOld)
+}
+
+// GetComments lists a post's visible comments in the given sort order
+func (s *CommentService) GetComments(postID uuid.UUID, clientIP, order string) ([]models.Comment, error) {
+	if !IsValidCommentSort(order) {
+		order = SortOld
+	}
+
 	var comments []models.Comment
 	ipHash := s.hashIP(clientIP)
 	
-	// Get comments that are not globally flagged AND not flagged by this user
-	result := db.DB.Where("post_id = ? AND flagged = ?", postID, false).
+	// Get comments that are publicly visible, not globally flagged AND not flagged by this user
+	result := db.DB.Where("post_id = ? AND flagged = ? AND status IN ?", postID, false, publicStatuses).
+		Where("shadowed = ? OR ip_hash = ?", false, ipHash).
 		Where("id NOT IN (?)", 
 			db.DB.Table("flags").
 				Select("comment_id").
@@ -106,6 +223,7 @@ func (s *CommentService) GetCommentsByPostID(postID uuid.UUID, clientIP string)
 	db.DB.Table("votes").
 		Select("comment_id, vote_type, COUNT(*) as count").
 		Where("comment_id IN ?", commentIDs).
+		Where("shadowed = ? OR ip_hash = ?", false, ipHash).
 		Group("comment_id, vote_type").
 		Scan(&voteCounts)
 
@@ -154,10 +272,49 @@ func (s *CommentService) GetCommentsByPostID(postID uuid.UUID, clientIP string)
 		comments[i].UserVote = userVoteMap[commentID]
 	}
 
-	return comments, nil
+	if order == SortOld {
+		return comments, nil
+	}
+
+	// Rank on true counts, weighted by voter trust when enabled
+	var tallies map[uuid.UUID]weightedTally
+	if s.trust.Enabled() {
+		tallies = s.trust.WeightedVoteTallies("comment_id", commentIDs)
+	}
+
+	items := make([]rankedItem, len(comments))
+	for i, comment := range comments {
+		items[i] = rankedItem{
+			index:     i,
+			createdAt: comment.CreatedAt,
+			upvotes:   float64(comment.Upvotes),
+			downvotes: float64(comment.Downvotes),
+		}
+		if tally, ok := tallies[comment.ID]; ok {
+			items[i].upvotes, items[i].downvotes = tally.upvotes, tally.downvotes
+		}
+	}
+
+	ranked := make([]models.Comment, 0, len(comments))
+	for _, idx := range rankOrder(items, order, bayesianPriorWeight()) {
+		ranked = append(ranked, comments[idx])
+	}
+
+	return ranked, nil
+}
+
+// ApplyVoteDisplay replaces exact vote counts with their public representation.
+// Moderators skip this and see the true counts.
+func (s *CommentService) ApplyVoteDisplay(comments []models.Comment) {
+	s.displayPolicy.ApplyToComments(comments)
 }
 
 func (s *CommentService) FlagComment(commentID uuid.UUID, clientIP, reason, details string) error {
+	reason, err := s.reasons.Resolve(reason, details)
+	if err != nil {
+		return err
+	}
+
 	ipHash := s.hashIP(clientIP)
 	
 	// Check if user already flagged this comment
@@ -179,48 +336,40 @@ func (s *CommentService) FlagComment(commentID uuid.UUID, clientIP, reason, deta
 	if err := db.DB.Create(flag).Error; err != nil {
 		return err
 	}
+
+	s.trust.RecordActivity(ipHash, models.ActivityFlag)
 	
-	// Check if comment should be globally flagged (e.g., if 3+ users flag it)
-	var flagCount int64
-	db.DB.Model(&models.Flag{}).Where("flag_type = ? AND comment_id = ?", models.FlagTypeComment, commentID).Count(&flagCount)
-	
-	if flagCount >= 3 {
+	// Check if comment should be held or globally flagged under the configured thresholds
+	var flags []models.Flag
+	db.DB.Where("flag_type = ? AND comment_id = ?", models.FlagTypeComment, commentID).Find(&flags)
+	outcome := s.flagPolicy.Evaluate(models.FlagTypeComment, comment.CreatedAt, flags)
+
+	column, value, action := "", interface{}(nil), ""
+	if outcome.Hide && !comment.Flagged {
 		// Globally flag the comment
-		db.DB.Model(&models.Comment{}).Where("id = ?", commentID).Update("flagged", true)
+		column, value, action = "flagged", true, models.AuditAutoFlag
+	} else if outcome.Hold && comment.Status == models.StatusVisible {
+		// Keep it visible, but queue it for moderator review
+		column, value, action = "status", models.StatusHeld, models.AuditAutoHold
+	}
+	if action == "" {
+		return nil
 	}
 
-	return nil
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Comment{}).Where("id = ?", commentID).Update(column, value).Error; err != nil {
+			return err
+		}
+		return s.audit.Record(tx, models.AuditActorSystem, action, models.FlagTypeComment, commentID.String(),
+			flagOutcomeReason(outcome), fmt.Sprintf("weight=%.2f flags=%d ignored=%d", outcome.Weight, len(flags), outcome.Ignored))
+	})
 }
 
 func (s *CommentService) hashIP(ip string) string {
-	saltKey := os.Getenv("SALT_KEY")
-	if saltKey == "" {
-		saltKey = "default_salt_change_in_production"
-	}
-	
-	// Parse IP to handle IPv6 properly
-	parsedIP := net.ParseIP(ip)
-	var ipBytes []byte
-	
-	if parsedIP != nil {
-		ipBytes = parsedIP.To16() // Convert to IPv6 format (works for IPv4 too)
-	} else {
-		ipBytes = []byte(ip) // Fallback for unparseable IPs
-	}
-	
-	data := append(ipBytes, []byte(saltKey)...)
-	hash := sha256.Sum256(data)
-	return fmt.Sprintf("%x", hash)
+	return hashClientIP(ip)
 }
 
-func (s *CommentService) isSpamming(ipHash string) bool {
-	// Simple spam check: max 10 comments per IP in last 5 minutes
-	var count int64
-	fiveMinutesAgo := time.Now().Add(-5 * time.Minute)
-	
-	db.DB.Model(&models.Comment{}).
-		Where("ip_hash = ? AND created_at > ?", ipHash, fiveMinutesAgo).
-		Count(&count)
-	
-	return count >= 10
+func (s *CommentService) isSpamming(clientIP string) bool {
+	// Simple spam check: max 10 comments per client in 5 minutes, counted in the database
+	return checkRate(s.limits, "comment:"+RateLimitKey(clientIP), Rate{Requests: 10, Window: 5 * time.Minute})
 } 
\ No newline at end of file
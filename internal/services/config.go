@@ -0,0 +1,22 @@
+package services
+
+import (
+	"os"
+	"strconv"
+)
+
+// envFloat reads a float setting from the environment, falling back when unset or invalid
+func envFloat(key string, fallback float64) float64 {
+	if v, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+		return v
+	}
+	return fallback
+}
+
+// envInt reads an integer setting from the environment, falling back when unset or invalid
+func envInt(key string, fallback int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,392 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"reveal/internal/db"
+	"reveal/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// FilterMatch is one rule that matched submitted text
+type FilterMatch struct {
+	RuleID   uuid.UUID `json:"rule_id"`
+	RuleName string    `json:"rule_name"`
+	Field    string    `json:"field"`
+	Action   string    `json:"action"`
+	Text     string    `json:"text"` // The matched text
+}
+
+// FilterResult is the outcome of running the filter rules over a submission
+type FilterResult struct {
+	Title   string        `json:"title"`   // Title after masking
+	Content string        `json:"content"` // Content after masking
+	Reject  bool          `json:"reject"`
+	Hold    bool          `json:"hold"`
+	Flag    bool          `json:"flag"`
+	Warning string        `json:"warning,omitempty"`
+	Matches []FilterMatch `json:"matches"`
+}
+
+// RuleNames lists the names of the matched rules with the given action
+func (r *FilterResult) RuleNames(action string) []string {
+	var names []string
+	for _, match := range r.Matches {
+		if match.Action == action && !containsString(names, match.RuleName) {
+			names = append(names, match.RuleName)
+		}
+	}
+	return names
+}
+
+// compiledRule is a filter rule with its pattern compiled
+type compiledRule struct {
+	rule models.FilterRule
+	re   *regexp.Regexp
+}
+
+// filterCache holds the compiled enabled rules shared by all ContentFilters.
+// It is invalidated when a moderator edits a rule and refreshed periodically
+// so edits made through other instances are picked up as well.
+var filterCache struct {
+	sync.RWMutex
+	rules    []compiledRule
+	loadedAt time.Time
+	stale    bool
+}
+
+// filterReloadInterval is how long compiled rules are reused before reloading (FILTER_RELOAD_SECONDS)
+func filterReloadInterval() time.Duration {
+	return time.Duration(envInt("FILTER_RELOAD_SECONDS", 30)) * time.Second
+}
+
+// invalidateFilterRules forces the next evaluation to reload the rules
+func invalidateFilterRules() {
+	filterCache.Lock()
+	filterCache.stale = true
+	filterCache.Unlock()
+}
+
+// ResetCaches drops the cached filter rules and flag reasons so they are
+// reloaded from the current database (useful for testing)
+func ResetCaches() {
+	invalidateFilterRules()
+	invalidateFlagReasons()
+}
+
+// compileFilterRule turns a rule pattern into a regular expression. Keyword
+// lists match any of their comma-separated entries as whole words, ignoring
+// case. Keywords are folded to their skeleton, as is the text they match.
+func compileFilterRule(rule models.FilterRule) (*regexp.Regexp, error) {
+	switch rule.MatchType {
+	case models.FilterMatchRegex:
+		return regexp.Compile(rule.Pattern)
+	case models.FilterMatchKeyword:
+		var keywords []string
+		for _, keyword := range strings.Split(rule.Pattern, ",") {
+			if keyword, _ = skeleton(strings.TrimSpace(keyword)); keyword != "" {
+				keywords = append(keywords, regexp.QuoteMeta(keyword))
+			}
+		}
+		if len(keywords) == 0 {
+			return nil, fmt.Errorf("empty keyword list")
+		}
+		return regexp.Compile(`(?i)` + wordPattern(strings.Join(keywords, "|")))
+	}
+	return nil, fmt.Errorf("unknown match type %q", rule.MatchType)
+}
+
+// ContentFilter evaluates the moderator-defined filter rules and manages them
+type ContentFilter struct {
+	audit *AuditService
+}
+
+func NewContentFilter() *ContentFilter {
+	return &ContentFilter{
+		audit: NewAuditService(),
+	}
+}
+
+// rules returns the compiled enabled rules, reloading them when stale
+func (f *ContentFilter) rules() []compiledRule {
+	filterCache.RLock()
+	fresh := !filterCache.stale && !filterCache.loadedAt.IsZero() && time.Since(filterCache.loadedAt) < filterReloadInterval()
+	rules := filterCache.rules
+	filterCache.RUnlock()
+	if fresh {
+		return rules
+	}
+
+	var stored []models.FilterRule
+	if err := db.DB.Where("enabled = ?", true).Order("created_at ASC").Find(&stored).Error; err != nil {
+		// Keep filtering with the last good rules
+		return rules
+	}
+
+	compiled := make([]compiledRule, 0, len(stored))
+	for _, rule := range stored {
+		re, err := compileFilterRule(rule)
+		if err != nil {
+			continue
+		}
+		compiled = append(compiled, compiledRule{rule: rule, re: re})
+	}
+
+	filterCache.Lock()
+	filterCache.rules = compiled
+	filterCache.loadedAt = time.Now()
+	filterCache.stale = false
+	filterCache.Unlock()
+	return compiled
+}
+
+// Evaluate runs the enabled rules over a title and content. Comments pass an
+// empty title, so title-only rules never apply to them.
+func (f *ContentFilter) Evaluate(title, content string) *FilterResult {
+	return evaluateRules(f.rules(), title, content)
+}
+
+// DryRun evaluates sample text without storing anything. With a candidate
+// rule only that rule is tested; otherwise all enabled rules are.
+func (f *ContentFilter) DryRun(candidate *models.FilterRule, title, content string) (*FilterResult, error) {
+	if candidate == nil {
+		return f.Evaluate(title, content), nil
+	}
+	if err := validateFilterRule(candidate); err != nil {
+		return nil, err
+	}
+	re, _ := compileFilterRule(*candidate)
+	return evaluateRules([]compiledRule{{rule: *candidate, re: re}}, title, content), nil
+}
+
+func evaluateRules(rules []compiledRule, title, content string) *FilterResult {
+	result := &FilterResult{Title: title, Content: content, Matches: []FilterMatch{}}
+	var warnings []string
+
+	for _, cr := range rules {
+		fields := []string{models.FilterFieldTitle, models.FilterFieldContent}
+		if cr.rule.Field != models.FilterFieldBoth && cr.rule.Field != "" {
+			fields = []string{cr.rule.Field}
+		}
+
+		for _, field := range fields {
+			text := &result.Content
+			if field == models.FilterFieldTitle {
+				text = &result.Title
+			}
+
+			spans := cr.findAll(*text)
+			if len(spans) == 0 {
+				continue
+			}
+			for _, span := range spans {
+				result.Matches = append(result.Matches, FilterMatch{
+					RuleID:   cr.rule.ID,
+					RuleName: cr.rule.Name,
+					Field:    field,
+					Action:   cr.rule.Action,
+					Text:     (*text)[span[0]:span[1]],
+				})
+			}
+
+			switch cr.rule.Action {
+			case models.FilterActionReject:
+				result.Reject = true
+			case models.FilterActionMask:
+				*text = maskSpans(*text, spans)
+			case models.FilterActionHold:
+				result.Hold = true
+			case models.FilterActionFlag:
+				result.Flag = true
+			case models.FilterActionWarn:
+				warning := cr.rule.Warning
+				if warning == "" {
+					warning = cr.rule.Name
+				}
+				if !containsString(warnings, warning) {
+					warnings = append(warnings, warning)
+				}
+			}
+		}
+	}
+
+	result.Warning = strings.Join(warnings, ", ")
+	// The stored warning column holds at most 255 characters
+	if runes := []rune(result.Warning); len(runes) > 255 {
+		result.Warning = string(runes[:255])
+	}
+	return result
+}
+
+// findAll returns the byte ranges of the rule's matches in text. Keyword
+// rules match the skeleton of the text, so homoglyphs, invisible characters
+// and styled letters cannot slip a keyword past them.
+func (cr compiledRule) findAll(text string) [][]int {
+	if cr.rule.MatchType != models.FilterMatchKeyword {
+		return cr.re.FindAllStringIndex(text, -1)
+	}
+
+	folded, offsets := skeleton(text)
+	var spans [][]int
+	for _, loc := range findWords(cr.re, folded, -1) {
+		last := offsets[loc[1]-1]
+		_, size := utf8.DecodeRuneInString(text[last:])
+		spans = append(spans, []int{offsets[loc[0]], last + size})
+	}
+	return spans
+}
+
+// maskSpans replaces each matched range with one asterisk per character
+func maskSpans(text string, spans [][]int) string {
+	var b strings.Builder
+	last := 0
+	for _, span := range spans {
+		if span[0] < last {
+			continue
+		}
+		b.WriteString(text[last:span[0]])
+		b.WriteString(strings.Repeat("*", utf8.RuneCountInString(text[span[0]:span[1]])))
+		last = span[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// validateFilterRule checks a rule's enumerations and that its pattern compiles
+func validateFilterRule(rule *models.FilterRule) error {
+	if rule.Field == "" {
+		rule.Field = models.FilterFieldBoth
+	}
+	if rule.MatchType != models.FilterMatchKeyword && rule.MatchType != models.FilterMatchRegex {
+		return fmt.Errorf("invalid match type")
+	}
+	if rule.Field != models.FilterFieldTitle && rule.Field != models.FilterFieldContent && rule.Field != models.FilterFieldBoth {
+		return fmt.Errorf("invalid field")
+	}
+	if !models.IsValidFilterAction(rule.Action) {
+		return fmt.Errorf("invalid action")
+	}
+	if _, err := compileFilterRule(*rule); err != nil {
+		return fmt.Errorf("invalid pattern")
+	}
+	return nil
+}
+
+// ListRules returns all filter rules, enabled or not, oldest first
+func (f *ContentFilter) ListRules() ([]models.FilterRule, error) {
+	var rules []models.FilterRule
+	err := db.DB.Order("created_at ASC").Find(&rules).Error
+	return rules, err
+}
+
+// CreateRule validates and stores a new filter rule
+func (f *ContentFilter) CreateRule(rule *models.FilterRule, actor string) error {
+	if err := validateFilterRule(rule); err != nil {
+		return err
+	}
+
+	rule.ID = uuid.Nil
+	rule.UpdatedBy = actor
+	rule.CreatedAt = time.Now()
+	rule.UpdatedAt = rule.CreatedAt
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(rule).Error; err != nil {
+			return err
+		}
+		return f.audit.Record(tx, actor, models.AuditFilterCreate, "filter_rule", rule.ID.String(), "", describeFilterRule(rule))
+	})
+	if err != nil {
+		return err
+	}
+
+	invalidateFilterRules()
+	return nil
+}
+
+// UpdateRule replaces the definition of an existing filter rule
+func (f *ContentFilter) UpdateRule(ruleID uuid.UUID, rule *models.FilterRule, actor string) error {
+	if err := validateFilterRule(rule); err != nil {
+		return err
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var existing models.FilterRule
+		if err := tx.First(&existing, "id = ?", ruleID).Error; err != nil {
+			return fmt.Errorf("filter rule not found")
+		}
+
+		rule.ID = existing.ID
+		rule.CreatedAt = existing.CreatedAt
+		rule.UpdatedBy = actor
+		rule.UpdatedAt = time.Now()
+		if err := tx.Select("*").Save(rule).Error; err != nil {
+			return err
+		}
+		return f.audit.Record(tx, actor, models.AuditFilterUpdate, "filter_rule", rule.ID.String(), "", describeFilterRule(rule))
+	})
+	if err != nil {
+		return err
+	}
+
+	invalidateFilterRules()
+	return nil
+}
+
+// DeleteRule removes a filter rule
+func (f *ContentFilter) DeleteRule(ruleID uuid.UUID, actor string) error {
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var existing models.FilterRule
+		if err := tx.First(&existing, "id = ?", ruleID).Error; err != nil {
+			return fmt.Errorf("filter rule not found")
+		}
+		if err := tx.Delete(&existing).Error; err != nil {
+			return err
+		}
+		return f.audit.Record(tx, actor, models.AuditFilterDelete, "filter_rule", ruleID.String(), "", describeFilterRule(&existing))
+	})
+	if err != nil {
+		return err
+	}
+
+	invalidateFilterRules()
+	return nil
+}
+
+// recordFilterDecisions writes audit entries for content a filter rule held or flagged on creation
+func (f *ContentFilter) recordFilterDecisions(tx *gorm.DB, result *FilterResult, contentType string, id uuid.UUID) error {
+	if result.Flag {
+		reason := "matched filter rules: " + strings.Join(result.RuleNames(models.FilterActionFlag), ", ")
+		if err := f.audit.Record(tx, models.AuditActorSystem, models.AuditAutoFlag, contentType, id.String(), reason, ""); err != nil {
+			return err
+		}
+	}
+	if result.Hold {
+		reason := "matched filter rules: " + strings.Join(result.RuleNames(models.FilterActionHold), ", ")
+		if err := f.audit.Record(tx, models.AuditActorSystem, models.AuditAutoHold, contentType, id.String(), reason, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// describeFilterRule summarises a rule for the audit log
+func describeFilterRule(rule *models.FilterRule) string {
+	return fmt.Sprintf("%s: %s %q on %s -> %s (enabled=%t)", rule.Name, rule.MatchType, rule.Pattern, rule.Field, rule.Action, rule.Enabled)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
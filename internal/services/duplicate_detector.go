@@ -0,0 +1,221 @@
+package services
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"reveal/internal/db"
+	"reveal/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Duplicate actions (DUPLICATE_ACTION)
+const (
+	DuplicateActionHold   = "hold"   // Store near-duplicates pending moderator approval (default)
+	DuplicateActionReject = "reject" // Refuse near-duplicates
+	DuplicateActionOff    = "off"
+)
+
+// DuplicateMatch is a recent post a new submission nearly duplicates
+type DuplicateMatch struct {
+	PostID    uuid.UUID // The matched post
+	ClusterID uuid.UUID // The first post of the matched post's cluster
+	Distance  int       // Differing fingerprint bits
+}
+
+// DuplicateCluster is a post and the near-duplicates posted after it
+type DuplicateCluster struct {
+	Root       QueueItem   `json:"root"`
+	Duplicates []QueueItem `json:"duplicates"`
+}
+
+// DuplicateDetector fingerprints posts with SimHash to catch copy-paste spam
+// that has been lightly edited to dodge exact matching
+type DuplicateDetector struct{}
+
+func NewDuplicateDetector() *DuplicateDetector {
+	return &DuplicateDetector{}
+}
+
+// Action returns the configured response to a near-duplicate
+func (d *DuplicateDetector) Action() string {
+	switch action := os.Getenv("DUPLICATE_ACTION"); action {
+	case DuplicateActionReject, DuplicateActionOff:
+		return action
+	default:
+		return DuplicateActionHold
+	}
+}
+
+// fingerprintWords folds text to its skeleton and splits it into words,
+// dropping punctuation
+func fingerprintWords(text string) []string {
+	folded, _ := skeleton(text)
+	return strings.FieldsFunc(folded, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// simHash returns the 64-bit SimHash of a text's words. Texts that differ in
+// a few words have fingerprints that differ in only a few bits. Word features
+// separate short posts better than shingles, where one edit changes several.
+func simHash(words []string) uint64 {
+	var counts [64]int
+	for _, word := range words {
+		h := fnv.New64a()
+		h.Write([]byte(word))
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<uint(bit)) != 0 {
+				counts[bit]++
+			} else {
+				counts[bit]--
+			}
+		}
+	}
+
+	var fingerprint uint64
+	for bit, count := range counts {
+		if count > 0 {
+			fingerprint |= 1 << uint(bit)
+		}
+	}
+	return fingerprint
+}
+
+func hammingDistance(a, b int64) int {
+	return bits.OnesCount64(uint64(a) ^ uint64(b))
+}
+
+// Fingerprint returns the SimHash of a post's title and content, or 0 when
+// the text is too short (DUPLICATE_MIN_WORDS) to be fingerprinted reliably
+func (d *DuplicateDetector) Fingerprint(title, content string) int64 {
+	words := fingerprintWords(title + " " + content)
+	if len(words) < envInt("DUPLICATE_MIN_WORDS", 8) {
+		return 0
+	}
+	return int64(simHash(words))
+}
+
+// bandNeighbours returns the 16-bit values within radius bits of band, for a
+// radius of at most 2
+func bandNeighbours(band int32, radius int) []int32 {
+	values := []int32{band}
+	for i := uint(0); i < 16 && radius >= 1; i++ {
+		values = append(values, band^1<<i)
+		for j := i + 1; j < 16 && radius >= 2; j++ {
+			values = append(values, band^1<<i^1<<j)
+		}
+	}
+	return values
+}
+
+// FindMatch returns the closest post from the last DUPLICATE_WINDOW_HOURS whose
+// fingerprint is within DUPLICATE_MAX_DISTANCE bits, or nil. Removed posts are
+// included so spam cannot simply be reposted after removal.
+//
+// Candidates are found through the indexed fingerprint bands: by the
+// pigeonhole principle, two fingerprints within d bits have a band within
+// d/4 bits of each other, so only posts with a band near one of ours can match.
+func (d *DuplicateDetector) FindMatch(fingerprint int64) (*DuplicateMatch, error) {
+	if fingerprint == 0 {
+		return nil, nil
+	}
+
+	type candidate struct {
+		ID          uuid.UUID
+		Fingerprint int64
+		DuplicateOf *uuid.UUID
+	}
+	var candidates []candidate
+	window := time.Duration(envFloat("DUPLICATE_WINDOW_HOURS", 24) * float64(time.Hour))
+	maxDistance := envInt("DUPLICATE_MAX_DISTANCE", 6)
+	query := db.DB.Model(&models.Post{}).
+		Select("id, fingerprint, duplicate_of").
+		Where("created_at > ? AND fingerprint <> ?", time.Now().Add(-window), 0)
+	// Past 11 bits the neighbour lists grow too long; scan the window instead
+	if radius := maxDistance / 4; radius <= 2 {
+		bands := models.FingerprintBands(fingerprint)
+		query = query.Where("fingerprint_band0 IN ? OR fingerprint_band1 IN ? OR fingerprint_band2 IN ? OR fingerprint_band3 IN ?",
+			bandNeighbours(bands[0], radius), bandNeighbours(bands[1], radius),
+			bandNeighbours(bands[2], radius), bandNeighbours(bands[3], radius))
+	}
+	if err := query.Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	var best *DuplicateMatch
+	for _, c := range candidates {
+		distance := hammingDistance(fingerprint, c.Fingerprint)
+		if distance > maxDistance || (best != nil && distance >= best.Distance) {
+			continue
+		}
+		best = &DuplicateMatch{PostID: c.ID, ClusterID: c.ID, Distance: distance}
+		if c.DuplicateOf != nil {
+			best.ClusterID = *c.DuplicateOf
+		}
+	}
+	return best, nil
+}
+
+// Clusters lists near-duplicate clusters with a duplicate posted since the
+// given time, most recently active first
+func (d *DuplicateDetector) Clusters(since time.Time, limit int) ([]DuplicateCluster, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	var rootIDs []uuid.UUID
+	err := db.DB.Model(&models.Post{}).
+		Select("duplicate_of").
+		Where("duplicate_of IS NOT NULL AND created_at >= ?", since).
+		Group("duplicate_of").
+		Order("MAX(created_at) DESC").
+		Limit(limit).
+		Pluck("duplicate_of", &rootIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(rootIDs) == 0 {
+		return []DuplicateCluster{}, nil
+	}
+
+	var posts []models.Post
+	err = db.DB.Where("id IN ? OR duplicate_of IN ?", rootIDs, rootIDs).
+		Order("created_at ASC").
+		Find(&posts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	clusters := make(map[uuid.UUID]*DuplicateCluster, len(rootIDs))
+	for _, id := range rootIDs {
+		clusters[id] = &DuplicateCluster{Duplicates: []QueueItem{}}
+	}
+	for i := range posts {
+		if cluster, ok := clusters[posts[i].ID]; ok {
+			cluster.Root = postQueueItem(&posts[i])
+		} else if posts[i].DuplicateOf != nil {
+			if cluster, ok := clusters[*posts[i].DuplicateOf]; ok {
+				cluster.Duplicates = append(cluster.Duplicates, postQueueItem(&posts[i]))
+			}
+		}
+	}
+
+	result := make([]DuplicateCluster, 0, len(rootIDs))
+	for _, id := range rootIDs {
+		result = append(result, *clusters[id])
+	}
+	return result, nil
+}
+
+// duplicateReason describes a near-duplicate match for the audit log
+func duplicateReason(match *DuplicateMatch) string {
+	return fmt.Sprintf("near-duplicate of post %s (distance %d)", match.PostID, match.Distance)
+}
@@ -0,0 +1,295 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"reveal/internal/db"
+	"reveal/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FlagOutcome is the result of evaluating the flags on a post or comment
+type FlagOutcome struct {
+	Weight  float64 // Weighted total of the counted flags
+	Hide    bool    // Content should be globally flagged
+	Hold    bool    // Content should be held for review
+	Trigger string  // Reason whose threshold was crossed, or "" for the weighted total
+	Ignored int     // Flags that no longer count because of poor flagger accuracy or age
+}
+
+// FlagPolicyService decides when flagged content is held for review or hidden
+type FlagPolicyService struct {
+	audit *AuditService
+}
+
+func NewFlagPolicyService() *FlagPolicyService {
+	return &FlagPolicyService{
+		audit: NewAuditService(),
+	}
+}
+
+// defaultThresholds returns the environment defaults for a content type. The
+// legacy behaviour (hide posts at 5 flags, comments at 3) is the fallback.
+func defaultThresholds(contentType string) []models.FlagThreshold {
+	prefix, hideAt := "FLAG_POST_", 5.0
+	if contentType == models.FlagTypeComment {
+		prefix, hideAt = "FLAG_COMMENT_", 3.0
+	}
+
+	thresholds := []models.FlagThreshold{{
+		ContentType: contentType,
+		HideAt:      envFloat(prefix+"HIDE_THRESHOLD", hideAt),
+		HoldAt:      envFloat(prefix+"HOLD_THRESHOLD", 0),
+	}}
+
+	// FLAG_POST_REASON_THRESHOLDS="violence:1,spam:5" hides after that many flags with the reason
+	for reason, hide := range parseReasonValues(os.Getenv(prefix + "REASON_THRESHOLDS")) {
+		thresholds = append(thresholds, models.FlagThreshold{
+			ContentType: contentType,
+			Reason:      reason,
+			HideAt:      hide,
+		})
+	}
+	return thresholds
+}
+
+// flagAccuracyFactor scales the flags of an identity by how often moderators
+// upheld its past flags. Identities with few rulings count fully; poor
+// accuracy discounts their flags, and very poor accuracy ignores them.
+func flagAccuracyFactor(identity *models.Identity) float64 {
+	rulings := identity.FlagsUpheld + identity.FlagsRejected
+	if rulings < int64(envInt("FLAG_ACCURACY_MIN_RULINGS", 3)) {
+		return 1
+	}
+
+	// Laplace-smoothed, like the flag component of the trust score
+	accuracy := float64(identity.FlagsUpheld+1) / float64(rulings+2)
+	if accuracy < envFloat("FLAG_ACCURACY_IGNORE_BELOW", 0.2) {
+		return 0
+	}
+	if full := envFloat("FLAG_ACCURACY_FULL_WEIGHT", 0.5); full > 0 {
+		return math.Min(1, accuracy/full)
+	}
+	return 1
+}
+
+// flagDecayFactor lets flags count fully until the flagged content is
+// FLAG_DECAY_HOURS old and then fades them out linearly over
+// FLAG_DECAY_FADE_HOURS, so flags on very old content stop counting, however
+// recently they were cast (FLAG_DECAY_HOURS=0 disables decay)
+func flagDecayFactor(postedAt, now time.Time) float64 {
+	hours := envFloat("FLAG_DECAY_HOURS", 720)
+	if hours <= 0 {
+		return 1
+	}
+
+	age := now.Sub(postedAt).Hours()
+	if age <= hours {
+		return 1
+	}
+	fade := envFloat("FLAG_DECAY_FADE_HOURS", 168)
+	if fade <= 0 {
+		return 0
+	}
+	return math.Max(0, 1-(age-hours)/fade)
+}
+
+// flagTarget returns the ID of the post or comment a flag is on
+func flagTarget(flag models.Flag) uuid.UUID {
+	if flag.PostID != nil {
+		return *flag.PostID
+	}
+	if flag.CommentID != nil {
+		return *flag.CommentID
+	}
+	return uuid.Nil
+}
+
+// FlagFactors returns how much each flag counts before its reason weight is
+// applied: 0 for dismissed or fully discounted flags, up to 1 otherwise.
+// postedAt holds the creation time of the flagged content by ID; flags on
+// content missing from it do not decay.
+func (s *FlagPolicyService) FlagFactors(flags []models.Flag, postedAt map[uuid.UUID]time.Time) []float64 {
+	var ipHashes []string
+	for _, flag := range flags {
+		ipHashes = append(ipHashes, flag.IPHash)
+	}
+
+	identities := make(map[string]*models.Identity)
+	if len(ipHashes) > 0 {
+		var rows []models.Identity
+		db.DB.Where("ip_hash IN ?", ipHashes).Find(&rows)
+		for i := range rows {
+			identities[rows[i].IPHash] = &rows[i]
+		}
+	}
+
+	now := time.Now()
+	factors := make([]float64, len(flags))
+	for i, flag := range flags {
+		if flag.Resolution == models.FlagResolutionDismissed {
+			continue
+		}
+		factor := 1.0
+		if posted, ok := postedAt[flagTarget(flag)]; ok {
+			factor = flagDecayFactor(posted, now)
+		}
+		if identity, ok := identities[flag.IPHash]; ok {
+			factor *= flagAccuracyFactor(identity)
+		}
+		factors[i] = factor
+	}
+	return factors
+}
+
+// Thresholds returns the effective thresholds for a content type, with
+// moderator overrides from the database replacing the environment defaults
+func (s *FlagPolicyService) Thresholds(contentType string) []models.FlagThreshold {
+	effective := make(map[string]models.FlagThreshold)
+	for _, t := range defaultThresholds(contentType) {
+		effective[t.Reason] = t
+	}
+
+	var overrides []models.FlagThreshold
+	if err := db.DB.Where("content_type = ?", contentType).Find(&overrides).Error; err == nil {
+		for _, t := range overrides {
+			effective[t.Reason] = t
+		}
+	}
+
+	thresholds := make([]models.FlagThreshold, 0, len(effective))
+	for _, t := range effective {
+		thresholds = append(thresholds, t)
+	}
+	sort.Slice(thresholds, func(i, j int) bool {
+		return thresholds[i].Reason < thresholds[j].Reason
+	})
+	return thresholds
+}
+
+// Evaluate applies the thresholds for a content type to the flags on one post
+// or comment, created at postedAt. Flags dismissed by a moderator no longer
+// count; the rest are discounted by their flagger's accuracy and by the age of
+// the content (see FlagFactors).
+func (s *FlagPolicyService) Evaluate(contentType string, postedAt time.Time, flags []models.Flag) FlagOutcome {
+	var outcome FlagOutcome
+	reasonCounts := make(map[string]float64)
+
+	posted := make(map[uuid.UUID]time.Time)
+	for _, flag := range flags {
+		posted[flagTarget(flag)] = postedAt
+	}
+	for i, factor := range s.FlagFactors(flags, posted) {
+		if factor == 0 {
+			if flags[i].Resolution != models.FlagResolutionDismissed {
+				outcome.Ignored++
+			}
+			continue
+		}
+		outcome.Weight += factor * reasonWeight(flags[i].Reason)
+		reasonCounts[flags[i].Reason] += factor
+	}
+
+	for _, t := range s.Thresholds(contentType) {
+		value := outcome.Weight
+		if t.Reason != "" {
+			value = reasonCounts[t.Reason]
+		}
+		if t.HideAt > 0 && value >= t.HideAt && !outcome.Hide {
+			outcome.Hide = true
+			outcome.Trigger = t.Reason
+		}
+		if t.HoldAt > 0 && value >= t.HoldAt {
+			outcome.Hold = true
+		}
+	}
+
+	return outcome
+}
+
+// SetThreshold stores a moderator override for a content type and reason
+func (s *FlagPolicyService) SetThreshold(contentType, reason string, hideAt, holdAt float64, actor string) (*models.FlagThreshold, error) {
+	if contentType != models.FlagTypePost && contentType != models.FlagTypeComment {
+		return nil, fmt.Errorf("invalid content type")
+	}
+	if hideAt < 0 || holdAt < 0 {
+		return nil, fmt.Errorf("invalid threshold")
+	}
+
+	threshold := &models.FlagThreshold{
+		ContentType: contentType,
+		Reason:      reason,
+		HideAt:      hideAt,
+		HoldAt:      holdAt,
+		UpdatedBy:   actor,
+		UpdatedAt:   time.Now(),
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "content_type"}, {Name: "reason"}},
+			DoUpdates: clause.AssignmentColumns([]string{"hide_at", "hold_at", "updated_by", "updated_at"}),
+		}).Create(threshold).Error
+		if err != nil {
+			return err
+		}
+		return s.audit.Record(tx, actor, models.AuditThresholdSet, "threshold", thresholdTarget(contentType, reason), "",
+			fmt.Sprintf("hide_at=%g hold_at=%g", hideAt, holdAt))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Reload so an updated row reports its original ID
+	var stored models.FlagThreshold
+	if err := db.DB.Where("content_type = ? AND reason = ?", contentType, reason).First(&stored).Error; err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// ResetThreshold removes a moderator override so the environment default applies again
+func (s *FlagPolicyService) ResetThreshold(contentType, reason, actor string) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("content_type = ? AND reason = ?", contentType, reason).Delete(&models.FlagThreshold{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("threshold not found")
+		}
+		return s.audit.Record(tx, actor, models.AuditThresholdReset, "threshold", thresholdTarget(contentType, reason), "", "")
+	})
+}
+
+// thresholdTarget identifies a threshold in the audit log, e.g. "post:violence" or "post:*"
+func thresholdTarget(contentType, reason string) string {
+	if reason == "" {
+		reason = "*"
+	}
+	return contentType + ":" + reason
+}
+
+// parseReasonValues parses "reason:value,reason:value" settings
+func parseReasonValues(raw string) map[string]float64 {
+	values := make(map[string]float64)
+	for _, entry := range strings.Split(raw, ",") {
+		reason, value, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if !found || reason == "" {
+			continue
+		}
+		if v, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil && v >= 0 {
+			values[reason] = v
+		}
+	}
+	return values
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"reveal/internal/db"
+	"reveal/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var flagReasonKeyPattern = regexp.MustCompile(`^[a-z0-9_]{1,50}$`)
+
+// flagReasonCache holds the flag reason taxonomy shared by all services. Like
+// the filter rules it is invalidated on edits and reloaded every
+// FILTER_RELOAD_SECONDS so edits made through other instances are picked up.
+var flagReasonCache struct {
+	sync.RWMutex
+	reasons  map[string]models.FlagReason
+	loadedAt time.Time
+	stale    bool
+}
+
+// invalidateFlagReasons forces the next lookup to reload the taxonomy
+func invalidateFlagReasons() {
+	flagReasonCache.Lock()
+	flagReasonCache.stale = true
+	flagReasonCache.Unlock()
+}
+
+// flagReasons returns the taxonomy keyed by reason, including disabled
+// reasons. An empty table is seeded with the built-in reasons, weighted by
+// FLAG_REASON_WEIGHTS. If the table cannot be read, the last good taxonomy
+// (or the built-in one) keeps flagging working.
+func flagReasons() map[string]models.FlagReason {
+	flagReasonCache.RLock()
+	fresh := !flagReasonCache.stale && !flagReasonCache.loadedAt.IsZero() && time.Since(flagReasonCache.loadedAt) < filterReloadInterval()
+	reasons := flagReasonCache.reasons
+	flagReasonCache.RUnlock()
+	if fresh {
+		return reasons
+	}
+
+	var stored []models.FlagReason
+	err := db.DB.Find(&stored).Error
+	if err == nil && len(stored) == 0 {
+		stored = defaultFlagReasons()
+		err = db.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&stored).Error
+	}
+	if err != nil {
+		if reasons == nil {
+			reasons = flagReasonMap(defaultFlagReasons())
+		}
+		return reasons
+	}
+
+	reasons = flagReasonMap(stored)
+	flagReasonCache.Lock()
+	flagReasonCache.reasons = reasons
+	flagReasonCache.loadedAt = time.Now()
+	flagReasonCache.stale = false
+	flagReasonCache.Unlock()
+	return reasons
+}
+
+// defaultFlagReasons returns the built-in taxonomy with the configured weights
+func defaultFlagReasons() []models.FlagReason {
+	reasons := models.DefaultFlagReasons()
+	weights := parseReasonValues(os.Getenv("FLAG_REASON_WEIGHTS"))
+	for i := range reasons {
+		if weight, ok := weights[reasons[i].Key]; ok {
+			reasons[i].Weight = weight
+		}
+	}
+	return reasons
+}
+
+func flagReasonMap(reasons []models.FlagReason) map[string]models.FlagReason {
+	byKey := make(map[string]models.FlagReason, len(reasons))
+	for _, reason := range reasons {
+		byKey[reason.Key] = reason
+	}
+	return byKey
+}
+
+// reasonWeight returns how much a single flag with the given reason counts
+// towards the weighted total. Reasons no longer in the taxonomy count 1.
+func reasonWeight(reason string) float64 {
+	if r, ok := flagReasons()[reason]; ok {
+		return r.Weight
+	}
+	return 1
+}
+
+// FlagReasonService validates flag reasons and manages the taxonomy
+type FlagReasonService struct {
+	audit *AuditService
+}
+
+func NewFlagReasonService() *FlagReasonService {
+	return &FlagReasonService{
+		audit: NewAuditService(),
+	}
+}
+
+// List returns the taxonomy, most severe reasons first
+func (s *FlagReasonService) List(includeDisabled bool) []models.FlagReason {
+	var reasons []models.FlagReason
+	for _, reason := range flagReasons() {
+		if reason.Enabled || includeDisabled {
+			reasons = append(reasons, reason)
+		}
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if reasons[i].Severity != reasons[j].Severity {
+			return reasons[i].Severity > reasons[j].Severity
+		}
+		return reasons[i].Key < reasons[j].Key
+	})
+	return reasons
+}
+
+// Resolve checks a submitted flag reason against the taxonomy and returns the
+// reason to store. A missing reason is recorded as a plain report.
+func (s *FlagReasonService) Resolve(reason, details string) (string, error) {
+	if reason == "" {
+		reason = models.FlagReasonReported
+	}
+
+	r, ok := flagReasons()[reason]
+	if !ok || !r.Enabled {
+		return "", fmt.Errorf("invalid flag reason")
+	}
+	if r.RequiresDetails && strings.TrimSpace(details) == "" {
+		return "", fmt.Errorf("details required")
+	}
+	return reason, nil
+}
+
+// SetReason adds a reason to the taxonomy or replaces an existing one.
+// Reasons are retired by disabling them, so existing flags keep their meaning.
+func (s *FlagReasonService) SetReason(reason *models.FlagReason, actor string) (*models.FlagReason, error) {
+	if !flagReasonKeyPattern.MatchString(reason.Key) {
+		return nil, fmt.Errorf("invalid flag reason key")
+	}
+	if strings.TrimSpace(reason.Labels[models.DefaultLanguage]) == "" {
+		return nil, fmt.Errorf("english label required")
+	}
+	if reason.Severity < 1 || reason.Severity > 5 {
+		return nil, fmt.Errorf("invalid severity")
+	}
+	if reason.Weight < 0 {
+		return nil, fmt.Errorf("invalid weight")
+	}
+
+	labels := make(map[string]string, len(reason.Labels))
+	for lang, label := range reason.Labels {
+		if label = strings.TrimSpace(label); label != "" {
+			labels[strings.ToLower(lang)] = label
+		}
+	}
+	reason.Labels = labels
+	reason.UpdatedBy = actor
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var existing models.FlagReason
+		action := models.AuditFlagReasonCreate
+		if err := tx.First(&existing, "key = ?", reason.Key).Error; err == nil {
+			action = models.AuditFlagReasonUpdate
+			reason.CreatedAt = existing.CreatedAt
+		}
+
+		if err := tx.Save(reason).Error; err != nil {
+			return err
+		}
+		return s.audit.Record(tx, actor, action, "flag_reason", reason.Key, "",
+			fmt.Sprintf("severity=%d weight=%.2f requires_details=%t enabled=%t",
+				reason.Severity, reason.Weight, reason.RequiresDetails, reason.Enabled))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	invalidateFlagReasons()
+	return reason, nil
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"os"
+)
+
+// hashClientIP returns the salted hash used as the anonymous identity of a client.
+// Every service must derive identities the same way so that flags, votes and
+// trust all refer to the same key.
+func hashClientIP(ip string) string {
+	saltKey := os.Getenv("SALT_KEY")
+	if saltKey == "" {
+		saltKey = "default_salt_change_in_production"
+	}
+
+	// Parse IP to handle IPv6 properly
+	parsedIP := net.ParseIP(ip)
+	var ipBytes []byte
+
+	if parsedIP != nil {
+		ipBytes = parsedIP.To16() // Convert to IPv6 format (works for IPv4 too)
+	} else {
+		ipBytes = []byte(ip) // Fallback for unparseable IPs
+	}
+
+	data := append(ipBytes, []byte(saltKey)...)
+	hash := sha256.Sum256(data)
+	return fmt.Sprintf("%x", hash)
+}
+
+// ClientKey groups client addresses for rate limiting. IPv6 clients usually
+// get a whole /64, so they are limited per /64 prefix rather than per address.
+func ClientKey(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ip
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.String()
+	}
+	return (&net.IPNet{IP: parsed.Mask(net.CIDRMask(64, 128)), Mask: net.CIDRMask(64, 128)}).String()
+}
+
+// RateLimitKey returns the salted hash of the client's ClientKey, which rate
+// limits are counted by
+func RateLimitKey(ip string) string {
+	return hashClientIP(ClientKey(ip))
+}
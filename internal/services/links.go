@@ -0,0 +1,206 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"reveal/internal/models"
+)
+
+// Link policies (LINK_POLICY)
+const (
+	LinkPolicyAllow     = "allow"     // Allow every link (default)
+	LinkPolicyAllowlist = "allowlist" // Allow only links to LINK_ALLOWED_DOMAINS
+	LinkPolicyDisallow  = "disallow"  // Refuse submissions containing links
+)
+
+// LinkError is returned when a submission contains links the policy does
+// not allow. Handlers report the offending links to the client.
+type LinkError struct {
+	Links []models.Link
+}
+
+func (e *LinkError) Error() string {
+	return "links not allowed"
+}
+
+var (
+	// Links written with a scheme or starting with www.
+	schemeLinkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"']+`)
+	// Bare domains such as example.com/page, limited to common top-level
+	// domains so ordinary sentences ("e.g.", "end.Next") are not mistaken for links
+	bareLinkPattern = regexp.MustCompile(`(?i)\b(?:[a-z0-9](?:[a-z0-9-]*[a-z0-9])?\.)+(?:com|net|org|info|biz|io|co|me|ly|gg|app|dev|xyz|online|site|shop|link|click|top|ru|cn|uk|de|fr|tk)\b(?:[/?#][^\s<>"']*)?`)
+)
+
+// LinkPolicy finds links in submitted text and enforces LINK_POLICY. Tracking
+// parameters are stripped from every link that is allowed.
+type LinkPolicy struct {
+	trust *TrustService
+}
+
+func NewLinkPolicy() *LinkPolicy {
+	return &LinkPolicy{
+		trust: NewTrustService(),
+	}
+}
+
+// Policy returns the configured link policy
+func (p *LinkPolicy) Policy() string {
+	switch policy := strings.ToLower(os.Getenv("LINK_POLICY")); policy {
+	case LinkPolicyAllowlist, LinkPolicyDisallow:
+		return policy
+	default:
+		return LinkPolicyAllow
+	}
+}
+
+// allowedDomains parses LINK_ALLOWED_DOMAINS, a comma-separated list of
+// domains whose subdomains are allowed as well
+func allowedDomains() []string {
+	var domains []string
+	for _, domain := range strings.Split(os.Getenv("LINK_ALLOWED_DOMAINS"), ",") {
+		domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), "www.")
+		if domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
+
+func domainAllowed(domain string, allowed []string) bool {
+	for _, a := range allowed {
+		if domain == a || strings.HasSuffix(domain, "."+a) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply enforces the link policy on a title and content. It returns the text
+// to store, with tracking parameters removed, and the links it contains, or a
+// *LinkError listing the links that are not allowed, as written. Comments
+// pass an empty title.
+func (p *LinkPolicy) Apply(title, content string) (string, string, []models.Link, error) {
+	policy := p.Policy()
+	allowed := allowedDomains()
+
+	var links, refused []models.Link
+	clean := func(field, text string) string {
+		var b strings.Builder
+		last := 0
+		for _, span := range findLinks(text) {
+			link := span.link
+			if policy == LinkPolicyDisallow || (policy == LinkPolicyAllowlist && !domainAllowed(link.Domain, allowed)) {
+				link.Text = text[span.start:span.end]
+				link.Start = utf8.RuneCountInString(text[:span.start])
+				link.End = link.Start + utf8.RuneCountInString(link.Text)
+				link.Field = field
+				refused = append(refused, link)
+				continue
+			}
+
+			b.WriteString(text[last:span.start])
+			link.Start = utf8.RuneCountInString(b.String())
+			b.WriteString(link.Text)
+			link.End = link.Start + utf8.RuneCountInString(link.Text)
+			link.Field = field
+			links = append(links, link)
+			last = span.end
+		}
+		b.WriteString(text[last:])
+		return b.String()
+	}
+
+	title = clean("title", title)
+	content = clean("content", content)
+	if len(refused) > 0 {
+		return "", "", nil, &LinkError{Links: refused}
+	}
+	return title, content, links, nil
+}
+
+// HoldReason returns why a submission with the given links from the identity
+// should be held for review, or "" if it need not be. Submissions with
+// LINK_HOLD_COUNT or more links are held while the identity is younger than
+// LINK_NEW_IDENTITY_HOURS. Set LINK_HOLD_COUNT=0 to switch this off.
+func (p *LinkPolicy) HoldReason(ipHash string, links []models.Link) string {
+	threshold := envInt("LINK_HOLD_COUNT", 3)
+	if threshold <= 0 || len(links) < threshold {
+		return ""
+	}
+
+	age := time.Duration(envFloat("LINK_NEW_IDENTITY_HOURS", 72) * float64(time.Hour))
+	if !p.trust.IsNew(ipHash, age) {
+		return ""
+	}
+	return fmt.Sprintf("%d links from a new identity", len(links))
+}
+
+// linkSpan is a link located in the scanned text. The link's Text holds
+// the cleaned link that replaces text[start:end].
+type linkSpan struct {
+	start, end int
+	link       models.Link
+}
+
+// findLinks locates the links in a text, in order of position. Links
+// preceded by "@" are the domains of email addresses and are skipped.
+func findLinks(text string) []linkSpan {
+	var spans []linkSpan
+	overlaps := func(start, end int) bool {
+		for _, span := range spans {
+			if start < span.end && span.start < end {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, pattern := range []*regexp.Regexp{schemeLinkPattern, bareLinkPattern} {
+		for _, loc := range pattern.FindAllStringIndex(text, -1) {
+			raw := strings.TrimRight(text[loc[0]:loc[1]], ".,;:!?)")
+			end := loc[0] + len(raw)
+			if overlaps(loc[0], end) || (loc[0] > 0 && text[loc[0]-1] == '@') {
+				continue
+			}
+			if link, ok := parseLink(raw); ok {
+				spans = append(spans, linkSpan{start: loc[0], end: end, link: link})
+			}
+		}
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	return spans
+}
+
+// parseLink turns a link as written into an absolute URL with tracking
+// parameters removed. Links without a scheme are assumed to use https.
+func parseLink(raw string) (models.Link, bool) {
+	scheme := strings.HasPrefix(strings.ToLower(raw), "http://") || strings.HasPrefix(strings.ToLower(raw), "https://")
+	absolute := raw
+	if !scheme {
+		absolute = "https://" + raw
+	}
+
+	u, err := url.Parse(absolute)
+	if err != nil || u.Hostname() == "" {
+		return models.Link{}, false
+	}
+
+	cleaned, _ := stripTrackingParams(absolute)
+	text := cleaned
+	if !scheme {
+		text = strings.TrimPrefix(cleaned, "https://")
+	}
+	return models.Link{
+		URL:    cleaned,
+		Text:   text,
+		Domain: strings.TrimPrefix(strings.ToLower(u.Hostname()), "www."),
+	}, true
+}
@@ -0,0 +1,32 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+)
+
+// newManageToken returns a random management token and the hash stored in its place
+func newManageToken() (token, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token = hex.EncodeToString(buf)
+	return token, hashManageToken(token), nil
+}
+
+func hashManageToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// manageTokenMatches reports whether token is the one whose hash was stored.
+// Content created before management tokens existed has no hash and never matches.
+func manageTokenMatches(storedHash, token string) bool {
+	if storedHash == "" || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(storedHash), []byte(hashManageToken(token))) == 1
+}
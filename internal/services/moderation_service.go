@@ -0,0 +1,456 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"reveal/internal/db"
+	"reveal/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// publicStatuses are the content statuses shown in public listings
+var publicStatuses = []string{models.StatusVisible, models.StatusHeld}
+
+// QueueItem is a post or comment awaiting moderator review, with its flags
+type QueueItem struct {
+	Type       string         `json:"type"` // "post" or "comment"
+	ID         uuid.UUID      `json:"id"`
+	PostID     *uuid.UUID     `json:"post_id,omitempty"` // Parent post for comments
+	Title      string         `json:"title,omitempty"`
+	Content    string         `json:"content"`
+	Status     string         `json:"status"`
+	Flagged    bool           `json:"flagged"`
+	AuthorHash string         `json:"author_hash"`
+	CreatedAt  time.Time      `json:"created_at"`
+	OpenFlags  int64          `json:"open_flags"`
+	Flags      []models.Flag  `json:"flags"`
+	Appeal     *models.Appeal `json:"appeal,omitempty"` // The author's appeal, if any
+
+	// First post of the near-duplicate cluster this post belongs to
+	DuplicateOf *uuid.UUID `json:"duplicate_of,omitempty"`
+
+	// Toxicity classifier output from when the item was created
+	ToxicityScore  float64  `json:"toxicity_score"`
+	ToxicityLabels []string `json:"toxicity_labels,omitempty"`
+}
+
+// ModerationTarget identifies a post or comment for bulk moderation
+type ModerationTarget struct {
+	Type string    `json:"type"`
+	ID   uuid.UUID `json:"id"`
+}
+
+// BulkResult reports the outcome of one item in a bulk moderation request
+type BulkResult struct {
+	Type  string    `json:"type"`
+	ID    uuid.UUID `json:"id"`
+	OK    bool      `json:"ok"`
+	Error string    `json:"error,omitempty"`
+}
+
+type ModerationService struct {
+	trust      *TrustService
+	audit      *AuditService
+	flagPolicy *FlagPolicyService
+}
+
+func NewModerationService() *ModerationService {
+	return &ModerationService{
+		trust:      NewTrustService(),
+		audit:      NewAuditService(),
+		flagPolicy: NewFlagPolicyService(),
+	}
+}
+
+// reportedThreshold is the number of open flags that puts visible content in the
+// queue, each counted by its FlagFactors discount
+func reportedThreshold() int {
+	if v := envInt("REVIEW_QUEUE_MIN_FLAGS", 2); v > 0 {
+		return v
+	}
+	return 2
+}
+
+// QueueCursor marks the last queue item of a page, for fetching the next one
+type QueueCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// GetQueue lists globally flagged, held, pending and heavily reported content, newest first.
+// contentType is "post", "comment" or "" for both.
+func (s *ModerationService) GetQueue(contentType string, limit int) ([]QueueItem, error) {
+	return s.GetQueuePage(contentType, nil, limit)
+}
+
+// GetQueuePage lists the queue like GetQueue, starting after the given cursor when set
+func (s *ModerationService) GetQueuePage(contentType string, after *QueueCursor, limit int) ([]QueueItem, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	page := func(query *gorm.DB) *gorm.DB {
+		if after != nil {
+			query = query.Where("created_at < ? OR (created_at = ? AND id < ?)", after.CreatedAt, after.CreatedAt, after.ID)
+		}
+		return query.Order("created_at DESC, id DESC").Limit(limit)
+	}
+
+	var items []QueueItem
+
+	if contentType == "" || contentType == models.FlagTypePost {
+		reported, err := s.reportedIDs(models.FlagTypePost, "post_id")
+		if err != nil {
+			return nil, err
+		}
+
+		var posts []models.Post
+		result := page(db.DB.Where("status <> ?", models.StatusRemoved).
+			Where("flagged = ? OR status IN ? OR id IN ?", true, []string{models.StatusHeld, models.StatusPending}, reported)).
+			Find(&posts)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		for i := range posts {
+			items = append(items, postQueueItem(&posts[i]))
+		}
+	}
+
+	if contentType == "" || contentType == models.FlagTypeComment {
+		reported, err := s.reportedIDs(models.FlagTypeComment, "comment_id")
+		if err != nil {
+			return nil, err
+		}
+
+		var comments []models.Comment
+		result := page(db.DB.Where("status <> ?", models.StatusRemoved).
+			Where("flagged = ? OR status IN ? OR id IN ?", true, []string{models.StatusHeld, models.StatusPending}, reported)).
+			Find(&comments)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		for i := range comments {
+			items = append(items, commentQueueItem(&comments[i]))
+		}
+	}
+
+	// Merge posts and comments newest first, in the same order as the queries
+	sort.SliceStable(items, func(i, j int) bool {
+		if !items[i].CreatedAt.Equal(items[j].CreatedAt) {
+			return items[i].CreatedAt.After(items[j].CreatedAt)
+		}
+		return items[i].ID.String() > items[j].ID.String()
+	})
+	if len(items) > limit {
+		items = items[:limit]
+	}
+
+	if err := s.attachFlags(items); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// reportedIDs returns the posts or comments whose open flags reach the review
+// threshold once discounted by FlagFactors, so flags from inaccurate flaggers
+// or on very old content do not push content into the queue
+func (s *ModerationService) reportedIDs(contentType, column string) ([]uuid.UUID, error) {
+	threshold := reportedThreshold()
+	open := db.DB.Model(&models.Flag{}).
+		Where("flag_type = ? AND resolution = ? AND "+column+" IS NOT NULL", contentType, models.FlagResolutionPending).
+		Session(&gorm.Session{})
+
+	// A flag counts once at most, so only content with enough flags can qualify
+	var flags []models.Flag
+	if err := open.Where(column+" IN (?)", open.Select(column).Group(column).Having("COUNT(*) >= ?", threshold)).
+		Find(&flags).Error; err != nil {
+		return nil, err
+	}
+
+	if len(flags) == 0 {
+		return nil, nil
+	}
+
+	// Flags decay with the age of the content they are on
+	var targets []uuid.UUID
+	for _, flag := range flags {
+		targets = append(targets, flagTarget(flag))
+	}
+	var content []struct {
+		ID        uuid.UUID
+		CreatedAt time.Time
+	}
+	model := interface{}(&models.Comment{})
+	if contentType == models.FlagTypePost {
+		model = &models.Post{}
+	}
+	if err := db.DB.Model(model).Select("id, created_at").Where("id IN ?", targets).Find(&content).Error; err != nil {
+		return nil, err
+	}
+	postedAt := make(map[uuid.UUID]time.Time, len(content))
+	for _, item := range content {
+		postedAt[item.ID] = item.CreatedAt
+	}
+
+	weights := make(map[uuid.UUID]float64)
+	for i, factor := range s.flagPolicy.FlagFactors(flags, postedAt) {
+		weights[flagTarget(flags[i])] += factor
+	}
+
+	var ids []uuid.UUID
+	for id, weight := range weights {
+		if weight >= float64(threshold) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// GetPost returns a post with all of its flags
+func (s *ModerationService) GetPost(postID uuid.UUID) (*QueueItem, error) {
+	var post models.Post
+	if err := db.DB.First(&post, postID).Error; err != nil {
+		return nil, fmt.Errorf("post not found")
+	}
+
+	items := []QueueItem{postQueueItem(&post)}
+	if err := s.attachFlags(items); err != nil {
+		return nil, err
+	}
+	return &items[0], nil
+}
+
+// GetComment returns a comment with all of its flags
+func (s *ModerationService) GetComment(commentID uuid.UUID) (*QueueItem, error) {
+	var comment models.Comment
+	if err := db.DB.First(&comment, commentID).Error; err != nil {
+		return nil, fmt.Errorf("comment not found")
+	}
+
+	items := []QueueItem{commentQueueItem(&comment)}
+	if err := s.attachFlags(items); err != nil {
+		return nil, err
+	}
+	return &items[0], nil
+}
+
+// ApprovePost clears the global flag on a post and dismisses its open flags
+func (s *ModerationService) ApprovePost(postID uuid.UUID, actor, reason string) error {
+	return s.moderate(models.FlagTypePost, postID, models.ModerationApprove, actor, reason, nil)
+}
+
+// RemovePost removes a post from public view and upholds its open flags
+func (s *ModerationService) RemovePost(postID uuid.UUID, actor, reason string) error {
+	return s.moderate(models.FlagTypePost, postID, models.ModerationRemove, actor, reason, nil)
+}
+
+// ApproveComment clears the global flag on a comment and dismisses its open flags
+func (s *ModerationService) ApproveComment(commentID uuid.UUID, actor, reason string) error {
+	return s.moderate(models.FlagTypeComment, commentID, models.ModerationApprove, actor, reason, nil)
+}
+
+// RemoveComment removes a comment from public view and upholds its open flags
+func (s *ModerationService) RemoveComment(commentID uuid.UUID, actor, reason string) error {
+	return s.moderate(models.FlagTypeComment, commentID, models.ModerationRemove, actor, reason, nil)
+}
+
+// Bulk applies one action to many posts and comments, reporting each outcome
+func (s *ModerationService) Bulk(action string, targets []ModerationTarget, actor, reason string) ([]BulkResult, error) {
+	if action != models.ModerationApprove && action != models.ModerationRemove {
+		return nil, fmt.Errorf("invalid action")
+	}
+
+	results := make([]BulkResult, 0, len(targets))
+	for _, target := range targets {
+		result := BulkResult{Type: target.Type, ID: target.ID, OK: true}
+		if err := s.moderate(target.Type, target.ID, action, actor, reason, nil); err != nil {
+			result.OK = false
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// moderate applies a moderator decision to a post or comment and resolves its open flags.
+// within, if set, runs in the same transaction so related records change atomically.
+func (s *ModerationService) moderate(contentType string, id uuid.UUID, action, actor, reason string, within func(tx *gorm.DB) error) error {
+	var model interface{}
+	var flagColumn string
+	switch contentType {
+	case models.FlagTypePost:
+		model, flagColumn = &models.Post{}, "post_id"
+	case models.FlagTypeComment:
+		model, flagColumn = &models.Comment{}, "comment_id"
+	default:
+		return fmt.Errorf("invalid content type")
+	}
+
+	updates := map[string]interface{}{}
+	resolution := ""
+	switch action {
+	case models.ModerationApprove:
+		updates["flagged"] = false
+		updates["status"] = models.StatusVisible
+		resolution = models.FlagResolutionDismissed
+	case models.ModerationRemove:
+		updates["status"] = models.StatusRemoved
+		resolution = models.FlagResolutionUpheld
+	default:
+		return fmt.Errorf("invalid action")
+	}
+
+	var flaggers []string
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(model, "id = ?", id).Error; err != nil {
+			return fmt.Errorf("%s not found", contentType)
+		}
+
+		if err := tx.Model(model).Where("id = ?", id).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		openFlags := tx.Model(&models.Flag{}).
+			Where("flag_type = ? AND "+flagColumn+" = ? AND resolution = ?", contentType, id, models.FlagResolutionPending).
+			Session(&gorm.Session{})
+		if err := openFlags.Pluck("ip_hash", &flaggers).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := openFlags.Updates(map[string]interface{}{
+			"resolution":  resolution,
+			"resolved_by": actor,
+			"resolved_at": &now,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := s.audit.Record(tx, actor, action, contentType, id.String(), reason,
+			fmt.Sprintf("%d open flags %s", len(flaggers), resolution)); err != nil {
+			return err
+		}
+
+		if within != nil {
+			return within(tx)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Moderation: %s %s %s by %s (%s)", action, contentType, id, actor, reason)
+
+	// Feed the ruling back into each flagger's trust
+	s.trust.RecordFlagOutcome(flaggers, resolution == models.FlagResolutionUpheld)
+	return nil
+}
+
+// attachFlags loads the flags and any appeal for each queue item
+func (s *ModerationService) attachFlags(items []QueueItem) error {
+	var postIDs, commentIDs []uuid.UUID
+	for _, item := range items {
+		if item.Type == models.FlagTypePost {
+			postIDs = append(postIDs, item.ID)
+		} else {
+			commentIDs = append(commentIDs, item.ID)
+		}
+	}
+
+	var flags []models.Flag
+	if len(postIDs) > 0 {
+		var postFlags []models.Flag
+		if err := db.DB.Where("flag_type = ? AND post_id IN ?", models.FlagTypePost, postIDs).
+			Order("created_at ASC").Find(&postFlags).Error; err != nil {
+			return err
+		}
+		flags = append(flags, postFlags...)
+	}
+	if len(commentIDs) > 0 {
+		var commentFlags []models.Flag
+		if err := db.DB.Where("flag_type = ? AND comment_id IN ?", models.FlagTypeComment, commentIDs).
+			Order("created_at ASC").Find(&commentFlags).Error; err != nil {
+			return err
+		}
+		flags = append(flags, commentFlags...)
+	}
+
+	postedAt := make(map[uuid.UUID]time.Time, len(items))
+	for _, item := range items {
+		postedAt[item.ID] = item.CreatedAt
+	}
+	for i, factor := range s.flagPolicy.FlagFactors(flags, postedAt) {
+		weight := factor * reasonWeight(flags[i].Reason)
+		flags[i].Weight = &weight
+	}
+
+	byTarget := make(map[uuid.UUID][]models.Flag)
+	for _, flag := range flags {
+		target := flagTarget(flag)
+		byTarget[target] = append(byTarget[target], flag)
+	}
+
+	var appeals []models.Appeal
+	if ids := append(postIDs, commentIDs...); len(ids) > 0 {
+		if err := db.DB.Where("target_id IN ?", ids).Find(&appeals).Error; err != nil {
+			return err
+		}
+	}
+	appealByTarget := make(map[uuid.UUID]*models.Appeal, len(appeals))
+	for i := range appeals {
+		appealByTarget[appeals[i].TargetID] = &appeals[i]
+	}
+
+	for i := range items {
+		items[i].Appeal = appealByTarget[items[i].ID]
+		items[i].Flags = byTarget[items[i].ID]
+		if items[i].Flags == nil {
+			items[i].Flags = []models.Flag{}
+		}
+		for _, flag := range items[i].Flags {
+			if flag.Resolution == models.FlagResolutionPending {
+				items[i].OpenFlags++
+			}
+		}
+	}
+	return nil
+}
+
+func postQueueItem(post *models.Post) QueueItem {
+	return QueueItem{
+		Type:           models.FlagTypePost,
+		ID:             post.ID,
+		Title:          post.Title,
+		Content:        post.Content,
+		Status:         post.Status,
+		Flagged:        post.Flagged,
+		AuthorHash:     post.IPHash,
+		CreatedAt:      post.CreatedAt,
+		DuplicateOf:    post.DuplicateOf,
+		ToxicityScore:  post.ToxicityScore,
+		ToxicityLabels: post.ToxicityLabels,
+	}
+}
+
+func commentQueueItem(comment *models.Comment) QueueItem {
+	postID := comment.PostID
+	return QueueItem{
+		Type:           models.FlagTypeComment,
+		ID:             comment.ID,
+		PostID:         &postID,
+		Content:        comment.Content,
+		Status:         comment.Status,
+		Flagged:        comment.Flagged,
+		AuthorHash:     comment.IPHash,
+		CreatedAt:      comment.CreatedAt,
+		ToxicityScore:  comment.ToxicityScore,
+		ToxicityLabels: comment.ToxicityLabels,
+	}
+}
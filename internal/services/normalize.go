@@ -0,0 +1,255 @@
+package services
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalization forms (TEXT_NORMALIZATION)
+const (
+	NormalizationNFC  = "nfc"  // Canonical composition; keeps the text as written (default)
+	NormalizationNFKC = "nfkc" // Also folds compatibility forms such as fullwidth and styled letters
+)
+
+// homoglyphs maps letters that look like ASCII letters to them. Mixed-case
+// entries are needed where the upper and lower case forms resemble
+// different letters, like Greek Η (H) and η (n).
+var homoglyphs = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'е': 'e', 'һ': 'h', 'н': 'h', 'і': 'i', 'ј': 'j', 'к': 'k',
+	'ӏ': 'l', 'м': 'm', 'о': 'o', 'р': 'p', 'ԛ': 'q', 'ѕ': 's', 'т': 't', 'у': 'y',
+	'х': 'x', 'ԁ': 'd', 'ԝ': 'w', 'ь': 'b', 'г': 'r', 'п': 'n',
+	// Greek
+	'Α': 'a', 'α': 'a', 'Β': 'b', 'β': 'b', 'Ε': 'e', 'ε': 'e', 'Ζ': 'z', 'Η': 'h',
+	'η': 'n', 'Ι': 'i', 'ι': 'i', 'Κ': 'k', 'κ': 'k', 'Μ': 'm', 'Ν': 'n', 'ν': 'v',
+	'Ο': 'o', 'ο': 'o', 'Ρ': 'p', 'ρ': 'p', 'Τ': 't', 'τ': 't', 'Υ': 'y', 'υ': 'u',
+	'Χ': 'x', 'χ': 'x', 'γ': 'y', 'ω': 'w',
+	// Latin letters without a decomposition
+	'ı': 'i', 'ȷ': 'j', 'ł': 'l', 'ø': 'o', 'đ': 'd', 'ħ': 'h', 'ɡ': 'g', 'ɑ': 'a',
+}
+
+// isInvisible reports whether a rune renders as nothing or reorders the text
+// around it: zero-width characters, bidi controls and similar format characters
+func isInvisible(r rune) bool {
+	switch {
+	case r >= 0x200B && r <= 0x200F, // Zero-width space, joiners and direction marks
+		r >= 0x202A && r <= 0x202E, // Bidi embeddings and overrides
+		r >= 0x2060 && r <= 0x2064, // Word joiner and invisible operators
+		r >= 0x2066 && r <= 0x2069, // Bidi isolates
+		r == 0x00AD, r == 0x034F, r == 0x061C, r == 0x115F, r == 0x1160,
+		r == 0x17B4, r == 0x17B5, r == 0x180E, r == 0x3164, r == 0xFEFF, r == 0xFFA0:
+		return true
+	}
+	return false
+}
+
+func isCombiningMark(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me)
+}
+
+// isEmojiComponent reports whether a zero-width joiner after r is part of an
+// emoji sequence such as 👩‍💻 and must be kept
+func isEmojiComponent(r rune) bool {
+	return unicode.Is(unicode.So, r) || r == 0xFE0F || (r >= 0x1F3FB && r <= 0x1F3FF)
+}
+
+// TextNormalizer cleans up Unicode abuse in submitted text: invisible and
+// bidi characters, stacked combining marks ("zalgo") and unnormalized forms
+type TextNormalizer struct {
+	form     norm.Form
+	maxMarks int
+}
+
+func NewTextNormalizer() *TextNormalizer {
+	form := norm.NFC
+	if strings.ToLower(os.Getenv("TEXT_NORMALIZATION")) == NormalizationNFKC {
+		form = norm.NFKC
+	}
+	return &TextNormalizer{
+		form:     form,
+		maxMarks: envInt("MAX_COMBINING_MARKS", 3),
+	}
+}
+
+// Normalize returns the text to store. Zero-width joiners are kept inside
+// emoji sequences and non-joiners after letters of scripts that use them.
+func (n *TextNormalizer) Normalize(text string) string {
+	var b strings.Builder
+	var prev rune
+	for _, r := range text {
+		keep := !isInvisible(r) ||
+			(r == 0x200D && isEmojiComponent(prev)) ||
+			(r == 0x200C && unicode.IsLetter(prev) && prev > unicode.MaxLatin1)
+		if keep {
+			b.WriteRune(r)
+			prev = r
+		}
+	}
+	text = n.form.String(b.String())
+
+	b.Reset()
+	marks := 0
+	for _, r := range text {
+		if isCombiningMark(r) {
+			marks++
+			if n.maxMarks > 0 && marks > n.maxMarks {
+				continue
+			}
+		} else {
+			marks = 0
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Skeleton returns the homoglyph-folded form of a text, for matching only
+func (n *TextNormalizer) Skeleton(text string) string {
+	s, _ := skeleton(text)
+	return s
+}
+
+// skeleton folds a text for matching: compatibility forms are decomposed,
+// marks and invisible characters dropped, letters lowercased and homoglyphs
+// replaced by the ASCII letters they imitate, so "frее ｍоnеy" and
+// "free money" look alike. It also returns, for each byte of the skeleton,
+// the offset in text of the rune it came from, so matches can be mapped back.
+func skeleton(text string) (string, []int) {
+	var b strings.Builder
+	offsets := make([]int, 0, len(text)+1)
+	for i, r := range text {
+		if isInvisible(r) || unicode.Is(unicode.Cf, r) {
+			continue
+		}
+		for _, c := range norm.NFKD.String(string(r)) {
+			if isCombiningMark(c) {
+				continue
+			}
+			if folded, ok := homoglyphs[c]; ok {
+				c = folded
+			} else if folded, ok := homoglyphs[unicode.ToLower(c)]; ok {
+				c = folded
+			} else {
+				c = unicode.ToLower(c)
+			}
+			start := b.Len()
+			b.WriteRune(c)
+			for j := start; j < b.Len(); j++ {
+				offsets = append(offsets, i)
+			}
+		}
+	}
+	offsets = append(offsets, len(text))
+	return b.String(), offsets
+}
+
+// wordChars are the characters that continue a word. RE2's \b only knows
+// ASCII word characters, so whole-word patterns spell out their boundaries.
+const wordChars = `\p{L}\p{M}\p{N}_`
+
+// wordPattern matches any of the alternatives as a whole word in any script,
+// capturing the word without the surrounding boundary characters
+func wordPattern(alternatives string) string {
+	return `(?:^|[^` + wordChars + `])(` + alternatives + `)(?:$|[^` + wordChars + `])`
+}
+
+// findWords returns the byte ranges of up to n words matched by a wordPattern
+// regexp in text, or all of them if n < 0. Each search resumes right after the
+// previous word, so the boundary it shares with the next one is not used up.
+func findWords(re *regexp.Regexp, text string, n int) [][]int {
+	var spans [][]int
+	for pos := 0; n < 0 || len(spans) < n; {
+		loc := re.FindStringSubmatchIndex(text[pos:])
+		if loc == nil || loc[3] == loc[2] {
+			break
+		}
+		spans = append(spans, []int{pos + loc[2], pos + loc[3]})
+		pos += loc[3]
+	}
+	return spans
+}
+
+// GraphemeCount returns the number of user-perceived characters in a text.
+// It follows the extended grapheme cluster rules of Unicode UAX #29 closely
+// enough for length limits: marks, joiners, variation selectors and emoji
+// modifiers extend a cluster, emoji ZWJ sequences, flag pairs and Hangul
+// syllables form one, and CR LF counts once.
+func GraphemeCount(text string) int {
+	count := 0
+	var prev rune
+	regionalIndicators := 0
+	for i, r := range text {
+		joins := i > 0 && (extendsGrapheme(r) ||
+			(prev == '\r' && r == '\n') ||
+			(prev == 0x200D && unicode.Is(unicode.So, r)) ||
+			(isRegionalIndicator(prev) && isRegionalIndicator(r) && regionalIndicators%2 == 1) ||
+			joinsHangul(prev, r))
+		if !joins {
+			count++
+		}
+
+		if isRegionalIndicator(r) {
+			regionalIndicators++
+		} else {
+			regionalIndicators = 0
+		}
+		prev = r
+	}
+	return count
+}
+
+func extendsGrapheme(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc) ||
+		r == 0x200C || r == 0x200D ||
+		(r >= 0xFE00 && r <= 0xFE0F) || (r >= 0xE0100 && r <= 0xE01EF) || // Variation selectors
+		(r >= 0x1F3FB && r <= 0x1F3FF) || // Emoji skin tone modifiers
+		(r >= 0xE0020 && r <= 0xE007F) // Tags of subdivision flags
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+// Hangul syllable types (UAX #29)
+const (
+	hangulNone = iota
+	hangulL
+	hangulV
+	hangulT
+	hangulLV
+	hangulLVT
+)
+
+func hangulType(r rune) int {
+	switch {
+	case (r >= 0x1100 && r <= 0x115F) || (r >= 0xA960 && r <= 0xA97C):
+		return hangulL
+	case (r >= 0x1160 && r <= 0x11A7) || (r >= 0xD7B0 && r <= 0xD7C6):
+		return hangulV
+	case (r >= 0x11A8 && r <= 0x11FF) || (r >= 0xD7CB && r <= 0xD7FB):
+		return hangulT
+	case r >= 0xAC00 && r <= 0xD7A3:
+		if (r-0xAC00)%28 == 0 {
+			return hangulLV
+		}
+		return hangulLVT
+	}
+	return hangulNone
+}
+
+// joinsHangul reports whether r continues the Hangul syllable ending in prev
+func joinsHangul(prev, r rune) bool {
+	switch p, t := hangulType(prev), hangulType(r); p {
+	case hangulL:
+		return t == hangulL || t == hangulV || t == hangulLV || t == hangulLVT
+	case hangulV, hangulLV:
+		return t == hangulV || t == hangulT
+	case hangulT, hangulLVT:
+		return t == hangulT
+	}
+	return false
+}
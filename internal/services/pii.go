@@ -0,0 +1,270 @@
+package services
+
+import (
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Kinds of personal information the scanner detects
+const (
+	PIIEmail       = "email"
+	PIIPhone       = "phone"
+	PIICard        = "card_number"
+	PIIGovernment  = "government_id"
+	PIITrackingURL = "tracking_url"
+)
+
+// PII modes (PII_MODE)
+const (
+	PIIModeRedact = "redact" // Replace personal information before storing (default)
+	PIIModeReject = "reject" // Refuse the submission and report what was found
+	PIIModeOff    = "off"
+)
+
+// PIIFinding is one piece of personal information found in a submission
+type PIIFinding struct {
+	Type  string `json:"type"`
+	Field string `json:"field"` // "title" or "content"
+	Text  string `json:"text"`  // The matched text, so the client can highlight it
+}
+
+// PIIError is returned when PII_MODE=reject and a submission contains
+// personal information. Handlers report the findings to the client.
+type PIIError struct {
+	Findings []PIIFinding
+}
+
+func (e *PIIError) Error() string {
+	return "personal information detected"
+}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)
+	urlPattern   = regexp.MustCompile(`https?://[^\s<>"']+`)
+	// Runs of 13-19 digits, optionally grouped with spaces or dashes
+	cardPattern = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+	// US social security numbers and UK national insurance numbers
+	governmentPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+		regexp.MustCompile(`\b[A-CEGHJ-PR-TW-Z][A-CEGHJ-NPR-TW-Z] ?\d{2} ?\d{2} ?\d{2} ?[A-D]\b`),
+	}
+	// Phone-shaped numbers, with at most one separator between digit groups:
+	// international (+44 20 7946 0958), North American ((415) 555-0132) or
+	// with a national trunk prefix (020 7946 0958, 06 12 34 56 78). Lists of
+	// years or counts do not fit these shapes. Confirmed by counting digits.
+	phonePattern = regexp.MustCompile(`\+\d{1,3}(?:[ .-]?\(?\d{1,4}\)?){2,5}\b` +
+		`|\(?\b[2-9]\d{2}\)?[ .-]?[2-9]\d{2}[ .-]?\d{4}\b` +
+		`|\b0\d{1,4}(?:[ .-]?\d{2,4}){2,4}\b`)
+	// Dates such as 2023-01-15 that would otherwise pass for phone numbers
+	datePattern = regexp.MustCompile(`^(?:19|20)\d{2}[-/.]\d{1,2}[-/.]\d{1,2}\b`)
+)
+
+// trackingParams are query parameters that identify a visitor or campaign
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "dclid": true, "gbraid": true, "wbraid": true,
+	"msclkid": true, "yclid": true, "twclid": true, "ttclid": true, "igshid": true,
+	"mc_cid": true, "mc_eid": true, "_hsenc": true, "_hsmi": true, "mkt_tok": true,
+	"ref_src": true, "si": true,
+}
+
+func isTrackingParam(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasPrefix(name, "utm_") || trackingParams[name]
+}
+
+// piiRedactions are the placeholders that replace each kind of personal information
+var piiRedactions = map[string]string{
+	PIIEmail:      "[email removed]",
+	PIIPhone:      "[phone removed]",
+	PIICard:       "[card number removed]",
+	PIIGovernment: "[ID number removed]",
+}
+
+// piiSpan is a finding located in the scanned text
+type piiSpan struct {
+	start, end  int
+	kind        string
+	replacement string
+}
+
+// PIIScanner finds personal information in submitted text
+type PIIScanner struct {
+	mode string
+}
+
+func NewPIIScanner() *PIIScanner {
+	mode := strings.ToLower(os.Getenv("PII_MODE"))
+	if mode != PIIModeReject && mode != PIIModeOff {
+		mode = PIIModeRedact
+	}
+	return &PIIScanner{mode: mode}
+}
+
+// Apply scans a title and content according to PII_MODE. It returns the text
+// to store, or a *PIIError in reject mode. Comments pass an empty title.
+func (s *PIIScanner) Apply(title, content string) (string, string, error) {
+	if s.mode == PIIModeOff {
+		return title, content, nil
+	}
+
+	titleSpans := scanPII(title)
+	contentSpans := scanPII(content)
+
+	if s.mode == PIIModeReject {
+		findings := append(piiFindings("title", title, titleSpans), piiFindings("content", content, contentSpans)...)
+		if len(findings) > 0 {
+			return "", "", &PIIError{Findings: findings}
+		}
+		return title, content, nil
+	}
+
+	return redactPII(title, titleSpans), redactPII(content, contentSpans), nil
+}
+
+// Scan reports the personal information in a text without changing it
+func (s *PIIScanner) Scan(field, text string) []PIIFinding {
+	return piiFindings(field, text, scanPII(text))
+}
+
+// scanPII locates personal information, in order of position. Earlier
+// detectors win where matches overlap, so a URL containing digits is not
+// also reported as a phone number.
+func scanPII(text string) []piiSpan {
+	var spans []piiSpan
+	overlaps := func(start, end int) bool {
+		for _, span := range spans {
+			if start < span.end && span.start < end {
+				return true
+			}
+		}
+		return false
+	}
+	add := func(start, end int, kind, replacement string) {
+		if !overlaps(start, end) {
+			spans = append(spans, piiSpan{start: start, end: end, kind: kind, replacement: replacement})
+		}
+	}
+
+	for _, loc := range urlPattern.FindAllStringIndex(text, -1) {
+		raw := strings.TrimRight(text[loc[0]:loc[1]], ".,;:!?)")
+		if cleaned, tracked := stripTrackingParams(raw); tracked {
+			add(loc[0], loc[0]+len(raw), PIITrackingURL, cleaned)
+		} else {
+			// Keep plain URLs from being scanned for phone numbers
+			add(loc[0], loc[0]+len(raw), "", raw)
+		}
+	}
+	for _, loc := range emailPattern.FindAllStringIndex(text, -1) {
+		add(loc[0], loc[1], PIIEmail, piiRedactions[PIIEmail])
+	}
+	for _, loc := range cardPattern.FindAllStringIndex(text, -1) {
+		if luhnValid(digitsOf(text[loc[0]:loc[1]])) {
+			add(loc[0], loc[1], PIICard, piiRedactions[PIICard])
+		}
+	}
+	for _, pattern := range governmentPatterns {
+		for _, loc := range pattern.FindAllStringIndex(text, -1) {
+			add(loc[0], loc[1], PIIGovernment, piiRedactions[PIIGovernment])
+		}
+	}
+	for _, loc := range phonePattern.FindAllStringIndex(text, -1) {
+		candidate := text[loc[0]:loc[1]]
+		if n := len(digitsOf(candidate)); n >= 9 && n <= 15 && !datePattern.MatchString(candidate) {
+			add(loc[0], loc[1], PIIPhone, piiRedactions[PIIPhone])
+		}
+	}
+
+	// Drop the placeholders that only shielded plain URLs
+	found := spans[:0]
+	for _, span := range spans {
+		if span.kind != "" {
+			found = append(found, span)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].start < found[j].start })
+	return found
+}
+
+// redactPII replaces each span with its redaction
+func redactPII(text string, spans []piiSpan) string {
+	if len(spans) == 0 {
+		return text
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, span := range spans {
+		b.WriteString(text[last:span.start])
+		b.WriteString(span.replacement)
+		last = span.end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+func piiFindings(field, text string, spans []piiSpan) []PIIFinding {
+	findings := make([]PIIFinding, 0, len(spans))
+	for _, span := range spans {
+		findings = append(findings, PIIFinding{Type: span.kind, Field: field, Text: text[span.start:span.end]})
+	}
+	return findings
+}
+
+// stripTrackingParams removes tracking query parameters from a URL,
+// reporting whether there were any
+func stripTrackingParams(raw string) (string, bool) {
+	u, err := url.Parse(raw)
+	if err != nil || u.RawQuery == "" {
+		return raw, false
+	}
+
+	query := u.Query()
+	tracked := false
+	for name := range query {
+		if isTrackingParam(name) {
+			query.Del(name)
+			tracked = true
+		}
+	}
+	if !tracked {
+		return raw, false
+	}
+
+	u.RawQuery = query.Encode()
+	return u.String(), true
+}
+
+func digitsOf(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// luhnValid reports whether a digit string passes the Luhn checksum used by card numbers
+func luhnValid(digits string) bool {
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
@@ -1,25 +1,85 @@
 package services
 
 import (
-	"crypto/sha256"
 	"fmt"
-	"net"
 	"os"
+	"strings"
 	"time"
 
 	"reveal/internal/db"
 	"reveal/internal/models"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
-type PostService struct{}
+// Pre-moderation modes (PREMODERATION)
+const (
+	PremoderationOff       = "off"       // New posts appear immediately (default)
+	PremoderationAll       = "all"       // Every new post waits for moderator approval
+	PremoderationUntrusted = "untrusted" // Only posts by identities below PREMODERATION_TRUST_BELOW wait
+)
+
+// Length limits, in user-perceived characters (grapheme clusters) of the normalized text
+const (
+	MaxTitleLength   = 255
+	MinContentLength = 10
+	MaxContentLength = 5000
+	MaxCommentLength = 1000
+)
+
+// MaxBytesPerCharacter bounds submitted text before it is normalized: text
+// longer than this many bytes per allowed character is refused outright, so
+// oversized or zalgo input is never processed. It leaves room for emoji
+// sequences and a few combining marks on every character.
+const MaxBytesPerCharacter = 16
+
+type PostService struct {
+	limits        RateLimitStore
+	displayPolicy *VoteDisplayPolicy
+	trust         *TrustService
+	flagPolicy    *FlagPolicyService
+	audit         *AuditService
+	bans          *BanService
+	filter        *ContentFilter
+	pii           *PIIScanner
+	reasons       *FlagReasonService
+	duplicates    *DuplicateDetector
+	links         *LinkPolicy
+	normalizer    *TextNormalizer
+	classifier    ContentClassifier
+}
 
 func NewPostService() *PostService {
-	return &PostService{}
+	return &PostService{
+		limits:        writeRateLimits(),
+		displayPolicy: NewVoteDisplayPolicy(),
+		trust:         NewTrustService(),
+		flagPolicy:    NewFlagPolicyService(),
+		audit:         NewAuditService(),
+		bans:          NewBanService(),
+		filter:        NewContentFilter(),
+		pii:           NewPIIScanner(),
+		reasons:       NewFlagReasonService(),
+		duplicates:    NewDuplicateDetector(),
+		links:         NewLinkPolicy(),
+		normalizer:    NewTextNormalizer(),
+		classifier:    NewContentClassifier(),
+	}
 }
 
 func (s *PostService) CreatePost(title, content, clientIP string) (*models.Post, error) {
+	if len(title) > MaxBytesPerCharacter*MaxTitleLength {
+		return nil, fmt.Errorf("title too long")
+	}
+	if len(content) > MaxBytesPerCharacter*MaxContentLength {
+		return nil, fmt.Errorf("content too long")
+	}
+
+	// Undo Unicode tricks before anything else looks at the text
+	title = s.normalizer.Normalize(title)
+	content = s.normalizer.Normalize(content)
+
 	// Validate input
 	if title == "" {
 		return nil, fmt.Errorf("title cannot be empty")
@@ -27,49 +87,188 @@ func (s *PostService) CreatePost(title, content, clientIP string) (*models.Post,
 	if content == "" {
 		return nil, fmt.Errorf("content cannot be empty")
 	}
+	if GraphemeCount(title) > MaxTitleLength {
+		return nil, fmt.Errorf("title too long")
+	}
+	if length := GraphemeCount(content); length < MinContentLength {
+		return nil, fmt.Errorf("content too short")
+	} else if length > MaxContentLength {
+		return nil, fmt.Errorf("content too long")
+	}
 
 	// Hash the IP address for privacy and spam prevention
 	ipHash := s.hashIP(clientIP)
 
-	// Check for spam (basic rate limiting per IP)
-	if s.isSpamming(ipHash) {
+	shadow, err := s.bans.Check(ipHash)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep personal information out of storage
+	title, content, err = s.pii.Apply(title, content)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply the moderator-defined filter rules
+	filtered := s.filter.Evaluate(title, content)
+	if filtered.Reject {
+		return nil, fmt.Errorf("content rejected")
+	}
+
+	// Enforce the link policy and hold link-heavy posts from new identities
+	var links []models.Link
+	filtered.Title, filtered.Content, links, err = s.links.Apply(filtered.Title, filtered.Content)
+	if err != nil {
+		return nil, err
+	}
+	linkHold := s.links.HoldReason(ipHash, links)
+
+	// Score the text for toxicity; the classifier may hold or label the post
+	classification := classifyContent(s.classifier, filtered.Title, filtered.Content)
+	classifierHold := classification.HoldReason()
+	filtered.Warning = mergeWarnings(filtered.Warning, classification.Warnings())
+
+	// Catch lightly edited reposts of recent posts
+	fingerprint := s.duplicates.Fingerprint(filtered.Title, filtered.Content)
+	var duplicate *DuplicateMatch
+	if s.duplicates.Action() != DuplicateActionOff {
+		duplicate, err = s.duplicates.FindMatch(fingerprint)
+		if err != nil {
+			return nil, err
+		}
+		if duplicate != nil && s.duplicates.Action() == DuplicateActionReject {
+			return nil, fmt.Errorf("duplicate post")
+		}
+	}
+
+	// Check for spam (basic rate limiting per client); only posts that would
+	// otherwise be accepted count against the limit
+	if s.isSpamming(clientIP) {
 		return nil, fmt.Errorf("rate limit exceeded")
 	}
 
+	status := models.StatusVisible
+	if filtered.Hold || duplicate != nil || linkHold != "" || classifierHold != "" || s.requiresPremoderation(ipHash) {
+		status = models.StatusPending
+	}
+
+	// The author keeps the token to manage the post later, e.g. to appeal
+	manageToken, manageTokenHash, err := newManageToken()
+	if err != nil {
+		return nil, err
+	}
+
 	post := &models.Post{
-		ID:        uuid.New(),
-		Title:     title,
-		Content:   content,
-		CreatedAt: time.Now(),
-		IPHash:    ipHash,
-		Flagged:   false,
+		ID:              uuid.New(),
+		Title:           filtered.Title,
+		Content:         filtered.Content,
+		CreatedAt:       time.Now(),
+		IPHash:          ipHash,
+		Flagged:         filtered.Flag,
+		Status:          status,
+		Shadowed:        shadow,
+		ContentWarning:  filtered.Warning,
+		Links:           links,
+		ToxicityScore:   classification.Score,
+		ToxicityLabels:  classification.Labels,
+		ManageTokenHash: manageTokenHash,
+		ManageToken:     manageToken,
+		Fingerprint:     fingerprint,
+	}
+	if duplicate != nil {
+		post.DuplicateOf = &duplicate.ClusterID
 	}
 
-	result := db.DB.Create(post)
-	if result.Error != nil {
-		return nil, result.Error
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(post).Error; err != nil {
+			return err
+		}
+		if duplicate != nil {
+			if err := s.audit.Record(tx, models.AuditActorSystem, models.AuditAutoHold, models.FlagTypePost, post.ID.String(),
+				duplicateReason(duplicate), ""); err != nil {
+				return err
+			}
+		}
+		for _, reason := range []string{linkHold, classifierHold} {
+			if reason == "" {
+				continue
+			}
+			if err := s.audit.Record(tx, models.AuditActorSystem, models.AuditAutoHold, models.FlagTypePost, post.ID.String(),
+				reason, ""); err != nil {
+				return err
+			}
+		}
+		return s.filter.recordFilterDecisions(tx, filtered, models.FlagTypePost, post.ID)
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	s.trust.RecordActivity(ipHash, models.ActivityPost)
+
 	return post, nil
 }
 
-func (s *PostService) GetRecentPosts(clientIP string, limit int) ([]models.Post, error) {
+// requiresPremoderation reports whether a new post by the identity must wait
+// for moderator approval. The untrusted mode has no effect with trust disabled.
+func (s *PostService) requiresPremoderation(ipHash string) bool {
+	switch os.Getenv("PREMODERATION") {
+	case PremoderationAll:
+		return true
+	case PremoderationUntrusted:
+		return s.trust.TrustScore(ipHash) < envFloat("PREMODERATION_TRUST_BELOW", 0.3)
+	default:
+		return false
+	}
+}
+
+func (s *PostService) GetRecentPosts(clientIP string, limit int, manageTokens ...string) ([]models.Post, error) {
+	return s.GetPosts(clientIP, limit, SortNew, manageTokens...)
+}
+
+// GetPosts lists public posts in the given sort order. Score-based sorts rank
+// the most recent candidate posts using their true vote counts. Authors also
+// see their own pending posts, recognised by IP hash or management token.
+func (s *PostService) GetPosts(clientIP string, limit int, order string, manageTokens ...string) ([]models.Post, error) {
 	if limit <= 0 {
 		limit = 50
 	}
+	if !IsValidPostSort(order) {
+		order = SortNew
+	}
+
+	fetchLimit := limit
+	if order != SortNew && rankingCandidates() > limit {
+		fetchLimit = rankingCandidates()
+	}
 	
 	ipHash := s.hashIP(clientIP)
 	var posts []models.Post
+
+	viewable := db.DB.Where("status IN ?", publicStatuses).
+		Or("status = ? AND ip_hash = ?", models.StatusPending, ipHash)
+	var tokenHashes []string
+	for _, token := range manageTokens {
+		if token = strings.TrimSpace(token); token != "" {
+			tokenHashes = append(tokenHashes, hashManageToken(token))
+		}
+	}
+	if len(tokenHashes) > 0 {
+		viewable = viewable.Or("status = ? AND manage_token_hash IN ?", models.StatusPending, tokenHashes)
+	}
 	
-	// Get posts that are not globally flagged AND not flagged by this user
+	// Get posts that are viewable, not globally flagged AND not flagged by this user
 	result := db.DB.Where("flagged = ?", false).
+		Where(viewable).
+		Where("shadowed = ? OR ip_hash = ?", false, ipHash).
 		Where("id NOT IN (?)", 
 			db.DB.Table("flags").
 				Select("post_id").
 				Where("flag_type = ? AND ip_hash = ? AND post_id IS NOT NULL", models.FlagTypePost, ipHash),
 		).
 		Order("created_at DESC").
-		Limit(limit).
+		Limit(fetchLimit).
 		Find(&posts)
 	
 	if result.Error != nil {
@@ -98,6 +297,7 @@ func (s *PostService) GetRecentPosts(clientIP string, limit int) ([]models.Post,
 	db.DB.Table("votes").
 		Select("post_id, vote_type, COUNT(*) as count").
 		Where("post_id IN ?", postIDs).
+		Where("shadowed = ? OR ip_hash = ?", false, ipHash).
 		Group("post_id, vote_type").
 		Scan(&voteCounts)
 
@@ -146,10 +346,52 @@ func (s *PostService) GetRecentPosts(clientIP string, limit int) ([]models.Post,
 		posts[i].UserVote = userVoteMap[postID]
 	}
 
-	return posts, nil
+	if order == SortNew {
+		return posts, nil
+	}
+
+	// Rank on true counts, weighted by voter trust when enabled
+	var tallies map[uuid.UUID]weightedTally
+	if s.trust.Enabled() {
+		tallies = s.trust.WeightedVoteTallies("post_id", postIDs)
+	}
+
+	items := make([]rankedItem, len(posts))
+	for i, post := range posts {
+		items[i] = rankedItem{
+			index:     i,
+			createdAt: post.CreatedAt,
+			upvotes:   float64(post.Upvotes),
+			downvotes: float64(post.Downvotes),
+		}
+		if tally, ok := tallies[post.ID]; ok {
+			items[i].upvotes, items[i].downvotes = tally.upvotes, tally.downvotes
+		}
+	}
+
+	ranked := make([]models.Post, 0, limit)
+	for _, idx := range rankOrder(items, order, bayesianPriorWeight()) {
+		if len(ranked) == limit {
+			break
+		}
+		ranked = append(ranked, posts[idx])
+	}
+
+	return ranked, nil
+}
+
+// ApplyVoteDisplay replaces exact vote counts with their public representation.
+// Moderators skip this and see the true counts.
+func (s *PostService) ApplyVoteDisplay(posts []models.Post) {
+	s.displayPolicy.ApplyToPosts(posts)
 }
 
 func (s *PostService) FlagPost(postID uuid.UUID, clientIP, reason, details string) error {
+	reason, err := s.reasons.Resolve(reason, details)
+	if err != nil {
+		return err
+	}
+
 	ipHash := s.hashIP(clientIP)
 	
 	// Check if user already flagged this post
@@ -171,48 +413,40 @@ func (s *PostService) FlagPost(postID uuid.UUID, clientIP, reason, details strin
 	if err := db.DB.Create(flag).Error; err != nil {
 		return err
 	}
+
+	s.trust.RecordActivity(ipHash, models.ActivityFlag)
 	
-	// Check if post should be globally flagged (e.g., if 5+ users flag it)
-	var flagCount int64
-	db.DB.Model(&models.Flag{}).Where("flag_type = ? AND post_id = ?", models.FlagTypePost, postID).Count(&flagCount)
-	
-	if flagCount >= 5 {
+	// Check if post should be held or globally flagged under the configured thresholds
+	var flags []models.Flag
+	db.DB.Where("flag_type = ? AND post_id = ?", models.FlagTypePost, postID).Find(&flags)
+	outcome := s.flagPolicy.Evaluate(models.FlagTypePost, post.CreatedAt, flags)
+
+	column, value, action := "", interface{}(nil), ""
+	if outcome.Hide && !post.Flagged {
 		// Globally flag the post
-		db.DB.Model(&models.Post{}).Where("id = ?", postID).Update("flagged", true)
+		column, value, action = "flagged", true, models.AuditAutoFlag
+	} else if outcome.Hold && post.Status == models.StatusVisible {
+		// Keep it visible, but queue it for moderator review
+		column, value, action = "status", models.StatusHeld, models.AuditAutoHold
+	}
+	if action == "" {
+		return nil
 	}
 
-	return nil
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Post{}).Where("id = ?", postID).Update(column, value).Error; err != nil {
+			return err
+		}
+		return s.audit.Record(tx, models.AuditActorSystem, action, models.FlagTypePost, postID.String(),
+			flagOutcomeReason(outcome), fmt.Sprintf("weight=%.2f flags=%d ignored=%d", outcome.Weight, len(flags), outcome.Ignored))
+	})
 }
 
 func (s *PostService) hashIP(ip string) string {
-	saltKey := os.Getenv("SALT_KEY")
-	if saltKey == "" {
-		saltKey = "default_salt_change_in_production"
-	}
-	
-	// Parse IP to handle IPv6 properly
-	parsedIP := net.ParseIP(ip)
-	var ipBytes []byte
-	
-	if parsedIP != nil {
-		ipBytes = parsedIP.To16() // Convert to IPv6 format (works for IPv4 too)
-	} else {
-		ipBytes = []byte(ip) // Fallback for unparseable IPs
-	}
-	
-	data := append(ipBytes, []byte(saltKey)...)
-	hash := sha256.Sum256(data)
-	return fmt.Sprintf("%x", hash)
+	return hashClientIP(ip)
 }
 
-func (s *PostService) isSpamming(ipHash string) bool {
-	// Simple spam check: max 5 posts per IP in last 10 minutes
-	var count int64
-	tenMinutesAgo := time.Now().Add(-10 * time.Minute)
-	
-	db.DB.Model(&models.Post{}).
-		Where("ip_hash = ? AND created_at > ?", ipHash, tenMinutesAgo).
-		Count(&count)
-	
-	return count >= 5
+func (s *PostService) isSpamming(clientIP string) bool {
+	// Simple spam check: max 5 posts per client in 10 minutes, counted in the database
+	return checkRate(s.limits, "post:"+RateLimitKey(clientIP), Rate{Requests: 5, Window: 10 * time.Minute})
 } 
\ No newline at end of file
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Sort orders accepted by the post and comment listings
+const (
+	SortNew      = "new"      // Newest first
+	SortOld      = "old"      // Oldest first (default for comments)
+	SortTop      = "top"      // Highest raw score (upvotes - downvotes)
+	SortBest     = "best"     // Lower bound of the Wilson score interval
+	SortBayesian = "bayesian" // Bayesian average of the upvote ratio
+)
+
+// wilsonZ is the z-score for a 95% confidence interval
+const wilsonZ = 1.96
+
+// IsValidPostSort checks if the provided sort order can be used for posts
+func IsValidPostSort(order string) bool {
+	switch order {
+	case SortNew, SortTop, SortBest, SortBayesian:
+		return true
+	}
+	return false
+}
+
+// IsValidCommentSort checks if the provided sort order can be used for comments
+func IsValidCommentSort(order string) bool {
+	switch order {
+	case SortOld, SortNew, SortTop, SortBest:
+		return true
+	}
+	return false
+}
+
+// WilsonLowerBound returns the lower bound of the Wilson score confidence
+// interval for the upvote ratio. Items with few votes get a wide interval and
+// therefore a low bound, so +1/-0 ranks below +40/-5.
+func WilsonLowerBound(upvotes, downvotes float64) float64 {
+	n := upvotes + downvotes
+	if n <= 0 {
+		return 0
+	}
+
+	phat := upvotes / n
+	z2 := wilsonZ * wilsonZ
+	numerator := phat + z2/(2*n) - wilsonZ*math.Sqrt((phat*(1-phat)+z2/(4*n))/n)
+	return numerator / (1 + z2/n)
+}
+
+// BayesianAverage returns the upvote ratio smoothed towards priorMean as if
+// priorWeight additional votes at that ratio had been cast
+func BayesianAverage(upvotes, downvotes, priorMean, priorWeight float64) float64 {
+	if priorWeight+upvotes+downvotes <= 0 {
+		return priorMean
+	}
+	return (priorWeight*priorMean + upvotes) / (priorWeight + upvotes + downvotes)
+}
+
+// rankedItem carries what the ranking functions need from a post or comment
+type rankedItem struct {
+	index     int
+	createdAt time.Time
+	upvotes   float64
+	downvotes float64
+}
+
+// rankOrder returns item indexes ordered by the given sort. Ties fall back to newest first.
+func rankOrder(items []rankedItem, order string, priorWeight float64) []int {
+	scores := make([]float64, len(items))
+
+	switch order {
+	case SortTop:
+		for i, item := range items {
+			scores[i] = item.upvotes - item.downvotes
+		}
+	case SortBest:
+		for i, item := range items {
+			scores[i] = WilsonLowerBound(item.upvotes, item.downvotes)
+		}
+	case SortBayesian:
+		var totalUp, totalVotes float64
+		for _, item := range items {
+			totalUp += item.upvotes
+			totalVotes += item.upvotes + item.downvotes
+		}
+		priorMean := 0.5
+		if totalVotes > 0 {
+			priorMean = totalUp / totalVotes
+		}
+		for i, item := range items {
+			scores[i] = BayesianAverage(item.upvotes, item.downvotes, priorMean, priorWeight)
+		}
+	}
+
+	positions := make([]int, len(items))
+	for i := range items {
+		positions[i] = i
+	}
+
+	sort.SliceStable(positions, func(a, b int) bool {
+		pa, pb := positions[a], positions[b]
+		switch order {
+		case SortOld:
+			return items[pa].createdAt.Before(items[pb].createdAt)
+		case SortNew:
+			return items[pa].createdAt.After(items[pb].createdAt)
+		}
+		if scores[pa] != scores[pb] {
+			return scores[pa] > scores[pb]
+		}
+		return items[pa].createdAt.After(items[pb].createdAt)
+	})
+
+	result := make([]int, len(positions))
+	for i, pos := range positions {
+		result[i] = items[pos].index
+	}
+	return result
+}
+
+// rankingCandidates is how many recent posts are considered for score-based sorts
+func rankingCandidates() int {
+	if v, err := strconv.Atoi(os.Getenv("RANKING_CANDIDATES")); err == nil && v > 0 {
+		return v
+	}
+	return 500
+}
+
+// bayesianPriorWeight is the number of virtual votes used by the Bayesian average
+func bayesianPriorWeight() float64 {
+	if v, err := strconv.ParseFloat(os.Getenv("BAYESIAN_PRIOR_WEIGHT"), 64); err == nil && v >= 0 {
+		return v
+	}
+	return 10
+}
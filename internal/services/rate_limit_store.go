@@ -0,0 +1,350 @@
+package services
+
+import (
+	"container/list"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"reveal/internal/db"
+	"reveal/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Rate limit store backends (RATE_LIMIT_STORE)
+const (
+	RateLimitStoreMemory   = "memory"
+	RateLimitStorePostgres = "postgres"
+)
+
+// Rate allows Requests per Window, all of which may be used at once
+type Rate struct {
+	Requests int
+	Window   time.Duration
+}
+
+// RateLimitResult is the outcome of taking one request from a bucket
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration // Until the next request is allowed, when denied
+	ResetAfter time.Duration // Until the bucket is full again
+}
+
+// RateLimitStore keeps rate limit buckets by key. Keys carry their own scope
+// prefix, so one store serves the HTTP middleware and the service checks.
+type RateLimitStore interface {
+	Allow(key string, rate Rate) (RateLimitResult, error)
+}
+
+var (
+	sharedRateLimitStore     RateLimitStore
+	sharedRateLimitStoreOnce sync.Once
+
+	writeRateLimitStore     atomic.Pointer[PostgresRateLimitStore]
+	writeRateLimitStoreOnce sync.Once
+)
+
+// writeRateLimitScopes are the key prefixes of the services' per-client limits
+var writeRateLimitScopes = []string{"post:", "comment:", "vote:"}
+
+// SharedRateLimitStore returns the process-wide store selected by
+// RATE_LIMIT_STORE: memory (the default) limits each instance on its own,
+// postgres shares buckets between all instances using the database.
+func SharedRateLimitStore() RateLimitStore {
+	sharedRateLimitStoreOnce.Do(func() {
+		if os.Getenv("RATE_LIMIT_STORE") == RateLimitStorePostgres {
+			store := NewPostgresRateLimitStore()
+			store.StartJanitor(time.Minute)
+			sharedRateLimitStore = store
+			return
+		}
+		store := NewMemoryRateLimitStore(envInt("RATE_LIMIT_MAX_CLIENTS", 100000))
+		store.StartJanitor(time.Minute)
+		sharedRateLimitStore = store
+	})
+	return sharedRateLimitStore
+}
+
+// writeRateLimits returns the store for the services' per-client post, comment
+// and vote limits. Like the row counts they replaced, these always live in the
+// database, so they hold across instances whatever RATE_LIMIT_STORE says. With
+// RATE_LIMIT_STORE=memory that makes it a second store, with its own janitor.
+func writeRateLimits() *PostgresRateLimitStore {
+	writeRateLimitStoreOnce.Do(func() {
+		store, ok := SharedRateLimitStore().(*PostgresRateLimitStore)
+		if !ok {
+			store = NewPostgresRateLimitStore()
+			store.StartJanitor(time.Minute)
+		}
+		writeRateLimitStore.Store(store)
+	})
+	return writeRateLimitStore.Load()
+}
+
+// WriteRateLimitCount returns how many buckets the per-client post, comment
+// and vote limits hold in the database (-1 before they are first used)
+func WriteRateLimitCount() int64 {
+	store := writeRateLimitStore.Load()
+	if store == nil {
+		return -1
+	}
+	count, err := store.Count(writeRateLimitScopes...)
+	if err != nil {
+		log.Printf("Warning: Failed to count rate limit buckets: %v", err)
+		return -1
+	}
+	return count
+}
+
+// ResetRateLimits empties the shared store if it is in memory, and the
+// per-client write limits once they are in use (useful for testing)
+func ResetRateLimits() {
+	if store, ok := SharedRateLimitStore().(*MemoryRateLimitStore); ok {
+		store.Reset()
+	}
+	if store := writeRateLimitStore.Load(); store != nil {
+		if err := store.Clear(writeRateLimitScopes...); err != nil {
+			log.Printf("Warning: Failed to clear rate limit buckets: %v", err)
+		}
+	}
+}
+
+// allowRate is the GCRA decision: given the stored theoretical arrival time of
+// a bucket, it returns the new one to store and the result. A zero tat is an
+// unknown, full bucket.
+func allowRate(tat, now time.Time, rate Rate) (time.Time, RateLimitResult) {
+	interval := rate.Window / time.Duration(rate.Requests)
+	if tat.Before(now) {
+		tat = now
+	}
+	next := tat.Add(interval)
+
+	if wait := next.Sub(now) - rate.Window; wait > 0 {
+		return tat, RateLimitResult{Limit: rate.Requests, RetryAfter: wait, ResetAfter: tat.Sub(now)}
+	}
+	return next, RateLimitResult{
+		Allowed:    true,
+		Limit:      rate.Requests,
+		Remaining:  int((rate.Window - next.Sub(now)) / interval),
+		ResetAfter: next.Sub(now),
+	}
+}
+
+// checkRate reports whether the key is over its rate. Store errors let the
+// request through, so a database hiccup does not lock everyone out.
+func checkRate(store RateLimitStore, key string, rate Rate) bool {
+	result, err := store.Allow(key, rate)
+	if err != nil {
+		log.Printf("Warning: Rate limit check failed: %v", err)
+		return false
+	}
+	return !result.Allowed
+}
+
+// MemoryRateLimitStore keeps buckets in this process, bounded in size. Full
+// buckets are swept by a background janitor, and when the store is full the
+// least recently used bucket is evicted.
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	recency *list.List // Front is the most recently used bucket
+	maxSize int
+
+	evictions atomic.Uint64
+}
+
+type memoryBucket struct {
+	key string
+	tat time.Time
+}
+
+// NewMemoryRateLimitStore creates a store holding at most maxSize buckets
+// (unbounded if maxSize <= 0)
+func NewMemoryRateLimitStore(maxSize int) *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		entries: make(map[string]*list.Element),
+		recency: list.New(),
+		maxSize: maxSize,
+	}
+}
+
+func (s *MemoryRateLimitStore) Allow(key string, rate Rate) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, ok := s.entries[key]
+	if ok {
+		s.recency.MoveToFront(element)
+	} else {
+		for s.maxSize > 0 && len(s.entries) >= s.maxSize {
+			s.remove(s.recency.Back())
+		}
+		element = s.recency.PushFront(&memoryBucket{key: key})
+		s.entries[key] = element
+	}
+
+	bucket := element.Value.(*memoryBucket)
+	tat, result := allowRate(bucket.tat, time.Now(), rate)
+	bucket.tat = tat
+	return result, nil
+}
+
+// Sweep drops buckets that are full again at now and returns how many
+func (s *MemoryRateLimitStore) Sweep(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for element := s.recency.Back(); element != nil; {
+		prev := element.Prev()
+		if !element.Value.(*memoryBucket).tat.After(now) {
+			s.remove(element)
+			removed++
+		}
+		element = prev
+	}
+	return removed
+}
+
+func (s *MemoryRateLimitStore) remove(element *list.Element) {
+	s.recency.Remove(element)
+	delete(s.entries, element.Value.(*memoryBucket).key)
+	s.evictions.Add(1)
+}
+
+// StartJanitor sweeps the store every interval until stop is called
+func (s *MemoryRateLimitStore) StartJanitor(interval time.Duration) (stop func()) {
+	return startJanitor(interval, func(now time.Time) { s.Sweep(now) })
+}
+
+// Len returns the number of buckets held
+func (s *MemoryRateLimitStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// Evictions returns how many buckets were swept or evicted for space
+func (s *MemoryRateLimitStore) Evictions() uint64 {
+	return s.evictions.Load()
+}
+
+// Reset forgets all buckets
+func (s *MemoryRateLimitStore) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = make(map[string]*list.Element)
+	s.recency.Init()
+}
+
+// PostgresRateLimitStore keeps buckets in the rate_limit_buckets table, so all
+// instances share them. Each decision is a single atomic upsert, written in
+// SQL that SQLite also accepts.
+type PostgresRateLimitStore struct{}
+
+func NewPostgresRateLimitStore() *PostgresRateLimitStore {
+	return &PostgresRateLimitStore{}
+}
+
+func (s *PostgresRateLimitStore) Allow(key string, rate Rate) (RateLimitResult, error) {
+	now := time.Now()
+	interval := rate.Window / time.Duration(rate.Requests)
+
+	// Take a request only if the bucket has room; otherwise no row is returned.
+	// The new arrival time is max(tat, now) + interval, i.e. excluded.tat plus
+	// however far the stored tat is ahead of now.
+	var taken []int64
+	err := db.DB.Raw(`
+		INSERT INTO rate_limit_buckets (key, tat) VALUES (@key, CAST(@next AS BIGINT))
+		ON CONFLICT (key) DO UPDATE
+		SET tat = excluded.tat + CASE WHEN rate_limit_buckets.tat > CAST(@now AS BIGINT) THEN rate_limit_buckets.tat - CAST(@now AS BIGINT) ELSE 0 END
+		WHERE excluded.tat + CASE WHEN rate_limit_buckets.tat > CAST(@now AS BIGINT) THEN rate_limit_buckets.tat - CAST(@now AS BIGINT) ELSE 0 END <= CAST(@latest AS BIGINT)
+		RETURNING tat`,
+		map[string]interface{}{
+			"key":    key,
+			"now":    now.UnixNano(),
+			"next":   now.Add(interval).UnixNano(),
+			"latest": now.Add(rate.Window).UnixNano(),
+		}).Scan(&taken).Error
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	if len(taken) > 0 {
+		// The stored time is the one before this request was taken
+		_, result := allowRate(time.Unix(0, taken[0]-int64(interval)), now, rate)
+		return result, nil
+	}
+
+	var bucket models.RateLimitBucket
+	if err := db.DB.First(&bucket, "key = ?", key).Error; err != nil {
+		return RateLimitResult{}, err
+	}
+	_, result := allowRate(time.Unix(0, bucket.TAT), now, rate)
+	return result, nil
+}
+
+// Sweep deletes buckets that are full again at now
+func (s *PostgresRateLimitStore) Sweep(now time.Time) (int64, error) {
+	result := db.DB.Where("tat <= ?", now.UnixNano()).Delete(&models.RateLimitBucket{})
+	return result.RowsAffected, result.Error
+}
+
+// Count returns the number of buckets whose keys start with one of prefixes
+func (s *PostgresRateLimitStore) Count(prefixes ...string) (int64, error) {
+	var count int64
+	err := s.withPrefixes(prefixes).Count(&count).Error
+	return count, err
+}
+
+// Clear deletes the buckets whose keys start with one of prefixes
+func (s *PostgresRateLimitStore) Clear(prefixes ...string) error {
+	return s.withPrefixes(prefixes).Delete(&models.RateLimitBucket{}).Error
+}
+
+// withPrefixes scopes a query to the buckets whose keys start with one of prefixes
+func (s *PostgresRateLimitStore) withPrefixes(prefixes []string) *gorm.DB {
+	query := db.DB.Model(&models.RateLimitBucket{})
+	if len(prefixes) == 0 {
+		return query.Where("1 = 1")
+	}
+	scope := db.DB.Where("key LIKE ?", prefixes[0]+"%")
+	for _, prefix := range prefixes[1:] {
+		scope = scope.Or("key LIKE ?", prefix+"%")
+	}
+	return query.Where(scope)
+}
+
+// StartJanitor sweeps the table every interval until stop is called
+func (s *PostgresRateLimitStore) StartJanitor(interval time.Duration) (stop func()) {
+	return startJanitor(interval, func(now time.Time) {
+		if _, err := s.Sweep(now); err != nil {
+			log.Printf("Warning: Failed to sweep rate limit buckets: %v", err)
+		}
+	})
+}
+
+// startJanitor calls sweep every interval in the background until stopped
+func startJanitor(interval time.Duration, sweep func(now time.Time)) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case now := <-ticker.C:
+				sweep(now)
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"reveal/internal/db"
+	"reveal/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SlowModeError is returned when an identity comments again on a thread in
+// slow mode before its interval has passed
+type SlowModeError struct {
+	RetryAfter time.Duration
+	Until      time.Time // When slow mode ends
+}
+
+func (e *SlowModeError) Error() string {
+	return "slow mode"
+}
+
+// SlowModeService cools down heated threads. A thread enters slow mode when
+// its comment rate or its share of downvotes and flags spikes, or when a
+// moderator switches it on.
+type SlowModeService struct {
+	audit *AuditService
+}
+
+func NewSlowModeService() *SlowModeService {
+	return &SlowModeService{
+		audit: NewAuditService(),
+	}
+}
+
+// slowModeActive reports whether a post is in slow mode
+func slowModeActive(post *models.Post, now time.Time) bool {
+	return post.SlowModeUntil != nil && post.SlowModeUntil.After(now) && post.SlowModeSeconds > 0
+}
+
+// Check returns a *SlowModeError if the post is in slow mode and the identity
+// commented on it less than the slow mode interval ago
+func (s *SlowModeService) Check(post *models.Post, ipHash string) error {
+	now := time.Now()
+	if !slowModeActive(post, now) {
+		return nil
+	}
+
+	var last models.Comment
+	err := db.DB.Select("created_at").
+		Where("post_id = ? AND ip_hash = ?", post.ID, ipHash).
+		Order("created_at DESC").
+		First(&last).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	interval := time.Duration(post.SlowModeSeconds) * time.Second
+	if wait := last.CreatedAt.Add(interval).Sub(now); wait > 0 {
+		return &SlowModeError{RetryAfter: wait, Until: *post.SlowModeUntil}
+	}
+	return nil
+}
+
+// heatReason returns why a thread counts as heated, or "" if it does not.
+// Within the last SLOW_MODE_WINDOW_MINUTES, a thread is heated with
+// SLOW_MODE_COMMENT_RATE or more new comments, or when at least
+// SLOW_MODE_MIN_REACTIONS votes and flags on it and its comments include a
+// share of SLOW_MODE_NEGATIVE_RATIO downvotes and flags.
+func (s *SlowModeService) heatReason(postID uuid.UUID, now time.Time) (string, error) {
+	windowMinutes := envFloat("SLOW_MODE_WINDOW_MINUTES", 10)
+	since := now.Add(-time.Duration(windowMinutes * float64(time.Minute)))
+
+	var comments int64
+	if err := db.DB.Model(&models.Comment{}).
+		Where("post_id = ? AND created_at > ?", postID, since).
+		Count(&comments).Error; err != nil {
+		return "", err
+	}
+	if rate := envInt("SLOW_MODE_COMMENT_RATE", 20); rate > 0 && comments >= int64(rate) {
+		return fmt.Sprintf("%d comments in %.0f minutes", comments, windowMinutes), nil
+	}
+
+	threadComments := db.DB.Model(&models.Comment{}).Select("id").Where("post_id = ?", postID)
+	var votes []struct {
+		VoteType string
+		Count    int64
+	}
+	if err := db.DB.Model(&models.Vote{}).
+		Select("vote_type, COUNT(*) AS count").
+		Where("(post_id = ? OR comment_id IN (?)) AND created_at > ? AND shadowed = ?", postID, threadComments, since, false).
+		Group("vote_type").
+		Scan(&votes).Error; err != nil {
+		return "", err
+	}
+	var flags int64
+	if err := db.DB.Model(&models.Flag{}).
+		Where("(post_id = ? OR comment_id IN (?)) AND created_at > ?", postID, threadComments, since).
+		Count(&flags).Error; err != nil {
+		return "", err
+	}
+
+	negative, reactions := flags, flags
+	for _, v := range votes {
+		reactions += v.Count
+		if v.VoteType == models.VoteTypeDownvote {
+			negative += v.Count
+		}
+	}
+	minReactions := envInt("SLOW_MODE_MIN_REACTIONS", 20)
+	if minReactions <= 0 || reactions < int64(minReactions) {
+		return "", nil
+	}
+	if ratio := float64(negative) / float64(reactions); ratio >= envFloat("SLOW_MODE_NEGATIVE_RATIO", 0.6) {
+		return fmt.Sprintf("%.0f%% of %d reactions are downvotes or flags", ratio*100, reactions), nil
+	}
+	return "", nil
+}
+
+// Evaluate puts a heated thread into slow mode for SLOW_MODE_COOLDOWN_MINUTES,
+// allowing one comment per identity every SLOW_MODE_INTERVAL_SECONDS. It
+// leaves threads alone that are already in slow mode or whose slow mode a
+// moderator switched off recently. The post is updated in place.
+func (s *SlowModeService) Evaluate(post *models.Post) error {
+	now := time.Now()
+	if slowModeActive(post, now) || (post.SlowModeSuppressedUntil != nil && post.SlowModeSuppressedUntil.After(now)) {
+		return nil
+	}
+
+	reason, err := s.heatReason(post.ID, now)
+	if err != nil || reason == "" {
+		return err
+	}
+
+	seconds := envInt("SLOW_MODE_INTERVAL_SECONDS", 60)
+	if seconds <= 0 {
+		return nil
+	}
+	until := now.Add(time.Duration(envFloat("SLOW_MODE_COOLDOWN_MINUTES", 30) * float64(time.Minute)))
+
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		// Only one of several concurrent comments switches slow mode on
+		result := tx.Model(&models.Post{}).
+			Where("id = ? AND (slow_mode_until IS NULL OR slow_mode_until <= ?)", post.ID, now).
+			Updates(map[string]interface{}{"slow_mode_until": until, "slow_mode_seconds": seconds})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		post.SlowModeUntil, post.SlowModeSeconds = &until, seconds
+		return s.audit.Record(tx, models.AuditActorSystem, models.AuditSlowModeOn, models.FlagTypePost, post.ID.String(),
+			reason, fmt.Sprintf("interval=%ds until=%s", seconds, until.UTC().Format(time.RFC3339)))
+	})
+}
+
+// SetSlowMode lets a moderator switch slow mode on, with the given interval
+// between comments for the given duration, or off. Switched off, it does not
+// restart automatically for SLOW_MODE_COOLDOWN_MINUTES.
+func (s *SlowModeService) SetSlowMode(postID uuid.UUID, enabled bool, interval, duration time.Duration, actor, reason string) (*models.Post, error) {
+	if enabled && (interval < time.Second || duration <= 0) {
+		return nil, fmt.Errorf("invalid slow mode")
+	}
+
+	var post models.Post
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&post, "id = ?", postID).Error; err != nil {
+			return fmt.Errorf("post not found")
+		}
+
+		now := time.Now()
+		updates := map[string]interface{}{}
+		action, details := models.AuditSlowModeOn, ""
+		if enabled {
+			until := now.Add(duration)
+			updates["slow_mode_until"] = until
+			updates["slow_mode_seconds"] = int(interval / time.Second)
+			updates["slow_mode_suppressed_until"] = nil
+			details = fmt.Sprintf("interval=%ds until=%s", int(interval/time.Second), until.UTC().Format(time.RFC3339))
+		} else {
+			suppressed := now.Add(time.Duration(envFloat("SLOW_MODE_COOLDOWN_MINUTES", 30) * float64(time.Minute)))
+			updates["slow_mode_until"] = nil
+			updates["slow_mode_seconds"] = 0
+			updates["slow_mode_suppressed_until"] = suppressed
+			action = models.AuditSlowModeOff
+		}
+
+		if err := tx.Model(&post).Updates(updates).Error; err != nil {
+			return err
+		}
+		return s.audit.Record(tx, actor, action, models.FlagTypePost, postID.String(), reason, details)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.DB.First(&post, "id = ?", postID).Error; err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"math"
+	"os"
+	"time"
+
+	"reveal/internal/db"
+	"reveal/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TrustConfig controls how identity trust is computed and how strongly it weights votes
+type TrustConfig struct {
+	Enabled            bool
+	AgeWeight          float64       // Weight of identity age in the trust score
+	ActivityWeight     float64       // Weight of participation in the trust score
+	FlagWeight         float64       // Weight of flag accuracy in the trust score
+	AgeSaturation      time.Duration // Age at which the age component reaches 1
+	ActivitySaturation float64       // Number of actions at which the activity component reaches 1
+	MinVoteWeight      float64       // Vote weight of an identity with zero trust
+}
+
+// LoadTrustConfig reads the trust configuration from the environment
+func LoadTrustConfig() TrustConfig {
+	return TrustConfig{
+		Enabled:            os.Getenv("TRUST_ENABLED") != "false",
+		AgeWeight:          envFloat("TRUST_AGE_WEIGHT", 0.5),
+		ActivityWeight:     envFloat("TRUST_ACTIVITY_WEIGHT", 0.3),
+		FlagWeight:         envFloat("TRUST_FLAG_WEIGHT", 0.2),
+		AgeSaturation:      time.Duration(envFloat("TRUST_AGE_SATURATION_DAYS", 30) * float64(24*time.Hour)),
+		ActivitySaturation: envFloat("TRUST_ACTIVITY_SATURATION", 50),
+		MinVoteWeight:      envFloat("TRUST_MIN_VOTE_WEIGHT", 0.1),
+	}
+}
+
+// TrustService records identity activity and derives trust scores from it
+type TrustService struct {
+	config TrustConfig
+}
+
+func NewTrustService() *TrustService {
+	return &TrustService{
+		config: LoadTrustConfig(),
+	}
+}
+
+// Enabled reports whether trust tracking and vote weighting are switched on
+func (s *TrustService) Enabled() bool {
+	return s.config.Enabled
+}
+
+// RecordActivity notes that an identity performed an action, creating the identity on first sight
+func (s *TrustService) RecordActivity(ipHash, activity string) {
+	if !s.config.Enabled || ipHash == "" {
+		return
+	}
+
+	now := time.Now()
+	identity := models.Identity{
+		IPHash:      ipHash,
+		FirstSeenAt: now,
+		LastSeenAt:  now,
+	}
+
+	column := ""
+	switch activity {
+	case models.ActivityPost:
+		column, identity.PostCount = "post_count", 1
+	case models.ActivityComment:
+		column, identity.CommentCount = "comment_count", 1
+	case models.ActivityVote:
+		column, identity.VoteCount = "vote_count", 1
+	case models.ActivityFlag:
+		column, identity.FlagCount = "flag_count", 1
+	default:
+		return
+	}
+
+	db.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "ip_hash"}},
+		DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "last_seen_at"}, Value: now},
+			{Column: clause.Column{Name: column}, Value: gorm.Expr("identities." + column + " + 1")},
+		},
+	}).Create(&identity)
+}
+
+// RecordFlagOutcome updates the flag accuracy of the identities whose flags a
+// moderator ruled on. Accuracy is tracked even with trust weighting disabled,
+// because flag thresholds use it to discount flag abuse.
+func (s *TrustService) RecordFlagOutcome(ipHashes []string, upheld bool) {
+	column := "flags_rejected"
+	if upheld {
+		column = "flags_upheld"
+	}
+
+	now := time.Now()
+	for _, ipHash := range ipHashes {
+		identity := models.Identity{IPHash: ipHash, FirstSeenAt: now, LastSeenAt: now}
+		if upheld {
+			identity.FlagsUpheld = 1
+		} else {
+			identity.FlagsRejected = 1
+		}
+
+		db.DB.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "ip_hash"}},
+			DoUpdates: clause.Set{
+				{Column: clause.Column{Name: column}, Value: gorm.Expr("identities." + column + " + 1")},
+			},
+		}).Create(&identity)
+	}
+}
+
+// Score returns the trust score of an identity in [0, 1]
+func (s *TrustService) Score(identity *models.Identity) float64 {
+	cfg := s.config
+	totalWeight := cfg.AgeWeight + cfg.ActivityWeight + cfg.FlagWeight
+	if totalWeight <= 0 {
+		return 1
+	}
+
+	ageScore := 0.0
+	if cfg.AgeSaturation > 0 {
+		ageScore = math.Min(1, float64(time.Since(identity.FirstSeenAt))/float64(cfg.AgeSaturation))
+	}
+
+	activityScore := 0.0
+	if cfg.ActivitySaturation > 0 {
+		actions := float64(identity.PostCount + identity.CommentCount + identity.VoteCount + identity.FlagCount)
+		activityScore = math.Min(1, math.Log1p(actions)/math.Log1p(cfg.ActivitySaturation))
+	}
+
+	// Laplace-smoothed share of upheld flags; identities without rulings start neutral
+	flagScore := float64(identity.FlagsUpheld+1) / float64(identity.FlagsUpheld+identity.FlagsRejected+2)
+
+	score := (cfg.AgeWeight*ageScore + cfg.ActivityWeight*activityScore + cfg.FlagWeight*flagScore) / totalWeight
+	return math.Max(0, math.Min(1, score))
+}
+
+// TrustScore returns the trust score of the identity behind ipHash.
+// With trust disabled every identity is fully trusted.
+func (s *TrustService) TrustScore(ipHash string) float64 {
+	if !s.config.Enabled {
+		return 1
+	}
+
+	var identity models.Identity
+	if err := db.DB.First(&identity, "ip_hash = ?", ipHash).Error; err != nil {
+		// Never seen before
+		identity = models.Identity{IPHash: ipHash, FirstSeenAt: time.Now()}
+	}
+	return s.Score(&identity)
+}
+
+// IsNew reports whether the identity behind ipHash was first seen less than
+// age ago. With trust disabled identities are not tracked and none is new.
+func (s *TrustService) IsNew(ipHash string, age time.Duration) bool {
+	if !s.config.Enabled {
+		return false
+	}
+
+	var identity models.Identity
+	if err := db.DB.First(&identity, "ip_hash = ?", ipHash).Error; err != nil {
+		// Never seen before
+		return true
+	}
+	return time.Since(identity.FirstSeenAt) < age
+}
+
+// voteWeight maps a trust score to a vote weight in [MinVoteWeight, 1]
+func (s *TrustService) voteWeight(score float64) float64 {
+	return s.config.MinVoteWeight + (1-s.config.MinVoteWeight)*score
+}
+
+// voteWeights returns the vote weight of each identity
+func (s *TrustService) voteWeights(ipHashes []string) map[string]float64 {
+	weights := make(map[string]float64, len(ipHashes))
+	if len(ipHashes) == 0 {
+		return weights
+	}
+
+	var identities []models.Identity
+	db.DB.Where("ip_hash IN ?", ipHashes).Find(&identities)
+
+	for _, identity := range identities {
+		weights[identity.IPHash] = s.voteWeight(s.Score(&identity))
+	}
+	unknown := s.voteWeight(s.Score(&models.Identity{FirstSeenAt: time.Now()}))
+	for _, ipHash := range ipHashes {
+		if _, ok := weights[ipHash]; !ok {
+			weights[ipHash] = unknown
+		}
+	}
+	return weights
+}
+
+// weightedTally holds trust-weighted vote totals for one post or comment
+type weightedTally struct {
+	upvotes   float64
+	downvotes float64
+}
+
+// WeightedVoteTallies returns trust-weighted vote totals for the given posts or
+// comments (column is "post_id" or "comment_id"). These are used for ranking
+// only; displayed counts always remain plain vote counts. Shadowed votes are
+// left out.
+func (s *TrustService) WeightedVoteTallies(column string, ids []uuid.UUID) map[uuid.UUID]weightedTally {
+	tallies := make(map[uuid.UUID]weightedTally, len(ids))
+	if len(ids) == 0 || (column != "post_id" && column != "comment_id") {
+		return tallies
+	}
+
+	type voteRow struct {
+		TargetID uuid.UUID
+		VoteType string
+		IPHash   string
+	}
+
+	var rows []voteRow
+	db.DB.Table("votes").
+		Select(column+" AS target_id, vote_type, ip_hash").
+		Where(column+" IN ? AND shadowed = ?", ids, false).
+		Scan(&rows)
+
+	seen := make(map[string]bool)
+	var ipHashes []string
+	for _, row := range rows {
+		if !seen[row.IPHash] {
+			seen[row.IPHash] = true
+			ipHashes = append(ipHashes, row.IPHash)
+		}
+	}
+	weights := s.voteWeights(ipHashes)
+
+	for _, row := range rows {
+		tally := tallies[row.TargetID]
+		if row.VoteType == models.VoteTypeUpvote {
+			tally.upvotes += weights[row.IPHash]
+		} else if row.VoteType == models.VoteTypeDownvote {
+			tally.downvotes += weights[row.IPHash]
+		}
+		tallies[row.TargetID] = tally
+	}
+	return tallies
+}
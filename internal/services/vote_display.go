@@ -0,0 +1,96 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"reveal/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// VoteDisplayPolicy controls how vote counts are shown to regular users.
+// Counts on fresh content are hidden for HideFor, after which Upvotes and
+// Downvotes carry up to +/-FuzzAmount of deterministic noise. Ranking always
+// uses the true counts; the policy is only applied to API responses.
+type VoteDisplayPolicy struct {
+	HideFor    time.Duration
+	FuzzAmount int64
+	salt       string
+}
+
+// NewVoteDisplayPolicy loads the policy from VOTE_HIDE_MINUTES and VOTE_FUZZ_AMOUNT
+func NewVoteDisplayPolicy() *VoteDisplayPolicy {
+	hideMinutes := 60
+	if v, err := strconv.Atoi(os.Getenv("VOTE_HIDE_MINUTES")); err == nil && v >= 0 {
+		hideMinutes = v
+	}
+
+	fuzz := int64(2)
+	if v, err := strconv.ParseInt(os.Getenv("VOTE_FUZZ_AMOUNT"), 10, 64); err == nil && v >= 0 {
+		fuzz = v
+	}
+
+	salt := os.Getenv("SALT_KEY")
+	if salt == "" {
+		salt = "default_salt_change_in_production"
+	}
+
+	return &VoteDisplayPolicy{
+		HideFor:    time.Duration(hideMinutes) * time.Minute,
+		FuzzAmount: fuzz,
+		salt:       salt,
+	}
+}
+
+// Apply returns the displayed upvote and downvote counts for an item and
+// whether the counts are currently hidden
+func (p *VoteDisplayPolicy) Apply(id uuid.UUID, createdAt time.Time, upvotes, downvotes int64) (int64, int64, bool) {
+	if p.HideFor > 0 && time.Since(createdAt) < p.HideFor {
+		return 0, 0, true
+	}
+	if p.FuzzAmount == 0 {
+		return upvotes, downvotes, false
+	}
+
+	// Noise is derived from the item and its true counts, so repeated
+	// requests return the same numbers and cannot be averaged out.
+	seed := fmt.Sprintf("%s|%s|%d|%d", p.salt, id, upvotes, downvotes)
+	sum := sha256.Sum256([]byte(seed))
+	upNoise := p.noise(binary.BigEndian.Uint64(sum[0:8]))
+	downNoise := p.noise(binary.BigEndian.Uint64(sum[8:16]))
+
+	return clampVotes(upvotes + upNoise), clampVotes(downvotes + downNoise), false
+}
+
+// ApplyToPosts replaces the vote counts on each post with their displayed values
+func (p *VoteDisplayPolicy) ApplyToPosts(posts []models.Post) {
+	for i := range posts {
+		posts[i].Upvotes, posts[i].Downvotes, posts[i].VotesHidden =
+			p.Apply(posts[i].ID, posts[i].CreatedAt, posts[i].Upvotes, posts[i].Downvotes)
+	}
+}
+
+// ApplyToComments replaces the vote counts on each comment with their displayed values
+func (p *VoteDisplayPolicy) ApplyToComments(comments []models.Comment) {
+	for i := range comments {
+		comments[i].Upvotes, comments[i].Downvotes, comments[i].VotesHidden =
+			p.Apply(comments[i].ID, comments[i].CreatedAt, comments[i].Upvotes, comments[i].Downvotes)
+	}
+}
+
+func (p *VoteDisplayPolicy) noise(n uint64) int64 {
+	span := uint64(2*p.FuzzAmount + 1)
+	return int64(n%span) - p.FuzzAmount
+}
+
+func clampVotes(n int64) int64 {
+	if n < 0 {
+		return 0
+	}
+	return n
+}
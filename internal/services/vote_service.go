@@ -1,22 +1,31 @@
 package services
 
 import (
-	"crypto/sha256"
 	"fmt"
-	"net"
-	"os"
 	"time"
 
 	"reveal/internal/db"
 	"reveal/internal/models"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-type VoteService struct{}
+type VoteService struct {
+	limits        RateLimitStore
+	displayPolicy *VoteDisplayPolicy
+	trust         *TrustService
+	bans          *BanService
+}
 
 func NewVoteService() *VoteService {
-	return &VoteService{}
+	return &VoteService{
+		limits:        writeRateLimits(),
+		displayPolicy: NewVoteDisplayPolicy(),
+		trust:         NewTrustService(),
+		bans:          NewBanService(),
+	}
 }
 
 // VoteOnPost adds or toggles a vote on a post
@@ -35,8 +44,13 @@ func (s *VoteService) VoteOnPost(postID uuid.UUID, voteType, clientIP string) er
 	// Hash the IP address
 	ipHash := s.hashIP(clientIP)
 
-	// Check for spam (basic rate limiting per IP)
-	if s.isSpamming(ipHash) {
+	shadow, err := s.bans.Check(ipHash)
+	if err != nil {
+		return err
+	}
+
+	// Check for spam (basic rate limiting per client)
+	if s.isSpamming(clientIP) {
 		return fmt.Errorf("rate limit exceeded")
 	}
 
@@ -51,22 +65,12 @@ func (s *VoteService) VoteOnPost(postID uuid.UUID, voteType, clientIP string) er
 			return s.RemoveVoteFromPost(postID, clientIP)
 		} else {
 			// Different vote type, update the existing vote
-			existingVote.VoteType = voteType
-			existingVote.CreatedAt = time.Now()
-			return db.DB.Save(&existingVote).Error
+			return s.changePostVote(&existingVote, voteType, shadow)
 		}
 	}
 
 	// Create new vote
-	vote := &models.Vote{
-		ID:        uuid.New(),
-		PostID:    &postID,
-		VoteType:  voteType,
-		IPHash:    ipHash,
-		CreatedAt: time.Now(),
-	}
-
-	return db.DB.Create(vote).Error
+	return s.createPostVote(postID, voteType, ipHash, shadow)
 }
 
 // VoteOnComment adds or toggles a vote on a comment
@@ -85,8 +89,13 @@ func (s *VoteService) VoteOnComment(commentID uuid.UUID, voteType, clientIP stri
 	// Hash the IP address
 	ipHash := s.hashIP(clientIP)
 
-	// Check for spam (basic rate limiting per IP)
-	if s.isSpamming(ipHash) {
+	shadow, err := s.bans.Check(ipHash)
+	if err != nil {
+		return err
+	}
+
+	// Check for spam (basic rate limiting per client)
+	if s.isSpamming(clientIP) {
 		return fmt.Errorf("rate limit exceeded")
 	}
 
@@ -103,6 +112,7 @@ func (s *VoteService) VoteOnComment(commentID uuid.UUID, voteType, clientIP stri
 			// Different vote type, update the existing vote
 			existingVote.VoteType = voteType
 			existingVote.CreatedAt = time.Now()
+			existingVote.Shadowed = existingVote.Shadowed || shadow
 			return db.DB.Save(&existingVote).Error
 		}
 	}
@@ -114,30 +124,160 @@ func (s *VoteService) VoteOnComment(commentID uuid.UUID, voteType, clientIP stri
 		VoteType:  voteType,
 		IPHash:    ipHash,
 		CreatedAt: time.Now(),
+		Shadowed:  shadow,
+	}
+
+	if err := db.DB.Create(vote).Error; err != nil {
+		return err
+	}
+
+	s.trust.RecordActivity(ipHash, models.ActivityVote)
+	return nil
+}
+
+// SetPostVote sets the user's vote on a post to an explicit state.
+// Unlike VoteOnPost it is idempotent: repeating the same request leaves the vote unchanged.
+func (s *VoteService) SetPostVote(postID uuid.UUID, voteType, clientIP string) error {
+	if voteType != models.VoteTypeNone && !models.IsValidVoteType(voteType) {
+		return fmt.Errorf("invalid vote type")
+	}
+
+	// Verify post exists
+	var post models.Post
+	if err := db.DB.First(&post, postID).Error; err != nil {
+		return fmt.Errorf("post not found")
+	}
+
+	ipHash := s.hashIP(clientIP)
+
+	shadow, err := s.bans.Check(ipHash)
+	if err != nil {
+		return err
+	}
+
+	var existingVote models.Vote
+	hasVote := db.DB.Where("post_id = ? AND ip_hash = ?", postID, ipHash).First(&existingVote).Error == nil
+
+	if voteType == models.VoteTypeNone {
+		if !hasVote {
+			return nil
+		}
+		return s.deletePostVote(&existingVote)
+	}
+
+	if hasVote && existingVote.VoteType == voteType {
+		return nil
+	}
+
+	// Check for spam (basic rate limiting per client)
+	if s.isSpamming(clientIP) {
+		return fmt.Errorf("rate limit exceeded")
+	}
+
+	if hasVote {
+		return s.changePostVote(&existingVote, voteType, shadow)
+	}
+
+	return s.createPostVote(postID, voteType, ipHash, shadow)
+}
+
+// SetCommentVote sets the user's vote on a comment to an explicit state.
+// Unlike VoteOnComment it is idempotent: repeating the same request leaves the vote unchanged.
+func (s *VoteService) SetCommentVote(commentID uuid.UUID, voteType, clientIP string) error {
+	if voteType != models.VoteTypeNone && !models.IsValidVoteType(voteType) {
+		return fmt.Errorf("invalid vote type")
+	}
+
+	// Verify comment exists
+	var comment models.Comment
+	if err := db.DB.First(&comment, commentID).Error; err != nil {
+		return fmt.Errorf("comment not found")
+	}
+
+	ipHash := s.hashIP(clientIP)
+
+	shadow, err := s.bans.Check(ipHash)
+	if err != nil {
+		return err
+	}
+
+	var existingVote models.Vote
+	hasVote := db.DB.Where("comment_id = ? AND ip_hash = ?", commentID, ipHash).First(&existingVote).Error == nil
+
+	if voteType == models.VoteTypeNone {
+		if !hasVote {
+			return nil
+		}
+		return db.DB.Delete(&existingVote).Error
+	}
+
+	if hasVote && existingVote.VoteType == voteType {
+		return nil
+	}
+
+	// Check for spam (basic rate limiting per client)
+	if s.isSpamming(clientIP) {
+		return fmt.Errorf("rate limit exceeded")
+	}
+
+	if hasVote {
+		existingVote.VoteType = voteType
+		existingVote.CreatedAt = time.Now()
+		existingVote.Shadowed = existingVote.Shadowed || shadow
+		return db.DB.Save(&existingVote).Error
+	}
+
+	vote := &models.Vote{
+		ID:        uuid.New(),
+		CommentID: &commentID,
+		VoteType:  voteType,
+		IPHash:    ipHash,
+		CreatedAt: time.Now(),
+		Shadowed:  shadow,
 	}
 
-	return db.DB.Create(vote).Error
+	if err := db.DB.Create(vote).Error; err != nil {
+		return err
+	}
+
+	s.trust.RecordActivity(ipHash, models.ActivityVote)
+	return nil
 }
 
 // RemoveVoteFromPost removes a user's vote from a post
 func (s *VoteService) RemoveVoteFromPost(postID uuid.UUID, clientIP string) error {
+	// Verify post exists
+	var post models.Post
+	if err := db.DB.First(&post, postID).Error; err != nil {
+		return fmt.Errorf("post not found")
+	}
+
 	ipHash := s.hashIP(clientIP)
-	result := db.DB.Where("post_id = ? AND ip_hash = ?", postID, ipHash).Delete(&models.Vote{})
-	
-	if result.Error != nil {
-		return result.Error
+	if _, err := s.bans.Check(ipHash); err != nil {
+		return err
 	}
-	
-	if result.RowsAffected == 0 {
+
+	var existingVote models.Vote
+	if err := db.DB.Where("post_id = ? AND ip_hash = ?", postID, ipHash).First(&existingVote).Error; err != nil {
 		return fmt.Errorf("vote not found")
 	}
 
-	return nil
+	return s.deletePostVote(&existingVote)
 }
 
 // RemoveVoteFromComment removes a user's vote from a comment
 func (s *VoteService) RemoveVoteFromComment(commentID uuid.UUID, clientIP string) error {
+	// Verify comment exists
+	var comment models.Comment
+	if err := db.DB.First(&comment, commentID).Error; err != nil {
+		return fmt.Errorf("comment not found")
+	}
+
 	ipHash := s.hashIP(clientIP)
+	if _, err := s.bans.Check(ipHash); err != nil {
+		return err
+	}
+
 	result := db.DB.Where("comment_id = ? AND ip_hash = ?", commentID, ipHash).Delete(&models.Vote{})
 	
 	if result.Error != nil {
@@ -151,18 +291,169 @@ func (s *VoteService) RemoveVoteFromComment(commentID uuid.UUID, clientIP string
 	return nil
 }
 
+// createPostVote stores a new post vote and records it in the hourly rollup.
+// Shadowed votes are left out of the rollup.
+func (s *VoteService) createPostVote(postID uuid.UUID, voteType, ipHash string, shadow bool) error {
+	vote := &models.Vote{
+		ID:        uuid.New(),
+		PostID:    &postID,
+		VoteType:  voteType,
+		IPHash:    ipHash,
+		CreatedAt: time.Now(),
+		Shadowed:  shadow,
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(vote).Error; err != nil {
+			return err
+		}
+		if shadow {
+			return nil
+		}
+		return adjustPostVoteRollup(tx, postID, voteType, vote.CreatedAt, 1)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.trust.RecordActivity(ipHash, models.ActivityVote)
+	return nil
+}
+
+// changePostVote switches an existing post vote to another type, moving it to the
+// current hour. A vote changed while its voter is shadowbanned becomes shadowed.
+func (s *VoteService) changePostVote(vote *models.Vote, voteType string, shadow bool) error {
+	previousType, previousAt, previousShadowed := vote.VoteType, vote.CreatedAt, vote.Shadowed
+	vote.VoteType = voteType
+	vote.CreatedAt = time.Now()
+	vote.Shadowed = vote.Shadowed || shadow
+
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(vote).Error; err != nil {
+			return err
+		}
+		if !previousShadowed {
+			if err := adjustPostVoteRollup(tx, *vote.PostID, previousType, previousAt, -1); err != nil {
+				return err
+			}
+		}
+		if vote.Shadowed {
+			return nil
+		}
+		return adjustPostVoteRollup(tx, *vote.PostID, voteType, vote.CreatedAt, 1)
+	})
+}
+
+// deletePostVote removes a post vote and its contribution to the hourly rollup
+func (s *VoteService) deletePostVote(vote *models.Vote) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(vote).Error; err != nil {
+			return err
+		}
+		if vote.Shadowed {
+			return nil
+		}
+		return adjustPostVoteRollup(tx, *vote.PostID, vote.VoteType, vote.CreatedAt, -1)
+	})
+}
+
+// adjustPostVoteRollup adds delta to the rollup bucket that a vote cast at castAt falls into
+func adjustPostVoteRollup(tx *gorm.DB, postID uuid.UUID, voteType string, castAt time.Time, delta int64) error {
+	rollup := models.PostVoteRollup{
+		PostID:      postID,
+		BucketStart: castAt.UTC().Truncate(time.Hour),
+	}
+	if voteType == models.VoteTypeUpvote {
+		rollup.Upvotes = delta
+	} else {
+		rollup.Downvotes = delta
+	}
+
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "post_id"}, {Name: "bucket_start"}},
+		DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "upvotes"}, Value: gorm.Expr("post_vote_rollups.upvotes + excluded.upvotes")},
+			{Column: clause.Column{Name: "downvotes"}, Value: gorm.Expr("post_vote_rollups.downvotes + excluded.downvotes")},
+		},
+	}).Create(&rollup).Error
+}
+
+// VoteBucket is one interval of a post's vote history
+type VoteBucket struct {
+	BucketStart time.Time `json:"bucket_start"`
+	Upvotes     int64     `json:"upvotes"`
+	Downvotes   int64     `json:"downvotes"`
+}
+
+// GetPostVoteHistory returns a post's upvotes and downvotes bucketed by hour or day
+// over the last `days` days, oldest first. Empty buckets are included.
+func (s *VoteService) GetPostVoteHistory(postID uuid.UUID, bucket string, days int) ([]VoteBucket, error) {
+	var step time.Duration
+	switch bucket {
+	case models.VoteBucketHour:
+		step = time.Hour
+	case models.VoteBucketDay:
+		step = 24 * time.Hour
+	default:
+		return nil, fmt.Errorf("invalid bucket")
+	}
+
+	var post models.Post
+	if err := db.DB.Select("id, created_at").First(&post, postID).Error; err != nil {
+		return nil, fmt.Errorf("post not found")
+	}
+
+	now := time.Now().UTC()
+	from := now.Add(-time.Duration(days) * 24 * time.Hour)
+	if created := post.CreatedAt.UTC(); created.After(from) {
+		from = created
+	}
+	from = from.Truncate(step)
+
+	var rollups []models.PostVoteRollup
+	if err := db.DB.Where("post_id = ? AND bucket_start >= ?", postID, from).
+		Order("bucket_start ASC").
+		Find(&rollups).Error; err != nil {
+		return nil, err
+	}
+
+	// Day buckets are aggregated from the hourly rollup
+	totals := make(map[time.Time]*VoteBucket)
+	for _, r := range rollups {
+		start := r.BucketStart.UTC().Truncate(step)
+		if totals[start] == nil {
+			totals[start] = &VoteBucket{BucketStart: start}
+		}
+		totals[start].Upvotes += r.Upvotes
+		totals[start].Downvotes += r.Downvotes
+	}
+
+	var history []VoteBucket
+	for start := from; !start.After(now); start = start.Add(step) {
+		if b, ok := totals[start]; ok {
+			history = append(history, *b)
+		} else {
+			history = append(history, VoteBucket{BucketStart: start})
+		}
+	}
+
+	return history, nil
+}
+
 // GetPostVotes returns the vote counts and user's current vote for a post
 func (s *VoteService) GetPostVotes(postID uuid.UUID, clientIP string) (int64, int64, string, error) {
 	var upvotes, downvotes int64
+	ipHash := s.hashIP(clientIP)
 	
-	// Count upvotes
-	db.DB.Model(&models.Vote{}).Where("post_id = ? AND vote_type = ?", postID, models.VoteTypeUpvote).Count(&upvotes)
+	// Count upvotes; shadowed votes only count for their own voter
+	db.DB.Model(&models.Vote{}).Where("post_id = ? AND vote_type = ?", postID, models.VoteTypeUpvote).
+		Where("shadowed = ? OR ip_hash = ?", false, ipHash).Count(&upvotes)
 	
 	// Count downvotes
-	db.DB.Model(&models.Vote{}).Where("post_id = ? AND vote_type = ?", postID, models.VoteTypeDownvote).Count(&downvotes)
+	db.DB.Model(&models.Vote{}).Where("post_id = ? AND vote_type = ?", postID, models.VoteTypeDownvote).
+		Where("shadowed = ? OR ip_hash = ?", false, ipHash).Count(&downvotes)
 	
 	// Get user's current vote
-	ipHash := s.hashIP(clientIP)
 	var userVote models.Vote
 	userVoteType := ""
 	if err := db.DB.Where("post_id = ? AND ip_hash = ?", postID, ipHash).First(&userVote).Error; err == nil {
@@ -175,15 +466,17 @@ func (s *VoteService) GetPostVotes(postID uuid.UUID, clientIP string) (int64, in
 // GetCommentVotes returns the vote counts and user's current vote for a comment
 func (s *VoteService) GetCommentVotes(commentID uuid.UUID, clientIP string) (int64, int64, string, error) {
 	var upvotes, downvotes int64
+	ipHash := s.hashIP(clientIP)
 	
-	// Count upvotes
-	db.DB.Model(&models.Vote{}).Where("comment_id = ? AND vote_type = ?", commentID, models.VoteTypeUpvote).Count(&upvotes)
+	// Count upvotes; shadowed votes only count for their own voter
+	db.DB.Model(&models.Vote{}).Where("comment_id = ? AND vote_type = ?", commentID, models.VoteTypeUpvote).
+		Where("shadowed = ? OR ip_hash = ?", false, ipHash).Count(&upvotes)
 	
 	// Count downvotes
-	db.DB.Model(&models.Vote{}).Where("comment_id = ? AND vote_type = ?", commentID, models.VoteTypeDownvote).Count(&downvotes)
+	db.DB.Model(&models.Vote{}).Where("comment_id = ? AND vote_type = ?", commentID, models.VoteTypeDownvote).
+		Where("shadowed = ? OR ip_hash = ?", false, ipHash).Count(&downvotes)
 	
 	// Get user's current vote
-	ipHash := s.hashIP(clientIP)
 	var userVote models.Vote
 	userVoteType := ""
 	if err := db.DB.Where("comment_id = ? AND ip_hash = ?", commentID, ipHash).First(&userVote).Error; err == nil {
@@ -193,35 +486,29 @@ func (s *VoteService) GetCommentVotes(commentID uuid.UUID, clientIP string) (int
 	return upvotes, downvotes, userVoteType, nil
 }
 
-func (s *VoteService) hashIP(ip string) string {
-	saltKey := os.Getenv("SALT_KEY")
-	if saltKey == "" {
-		saltKey = "default_salt_change_in_production"
+// DisplayPostVotes applies the vote display policy to a post's true counts
+func (s *VoteService) DisplayPostVotes(postID uuid.UUID, upvotes, downvotes int64) (int64, int64, bool) {
+	var post models.Post
+	if err := db.DB.Select("id, created_at").First(&post, postID).Error; err != nil {
+		return upvotes, downvotes, false
 	}
-	
-	// Parse IP to handle IPv6 properly
-	parsedIP := net.ParseIP(ip)
-	var ipBytes []byte
-	
-	if parsedIP != nil {
-		ipBytes = parsedIP.To16() // Convert to IPv6 format (works for IPv4 too)
-	} else {
-		ipBytes = []byte(ip) // Fallback for unparseable IPs
+	return s.displayPolicy.Apply(post.ID, post.CreatedAt, upvotes, downvotes)
+}
+
+// DisplayCommentVotes applies the vote display policy to a comment's true counts
+func (s *VoteService) DisplayCommentVotes(commentID uuid.UUID, upvotes, downvotes int64) (int64, int64, bool) {
+	var comment models.Comment
+	if err := db.DB.Select("id, created_at").First(&comment, commentID).Error; err != nil {
+		return upvotes, downvotes, false
 	}
-	
-	data := append(ipBytes, []byte(saltKey)...)
-	hash := sha256.Sum256(data)
-	return fmt.Sprintf("%x", hash)
+	return s.displayPolicy.Apply(comment.ID, comment.CreatedAt, upvotes, downvotes)
 }
 
-func (s *VoteService) isSpamming(ipHash string) bool {
-	// Simple spam check: max 30 votes per IP in last 2 minutes
-	var count int64
-	twoMinutesAgo := time.Now().Add(-2 * time.Minute)
-	
-	db.DB.Model(&models.Vote{}).
-		Where("ip_hash = ? AND created_at > ?", ipHash, twoMinutesAgo).
-		Count(&count)
-	
-	return count >= 30
+func (s *VoteService) hashIP(ip string) string {
+	return hashClientIP(ip)
+}
+
+func (s *VoteService) isSpamming(clientIP string) bool {
+	// Simple spam check: max 30 votes per client in 2 minutes, counted in the database
+	return checkRate(s.limits, "vote:"+RateLimitKey(clientIP), Rate{Requests: 30, Window: 2 * time.Minute})
 } 
\ No newline at end of file
@@ -0,0 +1,173 @@
+package admincli_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"reveal/internal/admincli"
+	"reveal/internal/models"
+	"reveal/internal/services"
+	"reveal/tests/testutil"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type AdminCLITestSuite struct {
+	suite.Suite
+	db *gorm.DB
+}
+
+func (suite *AdminCLITestSuite) SetupSuite() {
+	database, err := testutil.OpenDB()
+	suite.Require().NoError(err)
+	suite.db = database
+
+	os.Setenv("REVEAL_MODERATOR", "oncall")
+}
+
+func (suite *AdminCLITestSuite) TearDownSuite() {
+	os.Unsetenv("REVEAL_MODERATOR")
+}
+
+func (suite *AdminCLITestSuite) SetupTest() {
+	testutil.Reset(suite.db)
+	suite.db.Exec("DELETE FROM flags")
+	suite.db.Exec("DELETE FROM posts")
+	suite.db.Exec("DELETE FROM bans")
+	suite.db.Exec("DELETE FROM audit_entries")
+}
+
+// run executes a revealadmin command and returns its standard output
+func (suite *AdminCLITestSuite) run(args ...string) (string, error) {
+	var out, errOut bytes.Buffer
+	err := admincli.New(&out, &errOut).Run(args)
+	return out.String(), err
+}
+
+func (suite *AdminCLITestSuite) createFlaggedPost() *models.Post {
+	post := &models.Post{ID: uuid.New(), Title: "Flagged title", Content: "Content", IPHash: "author-hash",
+		Status: models.StatusVisible, Flagged: true, CreatedAt: time.Now()}
+	suite.Require().NoError(suite.db.Create(post).Error)
+	suite.Require().NoError(suite.db.Create(models.NewPostFlag(post.ID, "flagger-hash", "spam", "buy now")).Error)
+	return post
+}
+
+func (suite *AdminCLITestSuite) TestQueueAndShow() {
+	post := suite.createFlaggedPost()
+
+	out, err := suite.run("queue")
+	suite.NoError(err)
+	suite.Contains(out, post.ID.String())
+	suite.Contains(out, "Flagged title")
+
+	out, err = suite.run("queue", "-json")
+	suite.NoError(err)
+	var items []services.QueueItem
+	suite.NoError(json.Unmarshal([]byte(out), &items))
+	suite.Len(items, 1)
+
+	out, err = suite.run("show", "post", post.ID.String())
+	suite.NoError(err)
+	suite.Contains(out, "author-hash")
+	suite.Contains(out, "spam")
+	suite.Contains(out, "buy now")
+}
+
+func (suite *AdminCLITestSuite) TestApproveAndRemove_AreAudited() {
+	post := suite.createFlaggedPost()
+
+	_, err := suite.run("approve", "post", post.ID.String(), "-reason", "satire")
+	suite.NoError(err)
+
+	var updated models.Post
+	suite.db.First(&updated, post.ID)
+	suite.False(updated.Flagged)
+
+	_, err = suite.run("remove", "-as", "alice", "post", post.ID.String())
+	suite.NoError(err)
+	suite.db.First(&updated, post.ID)
+	suite.Equal(models.StatusRemoved, updated.Status)
+
+	entries, err := services.NewAuditService().List(services.AuditFilter{TargetID: post.ID.String()})
+	suite.NoError(err)
+	suite.Require().Len(entries, 2)
+	suite.Equal("alice", entries[0].Actor)
+	suite.Equal("oncall", entries[1].Actor)
+	suite.Equal("satire", entries[1].Reason)
+
+	_, err = suite.run("approve", "post", uuid.New().String())
+	suite.EqualError(err, "post not found")
+}
+
+func (suite *AdminCLITestSuite) TestBanListAndUnban() {
+	post := suite.createFlaggedPost()
+
+	out, err := suite.run("ban", "-mode", "shadow", "-hours", "24", "post", post.ID.String())
+	suite.NoError(err)
+	suite.Contains(out, "shadow author-hash")
+
+	_, err = suite.run("ban", "hash", "other-hash")
+	suite.NoError(err)
+
+	out, err = suite.run("bans", "-json")
+	suite.NoError(err)
+	var bans []models.Ban
+	suite.NoError(json.Unmarshal([]byte(out), &bans))
+	suite.Require().Len(bans, 2)
+
+	_, err = suite.run("unban", bans[0].ID.String())
+	suite.NoError(err)
+	active, _ := services.NewBanService().ListBans(false)
+	suite.Len(active, 1)
+}
+
+func (suite *AdminCLITestSuite) TestExport() {
+	post := suite.createFlaggedPost()
+	_, err := suite.run("remove", "post", post.ID.String())
+	suite.Require().NoError(err)
+
+	out, err := suite.run("export", "audit")
+	suite.NoError(err)
+	records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	suite.NoError(err)
+	suite.Require().Len(records, 2)
+	suite.Equal("actor", records[0][2])
+	suite.Equal("oncall", records[1][2])
+
+	path := filepath.Join(suite.T().TempDir(), "bans.json")
+	_, err = suite.run("export", "-format", "json", "-o", path, "bans")
+	suite.NoError(err)
+	data, err := os.ReadFile(path)
+	suite.NoError(err)
+	suite.Equal("[]\n", string(data))
+
+	_, err = suite.run("export", "-since", "yesterday", "audit")
+	suite.Equal(admincli.ErrUsage, err)
+}
+
+func (suite *AdminCLITestSuite) TestUsageErrors() {
+	_, err := suite.run("frobnicate")
+	suite.Equal(admincli.ErrUsage, err)
+
+	_, err = suite.run("show", "video", uuid.New().String())
+	suite.Equal(admincli.ErrUsage, err)
+
+	_, err = suite.run("ban", "hash")
+	suite.Equal(admincli.ErrUsage, err)
+
+	out, err := suite.run()
+	suite.NoError(err)
+	suite.Contains(out, "Usage: revealadmin")
+}
+
+func TestAdminCLITestSuite(t *testing.T) {
+	suite.Run(t, new(AdminCLITestSuite))
+}
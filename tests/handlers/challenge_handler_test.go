@@ -0,0 +1,117 @@
+package handlers_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"math/bits"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+
+	"reveal/internal/handlers"
+	"reveal/internal/middleware"
+	"reveal/tests/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type ChallengeHandlerTestSuite struct {
+	suite.Suite
+	router *gin.Engine
+	db     *gorm.DB
+}
+
+func (suite *ChallengeHandlerTestSuite) SetupSuite() {
+	gin.SetMode(gin.TestMode)
+
+	database, err := testutil.OpenDB()
+	suite.Require().NoError(err)
+	suite.db = database
+
+	os.Setenv("POW_ENABLED", "true")
+	os.Setenv("POW_DIFFICULTY", "8")
+	os.Setenv("TRUST_ENABLED", "false")
+
+	postHandler := handlers.NewPostHandler()
+	suite.router = gin.New()
+	api := suite.router.Group("/api")
+	{
+		api.GET("/challenge", handlers.NewChallengeHandler().GetChallenge)
+		api.POST("/posts", middleware.RequireChallenge(), postHandler.CreatePost)
+	}
+}
+
+func (suite *ChallengeHandlerTestSuite) TearDownSuite() {
+	os.Unsetenv("POW_ENABLED")
+	os.Unsetenv("POW_DIFFICULTY")
+	os.Unsetenv("TRUST_ENABLED")
+}
+
+func (suite *ChallengeHandlerTestSuite) SetupTest() {
+	testutil.Reset(suite.db)
+	suite.db.Exec("DELETE FROM posts")
+}
+
+func (suite *ChallengeHandlerTestSuite) createPost(solution string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]string{"title": "A secret", "content": "Something I never told anyone"})
+	req, _ := http.NewRequest("POST", "/api/posts", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = "10.13.0.1:12345"
+	if solution != "" {
+		req.Header.Set(middleware.ChallengeSolutionHeader, solution)
+	}
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *ChallengeHandlerTestSuite) TestWriteRequiresSolvedChallenge() {
+	w := suite.createPost("")
+	suite.Equal(http.StatusForbidden, w.Code)
+	var response map[string]interface{}
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	suite.Equal("challenge_required", response["code"])
+
+	req, _ := http.NewRequest("GET", "/api/challenge", nil)
+	req.RemoteAddr = "10.13.0.1:12345"
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	suite.Equal("no-store", w.Header().Get("Cache-Control"))
+
+	var challenge struct {
+		Required   bool   `json:"required"`
+		Token      string `json:"token"`
+		Difficulty int    `json:"difficulty"`
+	}
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &challenge))
+	suite.True(challenge.Required)
+	suite.Equal(8, challenge.Difficulty)
+
+	solution := ""
+	for counter := 0; solution == ""; counter++ {
+		candidate := challenge.Token + ":" + strconv.Itoa(counter)
+		sum := sha256.Sum256([]byte(candidate))
+		if sum[0] == 0 || bits.LeadingZeros8(sum[0]) >= challenge.Difficulty {
+			solution = candidate
+		}
+	}
+
+	w = suite.createPost(solution)
+	suite.Equal(http.StatusCreated, w.Code)
+
+	// A solution is good for one write only
+	w = suite.createPost(solution)
+	suite.Equal(http.StatusForbidden, w.Code)
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	suite.Equal("challenge_expired", response["code"])
+}
+
+func TestChallengeHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(ChallengeHandlerTestSuite))
+}
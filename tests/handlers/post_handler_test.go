@@ -6,20 +6,19 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
-	"os"
+	"strings"
 	"testing"
 	"time"
 
-	"reveal/internal/db"
 	"reveal/internal/handlers"
 	"reveal/internal/middleware"
 	"reveal/internal/models"
+	"reveal/tests/testutil"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
@@ -35,20 +34,10 @@ func (suite *PostHandlerTestSuite) SetupSuite() {
 	gin.SetMode(gin.TestMode)
 	
 	// Use in-memory SQLite for testing
-	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	database, err := testutil.OpenDB()
 	suite.Require().NoError(err)
-	
-	// Set global DB for the handlers to use
-	db.DB = database
 	suite.db = database
 	
-	// Auto-migrate the schema
-	err = database.AutoMigrate(&models.Post{}, &models.Flag{})
-	suite.Require().NoError(err)
-	
-	// Set test environment variable
-	os.Setenv("SALT_KEY", "test_salt_key")
-	
 	suite.handler = handlers.NewPostHandler()
 	
 	// Setup router with middleware
@@ -65,20 +54,17 @@ func (suite *PostHandlerTestSuite) SetupSuite() {
 		api.POST("/posts", middleware.RateLimit(), suite.handler.CreatePost)
 		api.GET("/posts", suite.handler.GetPosts)
 		api.POST("/posts/:id/flag", middleware.RateLimit(), suite.handler.FlagPost)
+		api.POST("/posts/:id/comments", handlers.NewCommentHandler().CreateComment)
 	}
 }
 
-func (suite *PostHandlerTestSuite) TearDownSuite() {
-	os.Unsetenv("SALT_KEY")
-}
-
 func (suite *PostHandlerTestSuite) SetupTest() {
 	// Clean the database before each test
 	suite.db.Exec("DELETE FROM flags")
 	suite.db.Exec("DELETE FROM posts")
 	
-	// Reset rate limiters to avoid interference between tests
-	middleware.ResetLimiters()
+	// Reset shared state to avoid interference between tests
+	testutil.Reset(suite.db)
 }
 
 func (suite *PostHandlerTestSuite) TestHealthCheck() {
@@ -113,6 +99,25 @@ func (suite *PostHandlerTestSuite) TestGetFlagReasons() {
 	assert.Contains(suite.T(), reasons, "spam")
 	assert.Contains(suite.T(), reasons, "inappropriate")
 	assert.Contains(suite.T(), reasons, "other")
+	assert.Contains(suite.T(), reasons, "violence")
+
+	taxonomy, ok := response["taxonomy"].([]interface{})
+	assert.True(suite.T(), ok)
+	assert.Len(suite.T(), taxonomy, len(reasons))
+}
+
+func (suite *PostHandlerTestSuite) TestGetFlagReasons_Localized() {
+	req, _ := http.NewRequest("GET", "/api/flag-reasons", nil)
+	req.Header.Set("Accept-Language", "de-DE,de;q=0.9,en;q=0.8")
+	w := httptest.NewRecorder()
+
+	suite.router.ServeHTTP(w, req)
+
+	var response struct {
+		Reasons map[string]string `json:"reasons"`
+	}
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(suite.T(), "Gewalt oder Drohungen", response.Reasons["violence"])
 }
 
 func (suite *PostHandlerTestSuite) TestCreatePost_Success() {
@@ -195,6 +200,73 @@ func (suite *PostHandlerTestSuite) TestCreatePost_ContentTooShort() {
 	assert.Contains(suite.T(), response["error"], "at least 10 characters")
 }
 
+func (suite *PostHandlerTestSuite) TestCreatePost_LengthCountsGraphemes() {
+	// 255 accented letters and emoji are 255 characters, though far more bytes
+	postData := handlers.CreatePostRequest{
+		Title:   strings.Repeat("é", 200) + strings.Repeat("👩‍💻", 55),
+		Content: "Ünïcödé 🇩🇪 cöntént",
+	}
+
+	jsonData, _ := json.Marshal(postData)
+	req, _ := http.NewRequest("POST", "/api/posts", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+
+	postData.Title += "!"
+	jsonData, _ = json.Marshal(postData)
+	req, _ = http.NewRequest("POST", "/api/posts", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "at most 255 characters")
+}
+
+func (suite *PostHandlerTestSuite) TestCreatePost_BodyTooLarge() {
+	postData := handlers.CreatePostRequest{
+		Title:   "Title",
+		Content: strings.Repeat("a", 200000),
+	}
+
+	jsonData, _ := json.Marshal(postData)
+	req, _ := http.NewRequest("POST", "/api/posts", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func (suite *PostHandlerTestSuite) TestCreatePost_InvisibleOnly() {
+	send := func(path string, body interface{}) *httptest.ResponseRecorder {
+		jsonData, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", path, bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		suite.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Zero-width and bidi characters pass binding but normalize to nothing
+	w := send("/api/posts", handlers.CreatePostRequest{Title: "\u200b\u200b", Content: "Something I never told anyone"})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "Title cannot be empty")
+
+	w = send("/api/posts", handlers.CreatePostRequest{Title: "Title", Content: "\u202e\u200d\u2066"})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "Content cannot be empty")
+
+	post := &models.Post{ID: uuid.New(), Title: "Title", Content: "Some content", IPHash: "author", Status: models.StatusVisible}
+	suite.Require().NoError(suite.db.Create(post).Error)
+	w = send("/api/posts/"+post.ID.String()+"/comments", handlers.CreateCommentRequest{Content: "\u200b"})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "Comment cannot be empty")
+}
+
 func (suite *PostHandlerTestSuite) TestGetPosts_Empty() {
 	req, _ := http.NewRequest("GET", "/api/posts", nil)
 	w := httptest.NewRecorder()
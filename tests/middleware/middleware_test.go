@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"testing"
 
 	"reveal/internal/middleware"
@@ -184,4 +185,47 @@ func TestLogger(t *testing.T) {
 	})
 	
 	assert.Equal(t, http.StatusOK, w.Code)
-} 
\ No newline at end of file
+}
+
+func TestRequireModerator(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	os.Setenv("MODERATOR_TOKENS", "alice:s3cret,bob:hunter2")
+	defer os.Unsetenv("MODERATOR_TOKENS")
+
+	router := gin.New()
+	router.GET("/admin", middleware.RequireModerator(), func(c *gin.Context) {
+		c.JSON(200, gin.H{"moderator": c.GetString("moderator")})
+	})
+
+	t.Run("should reject requests without credentials", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/admin", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("should reject unknown tokens", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/admin", nil)
+		req.Header.Set("X-Moderator-Token", "wrong")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("should accept bearer tokens and name the moderator", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/admin", nil)
+		req.Header.Set("Authorization", "Bearer hunter2")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "bob", response["moderator"])
+	})
+}
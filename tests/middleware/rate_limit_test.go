@@ -0,0 +1,124 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"reveal/internal/middleware"
+	"reveal/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimit_GroupsIPv6ByPrefix(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	middleware.ResetLimiters()
+
+	router := gin.New()
+	router.Use(middleware.RateLimit())
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(200, gin.H{"message": "ok"})
+	})
+
+	// Rotating addresses within one /64 shares a single bucket
+	limited := false
+	for i := 0; i < 10; i++ {
+		req, _ := http.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = fmt.Sprintf("[2001:db8:1:2::%x]:12345", i+1)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code == http.StatusTooManyRequests {
+			limited = true
+		}
+	}
+	assert.True(t, limited)
+	assert.Equal(t, 1, middleware.LimiterCount())
+
+	var metrics map[string]float64
+	require.NoError(t, json.Unmarshal([]byte(expvar.Get("rate_limiter").String()), &metrics))
+	assert.Equal(t, float64(1), metrics["clients"])
+}
+
+func TestRateLimitPolicies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rate-limits.conf")
+	require.NoError(t, os.WriteFile(path, []byte("# Busy site\nread=600/1m\nvote=100/1m\nbogus\n"), 0o600))
+	os.Setenv("RATE_LIMIT_CONFIG", path)
+	os.Setenv("RATE_LIMIT_POLICIES", "vote=40/30s, search=10/1m, post=-1/1m, read=2000000/1ms")
+	os.Setenv("RATE_LIMIT_REQUESTS", "10")
+	defer os.Unsetenv("RATE_LIMIT_CONFIG")
+	defer os.Unsetenv("RATE_LIMIT_POLICIES")
+	defer os.Unsetenv("RATE_LIMIT_REQUESTS")
+
+	policies := middleware.RateLimitPolicies()
+	assert.Equal(t, services.Rate{Requests: 10, Window: time.Minute}, policies[middleware.PolicyDefault])
+	// read=2000000/1ms would space requests less than a nanosecond apart
+	assert.Equal(t, services.Rate{Requests: 600, Window: time.Minute}, policies[middleware.PolicyRead])
+	assert.Equal(t, services.Rate{Requests: 40, Window: 30 * time.Second}, policies[middleware.PolicyVote])
+	assert.Equal(t, services.Rate{Requests: 10, Window: time.Minute}, policies["search"])
+	// Invalid entries leave the built-in policy in place
+	assert.Equal(t, services.Rate{Requests: 3, Window: time.Minute}, policies[middleware.PolicyPost])
+}
+
+func TestRateLimitPolicy_Headers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	middleware.ResetLimiters()
+	os.Setenv("RATE_LIMIT_POLICIES", "vote=2/1m,read=0/1m")
+	defer os.Unsetenv("RATE_LIMIT_POLICIES")
+
+	router := gin.New()
+	router.POST("/vote", middleware.RateLimitPolicy(middleware.PolicyVote), func(c *gin.Context) {
+		c.JSON(200, gin.H{"message": "ok"})
+	})
+	router.POST("/post", middleware.RateLimitPolicy(middleware.PolicyPost), func(c *gin.Context) {
+		c.JSON(200, gin.H{"message": "ok"})
+	})
+	router.GET("/read", middleware.RateLimitPolicy(middleware.PolicyRead), func(c *gin.Context) {
+		c.JSON(200, gin.H{"message": "ok"})
+	})
+
+	send := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.RemoteAddr = "198.51.100.4:12345"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("POST", "/vote")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", w.Header().Get("RateLimit-Reset"))
+	assert.Empty(t, w.Header().Get("Retry-After"))
+
+	w = send("POST", "/vote")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", w.Header().Get("RateLimit-Reset"))
+
+	w = send("POST", "/vote")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+
+	// Other policies keep their own allowance
+	w = send("POST", "/post")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "3", w.Header().Get("RateLimit-Limit"))
+
+	// A policy with no requests is switched off
+	for i := 0; i < 5; i++ {
+		w = send("GET", "/read")
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+}
@@ -0,0 +1,127 @@
+package services_test
+
+import (
+	"fmt"
+	"testing"
+
+	"reveal/internal/models"
+	"reveal/internal/services"
+	"reveal/tests/testutil"
+
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type AppealServiceTestSuite struct {
+	suite.Suite
+	service      *services.AppealService
+	postService  *services.PostService
+	queueService *services.ModerationService
+	db           *gorm.DB
+}
+
+func (suite *AppealServiceTestSuite) SetupSuite() {
+	database, err := testutil.OpenDB()
+	suite.Require().NoError(err)
+	suite.db = database
+
+	suite.service = services.NewAppealService()
+	suite.postService = services.NewPostService()
+	suite.queueService = services.NewModerationService()
+}
+
+func (suite *AppealServiceTestSuite) SetupTest() {
+	testutil.Reset(suite.db)
+	for _, table := range []string{"appeals", "audit_entries", "flags", "posts", "identities"} {
+		suite.db.Exec("DELETE FROM " + table)
+	}
+}
+
+// hiddenPost creates a post and flags it until it is globally hidden
+func (suite *AppealServiceTestSuite) hiddenPost() *models.Post {
+	post, err := suite.postService.CreatePost("Title", "Some content here", "10.5.0.1")
+	suite.Require().NoError(err)
+	suite.Require().NotEmpty(post.ManageToken)
+
+	for i := 0; i < 5; i++ {
+		suite.Require().NoError(suite.postService.FlagPost(post.ID, fmt.Sprintf("10.5.1.%d", i+1), "spam", ""))
+	}
+	return post
+}
+
+func (suite *AppealServiceTestSuite) TestFileAppeal_RequiresTokenAndHiddenContent() {
+	post := suite.hiddenPost()
+
+	_, err := suite.service.FileAppeal(models.FlagTypePost, post.ID, "wrong", "Please restore it")
+	suite.EqualError(err, "invalid management token")
+
+	visible, err := suite.postService.CreatePost("Title", "Some content here", "10.5.0.2")
+	suite.Require().NoError(err)
+	_, err = suite.service.FileAppeal(models.FlagTypePost, visible.ID, visible.ManageToken, "Please restore it")
+	suite.EqualError(err, "content not hidden")
+
+	appeal, err := suite.service.FileAppeal(models.FlagTypePost, post.ID, post.ManageToken, "It was not spam")
+	suite.NoError(err)
+	suite.Equal(models.AppealPending, appeal.Status)
+
+	_, err = suite.service.FileAppeal(models.FlagTypePost, post.ID, post.ManageToken, "Again")
+	suite.EqualError(err, "appeal already filed")
+
+	// Appeals show up with the content in the moderator queue
+	items, err := suite.queueService.GetQueue(models.FlagTypePost, 10)
+	suite.NoError(err)
+	suite.Require().Len(items, 1)
+	suite.Require().NotNil(items[0].Appeal)
+	suite.Equal(appeal.ID, items[0].Appeal.ID)
+}
+
+func (suite *AppealServiceTestSuite) TestGrantAppeal_RestoresAndImmunizes() {
+	post := suite.hiddenPost()
+	appeal, err := suite.service.FileAppeal(models.FlagTypePost, post.ID, post.ManageToken, "It was not spam")
+	suite.Require().NoError(err)
+
+	suite.NoError(suite.service.GrantAppeal(appeal.ID, "alice", "Restored"))
+	suite.EqualError(suite.service.GrantAppeal(appeal.ID, "alice", ""), "appeal already decided")
+
+	status, err := suite.service.GetAppeal(models.FlagTypePost, post.ID, post.ManageToken)
+	suite.NoError(err)
+	suite.Equal(models.AppealGranted, status.Status)
+	suite.Equal("Restored", status.Decision)
+
+	var restored models.Post
+	suite.db.First(&restored, post.ID)
+	suite.False(restored.Flagged)
+
+	// The dismissed flags no longer count; one new flag does not re-hide the post
+	suite.NoError(suite.postService.FlagPost(post.ID, "10.5.2.1", "spam", ""))
+	suite.db.First(&restored, post.ID)
+	suite.False(restored.Flagged)
+
+	entries, err := services.NewAuditService().List(services.AuditFilter{Action: models.AuditAppealGrant})
+	suite.NoError(err)
+	suite.Len(entries, 1)
+}
+
+func (suite *AppealServiceTestSuite) TestDenyAppeal_KeepsContentHidden() {
+	post := suite.hiddenPost()
+	appeal, err := suite.service.FileAppeal(models.FlagTypePost, post.ID, post.ManageToken, "It was not spam")
+	suite.Require().NoError(err)
+
+	suite.NoError(suite.service.DenyAppeal(appeal.ID, "bob", "It was spam"))
+
+	var hidden models.Post
+	suite.db.First(&hidden, post.ID)
+	suite.True(hidden.Flagged)
+
+	pending, err := suite.service.ListAppeals(models.AppealPending, 10)
+	suite.NoError(err)
+	suite.Len(pending, 0)
+
+	status, err := suite.service.GetAppeal(models.FlagTypePost, post.ID, post.ManageToken)
+	suite.NoError(err)
+	suite.Equal(models.AppealDenied, status.Status)
+}
+
+func TestAppealServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(AppealServiceTestSuite))
+}
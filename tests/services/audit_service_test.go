@@ -0,0 +1,112 @@
+package services_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"reveal/internal/models"
+	"reveal/internal/services"
+	"reveal/tests/testutil"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type AuditServiceTestSuite struct {
+	suite.Suite
+	service           *services.AuditService
+	moderationService *services.ModerationService
+	postService       *services.PostService
+	db                *gorm.DB
+}
+
+func (suite *AuditServiceTestSuite) SetupSuite() {
+	database, err := testutil.OpenDB()
+	suite.Require().NoError(err)
+	suite.db = database
+
+	suite.service = services.NewAuditService()
+	suite.moderationService = services.NewModerationService()
+	suite.postService = services.NewPostService()
+}
+
+func (suite *AuditServiceTestSuite) SetupTest() {
+	testutil.Reset(suite.db)
+	suite.db.Exec("DELETE FROM audit_entries")
+	suite.db.Exec("DELETE FROM flags")
+	suite.db.Exec("DELETE FROM posts")
+}
+
+func (suite *AuditServiceTestSuite) createFlaggedPost() *models.Post {
+	post := &models.Post{ID: uuid.New(), Title: "Title", Content: "Content", IPHash: "author", Status: models.StatusVisible, CreatedAt: time.Now()}
+	suite.Require().NoError(suite.db.Create(post).Error)
+	for i := 0; i < 5; i++ {
+		suite.Require().NoError(suite.postService.FlagPost(post.ID, fmt.Sprintf("10.2.0.%d", i+1), "spam", ""))
+	}
+	return post
+}
+
+func (suite *AuditServiceTestSuite) TestRecordsAutomaticAndModeratorActions() {
+	post := suite.createFlaggedPost()
+	suite.NoError(suite.moderationService.RemovePost(post.ID, "alice", "spam wave"))
+
+	entries, err := suite.service.List(services.AuditFilter{TargetID: post.ID.String()})
+	suite.NoError(err)
+	suite.Require().Len(entries, 2)
+
+	// Newest first
+	suite.Equal(models.AuditRemove, entries[0].Action)
+	suite.Equal("alice", entries[0].Actor)
+	suite.Equal("spam wave", entries[0].Reason)
+	suite.Equal(models.AuditAutoFlag, entries[1].Action)
+	suite.Equal(models.AuditActorSystem, entries[1].Actor)
+	suite.Equal(entries[1].Hash, entries[0].PrevHash)
+}
+
+func (suite *AuditServiceTestSuite) TestListFilters() {
+	suite.createFlaggedPost()
+	post := suite.createFlaggedPost()
+	suite.NoError(suite.moderationService.ApprovePost(post.ID, "bob", ""))
+
+	entries, err := suite.service.List(services.AuditFilter{Actor: "bob"})
+	suite.NoError(err)
+	suite.Len(entries, 1)
+
+	entries, err = suite.service.List(services.AuditFilter{Action: models.AuditAutoFlag})
+	suite.NoError(err)
+	suite.Len(entries, 2)
+
+	entries, err = suite.service.List(services.AuditFilter{Limit: 1})
+	suite.NoError(err)
+	suite.Require().Len(entries, 1)
+
+	older, err := suite.service.List(services.AuditFilter{BeforeID: entries[0].ID})
+	suite.NoError(err)
+	suite.Len(older, 2)
+}
+
+func (suite *AuditServiceTestSuite) TestVerifyDetectsTampering() {
+	post := suite.createFlaggedPost()
+	suite.NoError(suite.moderationService.RemovePost(post.ID, "alice", "original reason"))
+
+	result, err := suite.service.Verify()
+	suite.NoError(err)
+	suite.True(result.Valid)
+	suite.Equal(int64(2), result.Entries)
+
+	// Rewrite history behind the service's back
+	var entry models.AuditEntry
+	suite.db.Where("action = ?", models.AuditRemove).First(&entry)
+	suite.db.Model(&entry).Update("reason", "edited reason")
+
+	result, err = suite.service.Verify()
+	suite.NoError(err)
+	suite.False(result.Valid)
+	suite.Equal(entry.ID, result.BrokenAt)
+}
+
+func TestAuditServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(AuditServiceTestSuite))
+}
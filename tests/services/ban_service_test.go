@@ -0,0 +1,152 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"reveal/internal/models"
+	"reveal/internal/services"
+	"reveal/tests/testutil"
+
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type BanServiceTestSuite struct {
+	suite.Suite
+	service        *services.BanService
+	postService    *services.PostService
+	commentService *services.CommentService
+	voteService    *services.VoteService
+	db             *gorm.DB
+}
+
+const (
+	abuserIP = "10.3.0.1"
+	readerIP = "10.3.0.2"
+)
+
+func (suite *BanServiceTestSuite) SetupSuite() {
+	database, err := testutil.OpenDB()
+	suite.Require().NoError(err)
+	suite.db = database
+
+	suite.service = services.NewBanService()
+	suite.postService = services.NewPostService()
+	suite.commentService = services.NewCommentService()
+	suite.voteService = services.NewVoteService()
+}
+
+func (suite *BanServiceTestSuite) SetupTest() {
+	testutil.Reset(suite.db)
+	for _, table := range []string{"bans", "audit_entries", "post_vote_rollups", "votes", "comments", "posts", "identities"} {
+		suite.db.Exec("DELETE FROM " + table)
+	}
+}
+
+// banAuthor creates a post from abuserIP and bans its author
+func (suite *BanServiceTestSuite) banAuthor(mode string, duration time.Duration) (*models.Post, *models.Ban) {
+	post, err := suite.postService.CreatePost("Title", "Content before the ban", abuserIP)
+	suite.Require().NoError(err)
+
+	ban, err := suite.service.CreateBan(services.BanTarget{PostID: &post.ID}, mode, "abuse", duration, "alice")
+	suite.Require().NoError(err)
+	suite.Equal(post.IPHash, ban.IPHash)
+	return post, ban
+}
+
+func (suite *BanServiceTestSuite) TestBan_RefusesActivity() {
+	post, _ := suite.banAuthor(models.BanModeBan, 0)
+
+	_, err := suite.postService.CreatePost("Title", "Some content", abuserIP)
+	suite.EqualError(err, "identity banned")
+	_, err = suite.commentService.CreateComment(post.ID, "A comment", abuserIP)
+	suite.EqualError(err, "identity banned")
+	suite.EqualError(suite.voteService.VoteOnPost(post.ID, models.VoteTypeUpvote, abuserIP), "identity banned")
+	suite.EqualError(suite.voteService.SetPostVote(post.ID, models.VoteTypeUpvote, abuserIP), "identity banned")
+
+	// Other identities are unaffected
+	_, err = suite.postService.CreatePost("Title", "Some content", readerIP)
+	suite.NoError(err)
+}
+
+func (suite *BanServiceTestSuite) TestShadowban_VisibleOnlyToAuthor() {
+	post, _ := suite.banAuthor(models.BanModeShadow, 0)
+
+	shadowed, err := suite.postService.CreatePost("Shadowed", "Only I can see this", abuserIP)
+	suite.NoError(err)
+	_, err = suite.commentService.CreateComment(post.ID, "Only I can see this", abuserIP)
+	suite.NoError(err)
+	suite.NoError(suite.voteService.VoteOnPost(post.ID, models.VoteTypeUpvote, abuserIP))
+
+	ownPosts, err := suite.postService.GetRecentPosts(abuserIP, 10)
+	suite.NoError(err)
+	suite.Len(ownPosts, 2)
+
+	otherPosts, err := suite.postService.GetRecentPosts(readerIP, 10)
+	suite.NoError(err)
+	suite.Require().Len(otherPosts, 1)
+	suite.NotEqual(shadowed.ID, otherPosts[0].ID)
+	suite.Equal(int64(0), otherPosts[0].Upvotes)
+
+	ownComments, _ := suite.commentService.GetCommentsByPostID(post.ID, abuserIP)
+	suite.Len(ownComments, 1)
+	otherComments, _ := suite.commentService.GetCommentsByPostID(post.ID, readerIP)
+	suite.Len(otherComments, 0)
+
+	up, _, userVote, _ := suite.voteService.GetPostVotes(post.ID, abuserIP)
+	suite.Equal(int64(1), up)
+	suite.Equal(models.VoteTypeUpvote, userVote)
+	up, _, _, _ = suite.voteService.GetPostVotes(post.ID, readerIP)
+	suite.Equal(int64(0), up)
+
+	// Shadowed votes stay out of the vote history
+	history, err := suite.voteService.GetPostVoteHistory(post.ID, models.VoteBucketHour, 1)
+	suite.NoError(err)
+	var total int64
+	for _, bucket := range history {
+		total += bucket.Upvotes
+	}
+	suite.Equal(int64(0), total)
+}
+
+func (suite *BanServiceTestSuite) TestExpiredAndRevokedBansLapse() {
+	_, ban := suite.banAuthor(models.BanModeBan, time.Hour)
+
+	_, err := suite.postService.CreatePost("Title", "Some content", abuserIP)
+	suite.EqualError(err, "identity banned")
+
+	suite.NoError(suite.service.RevokeBan(ban.ID, "bob", "appeal accepted"))
+	suite.EqualError(suite.service.RevokeBan(ban.ID, "bob", ""), "ban not found")
+
+	_, err = suite.postService.CreatePost("Title", "Some content", abuserIP)
+	suite.NoError(err)
+
+	// Expire a second ban by moving its expiry into the past
+	_, expired := suite.banAuthor(models.BanModeBan, time.Hour)
+	suite.db.Model(expired).Update("expires_at", time.Now().Add(-time.Minute))
+
+	active, err := suite.service.ListBans(false)
+	suite.NoError(err)
+	suite.Len(active, 0)
+	all, err := suite.service.ListBans(true)
+	suite.NoError(err)
+	suite.Len(all, 2)
+
+	entries, err := services.NewAuditService().List(services.AuditFilter{TargetID: ban.IPHash})
+	suite.NoError(err)
+	suite.Len(entries, 3) // ban, unban, ban
+}
+
+func (suite *BanServiceTestSuite) TestCreateBan_Validation() {
+	_, err := suite.service.CreateBan(services.BanTarget{IPHash: "abc"}, "forever", "", 0, "alice")
+	suite.EqualError(err, "invalid ban mode")
+	_, err = suite.service.CreateBan(services.BanTarget{}, models.BanModeBan, "", 0, "alice")
+	suite.EqualError(err, "invalid ban target")
+	_, err = suite.service.CreateBan(services.BanTarget{IPHash: "abc"}, models.BanModeBan, "", -time.Hour, "alice")
+	suite.EqualError(err, "invalid ban duration")
+}
+
+func TestBanServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(BanServiceTestSuite))
+}
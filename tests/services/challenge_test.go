@@ -0,0 +1,160 @@
+package services_test
+
+import (
+	"crypto/sha256"
+	"math/bits"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"reveal/internal/models"
+	"reveal/internal/services"
+	"reveal/tests/testutil"
+
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+// solveChallenge finds a "token:counter" solution with at least difficulty
+// leading zero bits, or one with fewer if short is set
+func solveChallenge(token string, difficulty int, short bool) string {
+	for counter := 0; ; counter++ {
+		solution := token + ":" + strconv.Itoa(counter)
+		sum := sha256.Sum256([]byte(solution))
+		zeros := 0
+		for _, b := range sum {
+			zeros += bits.LeadingZeros8(b)
+			if b != 0 {
+				break
+			}
+		}
+		if (zeros >= difficulty) != short {
+			return solution
+		}
+	}
+}
+
+type ChallengeTestSuite struct {
+	suite.Suite
+	db *gorm.DB
+}
+
+func (suite *ChallengeTestSuite) SetupSuite() {
+	database, err := testutil.OpenDB()
+	suite.Require().NoError(err)
+	suite.db = database
+
+	os.Setenv("POW_ENABLED", "true")
+	os.Setenv("POW_DIFFICULTY", "8")
+}
+
+func (suite *ChallengeTestSuite) TearDownSuite() {
+	os.Unsetenv("POW_ENABLED")
+	os.Unsetenv("POW_DIFFICULTY")
+}
+
+func (suite *ChallengeTestSuite) SetupTest() {
+	testutil.Reset(suite.db)
+	suite.db.Exec("DELETE FROM posts")
+	suite.db.Exec("DELETE FROM identities")
+	suite.db.Exec("DELETE FROM used_challenges")
+	os.Setenv("TRUST_ENABLED", "false")
+}
+
+func (suite *ChallengeTestSuite) TearDownTest() {
+	os.Unsetenv("TRUST_ENABLED")
+}
+
+func (suite *ChallengeTestSuite) TestSolveAndReplay() {
+	challenges := services.NewChallengeService()
+	suite.True(challenges.Enabled())
+
+	challenge, err := challenges.Issue("10.12.0.1")
+	suite.Require().NoError(err)
+	suite.Equal(8, challenge.Difficulty)
+	suite.Equal(services.ChallengeAlgorithm, challenge.Algorithm)
+
+	solution := solveChallenge(challenge.Token, challenge.Difficulty, false)
+	suite.NoError(challenges.Verify("10.12.0.1", solution))
+	suite.EqualError(challenges.Verify("10.12.0.1", solution), "challenge already used")
+
+	// Other instances and a cleared rate limit store still know it was used
+	services.ResetRateLimits()
+	suite.EqualError(services.NewChallengeService().Verify("10.12.0.1", solution), "challenge already used")
+}
+
+func (suite *ChallengeTestSuite) TestBoundToIPv6Prefix() {
+	challenges := services.NewChallengeService()
+	challenge, err := challenges.Issue("2001:db8:12::1")
+	suite.Require().NoError(err)
+	solution := solveChallenge(challenge.Token, challenge.Difficulty, false)
+
+	suite.EqualError(challenges.Verify("2001:db8:13::1", solution), "invalid challenge")
+	suite.NoError(challenges.Verify("2001:db8:12::ffff", solution))
+}
+
+func (suite *ChallengeTestSuite) TestRejectsBadSolutions() {
+	challenges := services.NewChallengeService()
+	challenge, err := challenges.Issue("10.12.0.2")
+	suite.Require().NoError(err)
+
+	suite.EqualError(challenges.Verify("10.12.0.2", ""), "challenge required")
+	suite.EqualError(challenges.Verify("10.12.0.2", "garbage"), "invalid challenge")
+
+	// Too little work does not use up the challenge
+	suite.EqualError(challenges.Verify("10.12.0.2", solveChallenge(challenge.Token, challenge.Difficulty, true)), "insufficient work")
+
+	// Challenges are bound to the client that requested them
+	solution := solveChallenge(challenge.Token, challenge.Difficulty, false)
+	suite.EqualError(challenges.Verify("10.12.0.3", solution), "invalid challenge")
+
+	// The difficulty cannot be lowered
+	parts := strings.Split(challenge.Token, ".")
+	parts[1] = "0"
+	suite.EqualError(challenges.Verify("10.12.0.2", strings.Join(parts, ".")+":1"), "invalid challenge")
+
+	suite.NoError(challenges.Verify("10.12.0.2", solution))
+}
+
+func (suite *ChallengeTestSuite) TestRefusedWhenReplayCacheFails() {
+	challenges := services.NewChallengeService()
+	challenge, err := challenges.Issue("10.12.0.6")
+	suite.Require().NoError(err)
+
+	suite.Require().NoError(suite.db.Migrator().DropTable(&models.UsedChallenge{}))
+	defer suite.db.AutoMigrate(&models.UsedChallenge{})
+	suite.EqualError(challenges.Verify("10.12.0.6", solveChallenge(challenge.Token, challenge.Difficulty, false)), "challenge check failed")
+}
+
+func (suite *ChallengeTestSuite) TestExpired() {
+	os.Setenv("POW_TTL_MINUTES", "-1")
+	defer os.Unsetenv("POW_TTL_MINUTES")
+	challenges := services.NewChallengeService()
+
+	challenge, err := challenges.Issue("10.12.0.4")
+	suite.Require().NoError(err)
+	suite.EqualError(challenges.Verify("10.12.0.4", solveChallenge(challenge.Token, challenge.Difficulty, false)), "challenge expired")
+}
+
+func (suite *ChallengeTestSuite) TestDifficultyScalesWithLoadAndTrust() {
+	os.Setenv("POW_LOAD_STEP", "2")
+	defer os.Unsetenv("POW_LOAD_STEP")
+	for i := 0; i < 4; i++ {
+		suite.Require().NoError(suite.db.Create(&models.Post{Title: "T", Content: "Busy, busy day", IPHash: "someone", Status: models.StatusVisible}).Error)
+	}
+
+	challenge, err := services.NewChallengeService().Issue("10.12.0.5")
+	suite.Require().NoError(err)
+	suite.Equal(10, challenge.Difficulty)
+
+	// A brand-new identity has a low trust score and gets a harder puzzle
+	os.Setenv("TRUST_ENABLED", "true")
+	challenge, err = services.NewChallengeService().Issue("10.12.0.5")
+	suite.Require().NoError(err)
+	suite.Greater(challenge.Difficulty, 10)
+}
+
+func TestChallengeTestSuite(t *testing.T) {
+	suite.Run(t, new(ChallengeTestSuite))
+}
@@ -0,0 +1,157 @@
+package services_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"reveal/internal/models"
+	"reveal/internal/services"
+	"reveal/tests/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+func TestLexiconClassifier(t *testing.T) {
+	classifier := services.NewLexiconClassifier()
+
+	result, err := classifier.Classify("A confession", "I secretly love pineapple on pizza")
+	require.NoError(t, err)
+	assert.Zero(t, result.Score)
+	assert.Empty(t, result.Labels)
+
+	result, err = classifier.Classify("", "You are a stupid idiot")
+	require.NoError(t, err)
+	assert.Equal(t, []string{services.LabelInsult}, result.Labels)
+	assert.InDelta(t, 0.79, result.Score, 0.01)
+	assert.Empty(t, result.HoldReason())
+	assert.Equal(t, []string{"Insults"}, result.Warnings())
+
+	// Homoglyphs do not hide terms
+	result, err = classifier.Classify("", "I know where you live and I will kill you, \u0455tupid")
+	require.NoError(t, err)
+	assert.Equal(t, []string{services.LabelThreat, services.LabelInsult}, result.Labels)
+	assert.Greater(t, result.Score, 0.95)
+	assert.Contains(t, result.HoldReason(), "threat, insult")
+}
+
+func TestLexiconClassifier_LoneThreatPhraseIsNotHeld(t *testing.T) {
+	classifier := services.NewLexiconClassifier()
+
+	for _, text := range []string{
+		"I will kill it at my interview",
+		"This heat will kill you",
+	} {
+		result, err := classifier.Classify("", text)
+		require.NoError(t, err)
+		assert.Equal(t, []string{services.LabelThreat}, result.Labels, text)
+		assert.Empty(t, result.HoldReason(), text)
+	}
+}
+
+func TestLexiconClassifier_CustomLexicon(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lexicon.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# Local additions\nscam,0.9,wire me money\ninsult,0.5,nitwit\ninsult,0.5,дурак\n"), 0o600))
+	os.Setenv("CLASSIFIER_LEXICON", path)
+	defer os.Unsetenv("CLASSIFIER_LEXICON")
+	classifier := services.NewLexiconClassifier()
+
+	result, err := classifier.Classify("", "Please wire me money, nitwit")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"scam", services.LabelInsult}, result.Labels)
+	assert.Equal(t, []string{"scam", "Insults"}, result.Warnings())
+
+	// Terms outside the Latin script match as words too
+	result, err = classifier.Classify("", "Ты дурак")
+	require.NoError(t, err)
+	assert.Equal(t, []string{services.LabelInsult}, result.Labels)
+}
+
+func TestHTTPClassifier(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		assert.Equal(t, "Hello there friend", req["content"])
+		json.NewEncoder(w).Encode(map[string]interface{}{"score": 1.7, "labels": []string{"spam_bot"}})
+	}))
+	defer server.Close()
+
+	classifier := services.NewHTTPClassifier(server.URL, time.Second, nil)
+	result, err := classifier.Classify("", "Hello there friend")
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, result.Score)
+	assert.Equal(t, []string{"spam bot"}, result.Warnings())
+
+	// An unreachable server falls back to the lexicon
+	server.Close()
+	classifier = services.NewHTTPClassifier(server.URL, time.Second, services.NewLexiconClassifier())
+	result, err = classifier.Classify("", "you moron")
+	require.NoError(t, err)
+	assert.Equal(t, []string{services.LabelInsult}, result.Labels)
+
+	_, err = services.NewHTTPClassifier(server.URL, time.Second, nil).Classify("", "you moron")
+	assert.Error(t, err)
+}
+
+type ClassifierTestSuite struct {
+	suite.Suite
+	postService *services.PostService
+	db          *gorm.DB
+}
+
+func (suite *ClassifierTestSuite) SetupSuite() {
+	database, err := testutil.OpenDB()
+	suite.Require().NoError(err)
+	suite.db = database
+
+	suite.postService = services.NewPostService()
+}
+
+func (suite *ClassifierTestSuite) SetupTest() {
+	testutil.Reset(suite.db)
+	suite.db.Exec("DELETE FROM posts")
+	suite.db.Exec("DELETE FROM audit_entries")
+}
+
+func (suite *ClassifierTestSuite) TestCreatePost_HoldsAndWarns() {
+	insult, err := suite.postService.CreatePost("Dear coworker", "You are a stupid idiot and everyone knows it", "10.9.0.1")
+	suite.Require().NoError(err)
+	suite.Equal(models.StatusVisible, insult.Status)
+	suite.Equal("Insults", insult.ContentWarning)
+
+	threat, err := suite.postService.CreatePost("Dear neighbour", "I know where you live and I will kill you", "10.9.0.2")
+	suite.Require().NoError(err)
+	suite.Equal(models.StatusPending, threat.Status)
+
+	item, err := services.NewModerationService().GetPost(threat.ID)
+	suite.Require().NoError(err)
+	suite.Greater(item.ToxicityScore, 0.9)
+	suite.Equal([]string{services.LabelThreat}, item.ToxicityLabels)
+
+	entries, err := services.NewAuditService().List(services.AuditFilter{Action: models.AuditAutoHold})
+	suite.NoError(err)
+	suite.Require().Len(entries, 1)
+	suite.Contains(entries[0].Reason, "classifier score")
+}
+
+func (suite *ClassifierTestSuite) TestCreatePost_ClassifierOff() {
+	os.Setenv("CLASSIFIER", services.ClassifierOff)
+	defer os.Unsetenv("CLASSIFIER")
+	postService := services.NewPostService()
+
+	post, err := postService.CreatePost("Dear neighbour", "I know where you live and I will kill you", "10.9.0.3")
+	suite.Require().NoError(err)
+	suite.Equal(models.StatusVisible, post.Status)
+	suite.Zero(post.ToxicityScore)
+}
+
+func TestClassifierTestSuite(t *testing.T) {
+	suite.Run(t, new(ClassifierTestSuite))
+}
@@ -0,0 +1,161 @@
+package services_test
+
+import (
+	"testing"
+
+	"reveal/internal/models"
+	"reveal/internal/services"
+	"reveal/tests/testutil"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type ContentFilterTestSuite struct {
+	suite.Suite
+	filter         *services.ContentFilter
+	postService    *services.PostService
+	commentService *services.CommentService
+	db             *gorm.DB
+}
+
+func (suite *ContentFilterTestSuite) SetupSuite() {
+	database, err := testutil.OpenDB()
+	suite.Require().NoError(err)
+	suite.db = database
+
+	suite.filter = services.NewContentFilter()
+	suite.postService = services.NewPostService()
+	suite.commentService = services.NewCommentService()
+}
+
+func (suite *ContentFilterTestSuite) SetupTest() {
+	testutil.Reset(suite.db)
+	suite.db.Exec("DELETE FROM audit_entries")
+	suite.db.Exec("DELETE FROM comments")
+	suite.db.Exec("DELETE FROM posts")
+}
+
+// TearDownTest deletes rules through the service so the shared rule cache is invalidated
+func (suite *ContentFilterTestSuite) TearDownTest() {
+	rules, _ := suite.filter.ListRules()
+	for _, rule := range rules {
+		suite.NoError(suite.filter.DeleteRule(rule.ID, "tester"))
+	}
+}
+
+func (suite *ContentFilterTestSuite) addRule(matchType, pattern, field, action string) *models.FilterRule {
+	rule := &models.FilterRule{
+		Name:      action + " rule",
+		MatchType: matchType,
+		Pattern:   pattern,
+		Field:     field,
+		Action:    action,
+		Enabled:   true,
+	}
+	suite.Require().NoError(suite.filter.CreateRule(rule, "alice"))
+	return rule
+}
+
+func (suite *ContentFilterTestSuite) TestReject() {
+	suite.addRule(models.FilterMatchKeyword, "buy now, free money", models.FilterFieldBoth, models.FilterActionReject)
+
+	_, err := suite.postService.CreatePost("Free Money inside", "Some content here", "10.4.0.1")
+	suite.EqualError(err, "content rejected")
+
+	// Keywords match whole words only
+	_, err = suite.postService.CreatePost("Title", "I freely admit this", "10.4.0.1")
+	suite.NoError(err)
+}
+
+func (suite *ContentFilterTestSuite) TestKeywordsMatchWholeWordsInAnyScript() {
+	rule := &models.FilterRule{Name: "words", MatchType: models.FilterMatchKeyword, Pattern: "нож, 刀, سكين, ёлка", Action: models.FilterActionMask}
+
+	result, err := suite.filter.DryRun(rule, "", "У него был нож. 刀 سكين ёлка ёлка")
+	suite.NoError(err)
+	suite.Equal("У него был ***. * **** **** ****", result.Content)
+
+	// Longer words that merely contain a keyword are left alone
+	result, err = suite.filter.DryRun(rule, "", "ножницы и ёлками")
+	suite.NoError(err)
+	suite.Empty(result.Matches)
+}
+
+func (suite *ContentFilterTestSuite) TestMaskAndWarn() {
+	suite.addRule(models.FilterMatchRegex, `\bdarn\w*`, models.FilterFieldContent, models.FilterActionMask)
+	warn := suite.addRule(models.FilterMatchKeyword, "grief", models.FilterFieldBoth, models.FilterActionWarn)
+	warn.Warning = "Loss"
+	suite.Require().NoError(suite.filter.UpdateRule(warn.ID, warn, "bob"))
+
+	post, err := suite.postService.CreatePost("darn grief", "It was darned hard", "10.4.0.2")
+	suite.NoError(err)
+	suite.Equal("darn grief", post.Title) // Mask rule covers content only
+	suite.Equal("It was ****** hard", post.Content)
+	suite.Equal("Loss", post.ContentWarning)
+}
+
+func (suite *ContentFilterTestSuite) TestHoldAndFlag() {
+	suite.addRule(models.FilterMatchKeyword, "address", models.FilterFieldContent, models.FilterActionHold)
+	suite.addRule(models.FilterMatchKeyword, "scam", models.FilterFieldContent, models.FilterActionFlag)
+
+	held, err := suite.postService.CreatePost("Title", "Here is my address", "10.4.0.3")
+	suite.NoError(err)
+	suite.Equal(models.StatusPending, held.Status)
+
+	posts, _ := suite.postService.GetRecentPosts("10.4.0.9", 10)
+	suite.Len(posts, 0)
+
+	visible, err := suite.postService.CreatePost("Title", "Some content", "10.4.0.3")
+	suite.NoError(err)
+	comment, err := suite.commentService.CreateComment(visible.ID, "This is a scam", "10.4.0.4")
+	suite.NoError(err)
+	suite.True(comment.Flagged)
+
+	entries, err := services.NewAuditService().List(services.AuditFilter{Actor: models.AuditActorSystem})
+	suite.NoError(err)
+	suite.Len(entries, 2)
+}
+
+func (suite *ContentFilterTestSuite) TestRuleChangesApplyImmediately() {
+	rule := suite.addRule(models.FilterMatchKeyword, "forbidden", models.FilterFieldBoth, models.FilterActionReject)
+	_, err := suite.postService.CreatePost("forbidden", "Some content", "10.4.0.5")
+	suite.Error(err)
+
+	rule.Enabled = false
+	suite.Require().NoError(suite.filter.UpdateRule(rule.ID, rule, "alice"))
+	_, err = suite.postService.CreatePost("forbidden", "Some content", "10.4.0.5")
+	suite.NoError(err)
+}
+
+func (suite *ContentFilterTestSuite) TestDryRun() {
+	suite.addRule(models.FilterMatchKeyword, "secret", models.FilterFieldBoth, models.FilterActionWarn)
+
+	result, err := suite.filter.DryRun(nil, "A secret", "Nothing else")
+	suite.NoError(err)
+	suite.Require().Len(result.Matches, 1)
+	suite.Equal(models.FilterFieldTitle, result.Matches[0].Field)
+
+	candidate := &models.FilterRule{Name: "digits", MatchType: models.FilterMatchRegex, Pattern: `\d{3}`, Action: models.FilterActionMask}
+	result, err = suite.filter.DryRun(candidate, "", "call 555 now")
+	suite.NoError(err)
+	suite.Equal("call *** now", result.Content)
+
+	_, err = suite.filter.DryRun(&models.FilterRule{Name: "bad", MatchType: models.FilterMatchRegex, Pattern: "(", Action: models.FilterActionMask}, "", "x")
+	suite.EqualError(err, "invalid pattern")
+
+	// Dry runs store nothing
+	var count int64
+	suite.db.Model(&models.Post{}).Count(&count)
+	suite.Equal(int64(0), count)
+}
+
+func (suite *ContentFilterTestSuite) TestUpdateAndDeleteMissingRule() {
+	rule := &models.FilterRule{Name: "x", MatchType: models.FilterMatchKeyword, Pattern: "x", Action: models.FilterActionWarn}
+	suite.EqualError(suite.filter.UpdateRule(uuid.New(), rule, "alice"), "filter rule not found")
+	suite.EqualError(suite.filter.DeleteRule(uuid.New(), "alice"), "filter rule not found")
+}
+
+func TestContentFilterTestSuite(t *testing.T) {
+	suite.Run(t, new(ContentFilterTestSuite))
+}
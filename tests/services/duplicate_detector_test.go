@@ -0,0 +1,136 @@
+package services_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"reveal/internal/models"
+	"reveal/internal/services"
+	"reveal/tests/testutil"
+
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+const (
+	secretText   = "I never told anyone that I was the one who broke the office coffee machine last winter and let the new intern take the blame for it because I was scared of losing my job"
+	editedSecret = "I never told anybody that I was the one who broke the office coffee maker last winter and let the new intern take the blame for it because I was scared of losing my job"
+	repostSecret = "i never told ANYONE that i was the one who broke the office coffee machine last winter, and let the new intern take the blame for it because i was afraid of losing my job!!!"
+	otherSecret  = "My sister and I still argue about who ate the last slice of birthday cake at grandma's ninetieth party, and honestly it was me the whole time"
+)
+
+type DuplicateDetectorTestSuite struct {
+	suite.Suite
+	postService *services.PostService
+	detector    *services.DuplicateDetector
+	db          *gorm.DB
+}
+
+func (suite *DuplicateDetectorTestSuite) SetupSuite() {
+	database, err := testutil.OpenDB()
+	suite.Require().NoError(err)
+	suite.db = database
+
+	suite.postService = services.NewPostService()
+	suite.detector = services.NewDuplicateDetector()
+}
+
+func (suite *DuplicateDetectorTestSuite) SetupTest() {
+	testutil.Reset(suite.db)
+	suite.db.Exec("DELETE FROM posts")
+	suite.db.Exec("DELETE FROM audit_entries")
+}
+
+func (suite *DuplicateDetectorTestSuite) TearDownTest() {
+	os.Unsetenv("DUPLICATE_ACTION")
+}
+
+func (suite *DuplicateDetectorTestSuite) TestFingerprint() {
+	original := suite.detector.Fingerprint("Confession", secretText)
+	suite.NotZero(original)
+
+	// Too short to fingerprint reliably
+	suite.Zero(suite.detector.Fingerprint("Title", "Content"))
+
+	match, err := suite.detector.FindMatch(original)
+	suite.NoError(err)
+	suite.Nil(match)
+}
+
+func (suite *DuplicateDetectorTestSuite) TestFindMatch_SpreadOutDifferences() {
+	const fingerprint = int64(0x0123456789ABCDEF)
+	near := &models.Post{Title: "T", Content: "C", IPHash: "a", Fingerprint: fingerprint ^ 0x0001_0002_0300_0C00}
+	far := &models.Post{Title: "T", Content: "C", IPHash: "b", Fingerprint: fingerprint ^ 0x0003_0030_0300_3000}
+	suite.Require().NoError(suite.db.Create(near).Error)
+	suite.Require().NoError(suite.db.Create(far).Error)
+	suite.Equal(models.FingerprintBands(near.Fingerprint), [4]int32{near.FingerprintBand0, near.FingerprintBand1, near.FingerprintBand2, near.FingerprintBand3})
+
+	// Six differing bits, no band identical: found through the one-bit neighbours
+	match, err := suite.detector.FindMatch(fingerprint)
+	suite.NoError(err)
+	suite.Require().NotNil(match)
+	suite.Equal(near.ID, match.PostID)
+	suite.Equal(6, match.Distance)
+
+	suite.db.Delete(near)
+	match, err = suite.detector.FindMatch(fingerprint)
+	suite.NoError(err)
+	suite.Nil(match)
+}
+
+func (suite *DuplicateDetectorTestSuite) TestCreatePost_HoldsNearDuplicatesInOneCluster() {
+	first, err := suite.postService.CreatePost("Confession", secretText, "10.7.0.1")
+	suite.Require().NoError(err)
+	suite.Equal(models.StatusVisible, first.Status)
+
+	second, err := suite.postService.CreatePost("Confession", editedSecret, "10.7.0.2")
+	suite.Require().NoError(err)
+	suite.Equal(models.StatusPending, second.Status)
+	suite.Require().NotNil(second.DuplicateOf)
+	suite.Equal(first.ID, *second.DuplicateOf)
+
+	third, err := suite.postService.CreatePost("confession", repostSecret, "10.7.0.3")
+	suite.Require().NoError(err)
+	suite.Require().NotNil(third.DuplicateOf)
+	suite.Equal(first.ID, *third.DuplicateOf)
+
+	unrelated, err := suite.postService.CreatePost("Confession", otherSecret, "10.7.0.4")
+	suite.Require().NoError(err)
+	suite.Equal(models.StatusVisible, unrelated.Status)
+	suite.Nil(unrelated.DuplicateOf)
+
+	entries, err := services.NewAuditService().List(services.AuditFilter{Action: models.AuditAutoHold})
+	suite.NoError(err)
+	suite.Len(entries, 2)
+
+	clusters, err := suite.detector.Clusters(time.Now().Add(-time.Hour), 10)
+	suite.NoError(err)
+	suite.Require().Len(clusters, 1)
+	suite.Equal(first.ID, clusters[0].Root.ID)
+	suite.Len(clusters[0].Duplicates, 2)
+}
+
+func (suite *DuplicateDetectorTestSuite) TestCreatePost_RejectMode() {
+	os.Setenv("DUPLICATE_ACTION", services.DuplicateActionReject)
+
+	_, err := suite.postService.CreatePost("Confession", secretText, "10.7.1.1")
+	suite.Require().NoError(err)
+
+	_, err = suite.postService.CreatePost("Confession", editedSecret, "10.7.1.2")
+	suite.EqualError(err, "duplicate post")
+}
+
+func (suite *DuplicateDetectorTestSuite) TestCreatePost_IgnoresPostsOutsideWindow() {
+	first, err := suite.postService.CreatePost("Confession", secretText, "10.7.2.1")
+	suite.Require().NoError(err)
+	suite.db.Model(&models.Post{}).Where("id = ?", first.ID).Update("created_at", time.Now().Add(-48*time.Hour))
+
+	second, err := suite.postService.CreatePost("Confession", editedSecret, "10.7.2.2")
+	suite.Require().NoError(err)
+	suite.Equal(models.StatusVisible, second.Status)
+}
+
+func TestDuplicateDetectorTestSuite(t *testing.T) {
+	suite.Run(t, new(DuplicateDetectorTestSuite))
+}
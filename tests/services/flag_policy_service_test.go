@@ -0,0 +1,178 @@
+package services_test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"reveal/internal/models"
+	"reveal/internal/services"
+	"reveal/tests/testutil"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type FlagPolicyServiceTestSuite struct {
+	suite.Suite
+	policy      *services.FlagPolicyService
+	postService *services.PostService
+	db          *gorm.DB
+}
+
+func (suite *FlagPolicyServiceTestSuite) SetupSuite() {
+	database, err := testutil.OpenDB()
+	suite.Require().NoError(err)
+	suite.db = database
+
+	suite.policy = services.NewFlagPolicyService()
+	suite.postService = services.NewPostService()
+}
+
+func (suite *FlagPolicyServiceTestSuite) SetupTest() {
+	testutil.Reset(suite.db)
+	suite.db.Exec("DELETE FROM flag_thresholds")
+	suite.db.Exec("DELETE FROM flags")
+	suite.db.Exec("DELETE FROM posts")
+	suite.db.Exec("DELETE FROM identities")
+}
+
+func flagsWithReasons(reasons ...string) []models.Flag {
+	flags := make([]models.Flag, len(reasons))
+	for i, reason := range reasons {
+		flags[i] = models.Flag{Reason: reason, CreatedAt: time.Now()}
+	}
+	return flags
+}
+
+func (suite *FlagPolicyServiceTestSuite) TestEvaluate_DefaultsMatchLegacyThresholds() {
+	suite.False(suite.policy.Evaluate(models.FlagTypePost, time.Now(), flagsWithReasons("spam", "spam", "spam", "spam")).Hide)
+	suite.True(suite.policy.Evaluate(models.FlagTypePost, time.Now(), flagsWithReasons("spam", "spam", "spam", "spam", "spam")).Hide)
+	suite.True(suite.policy.Evaluate(models.FlagTypeComment, time.Now(), flagsWithReasons("spam", "spam", "spam")).Hide)
+}
+
+func (suite *FlagPolicyServiceTestSuite) TestEvaluate_ReasonThresholdAndWeights() {
+	os.Setenv("FLAG_POST_REASON_THRESHOLDS", "violence:1")
+	defer os.Unsetenv("FLAG_POST_REASON_THRESHOLDS")
+
+	reasons := services.NewFlagReasonService()
+	harassment := &models.FlagReason{Key: "harassment", Labels: map[string]string{"en": "Harassment"}, Severity: 3, Weight: 2.5, Enabled: true}
+	_, err := reasons.SetReason(harassment, "alice")
+	suite.Require().NoError(err)
+	defer func() {
+		harassment.Weight = 1
+		reasons.SetReason(harassment, "alice")
+	}()
+
+	outcome := suite.policy.Evaluate(models.FlagTypePost, time.Now(), flagsWithReasons("violence"))
+	suite.True(outcome.Hide)
+	suite.Equal("violence", outcome.Trigger)
+
+	outcome = suite.policy.Evaluate(models.FlagTypePost, time.Now(), flagsWithReasons("harassment", "harassment"))
+	suite.True(outcome.Hide)
+	suite.Equal(5.0, outcome.Weight)
+}
+
+func (suite *FlagPolicyServiceTestSuite) TestEvaluate_IgnoresDismissedFlags() {
+	flags := flagsWithReasons("spam", "spam", "spam", "spam", "spam")
+	for i := range flags {
+		flags[i].Resolution = models.FlagResolutionDismissed
+	}
+	flags = append(flags, models.Flag{Reason: "spam", CreatedAt: time.Now()})
+
+	outcome := suite.policy.Evaluate(models.FlagTypePost, time.Now(), flags)
+	suite.False(outcome.Hide)
+	suite.Equal(1.0, outcome.Weight)
+}
+
+func (suite *FlagPolicyServiceTestSuite) TestEvaluate_DiscountsInaccurateFlaggers() {
+	now := time.Now()
+	suite.Require().NoError(suite.db.Create(&models.Identity{IPHash: "abuser", FirstSeenAt: now, LastSeenAt: now, FlagsRejected: 8}).Error)
+	suite.Require().NoError(suite.db.Create(&models.Identity{IPHash: "sloppy", FirstSeenAt: now, LastSeenAt: now, FlagsUpheld: 1, FlagsRejected: 3}).Error)
+	suite.Require().NoError(suite.db.Create(&models.Identity{IPHash: "newcomer", FirstSeenAt: now, LastSeenAt: now, FlagsRejected: 2}).Error)
+
+	flags := flagsWithReasons("spam", "spam", "spam")
+	flags[0].IPHash = "abuser"
+	flags[1].IPHash = "sloppy"
+	flags[2].IPHash = "newcomer"
+
+	// Accuracy 1/10 is ignored, 2/6 counts two thirds, too few rulings counts fully
+	outcome := suite.policy.Evaluate(models.FlagTypePost, time.Now(), flags)
+	suite.Equal(1, outcome.Ignored)
+	suite.InDelta(1+2.0/3, outcome.Weight, 1e-9)
+
+	factors := suite.policy.FlagFactors(flags, nil)
+	suite.Equal(0.0, factors[0])
+	suite.Equal(1.0, factors[2])
+}
+
+func (suite *FlagPolicyServiceTestSuite) TestEvaluate_DecaysFlagsOnOldContent() {
+	os.Setenv("FLAG_DECAY_HOURS", "24")
+	os.Setenv("FLAG_DECAY_FADE_HOURS", "24")
+	defer os.Unsetenv("FLAG_DECAY_HOURS")
+	defer os.Unsetenv("FLAG_DECAY_FADE_HOURS")
+
+	// Fresh flags on old content fade with the content's age
+	flags := flagsWithReasons("spam", "spam", "spam")
+	outcome := suite.policy.Evaluate(models.FlagTypePost, time.Now().Add(-36*time.Hour), flags)
+	suite.InDelta(1.5, outcome.Weight, 0.01)
+	outcome = suite.policy.Evaluate(models.FlagTypePost, time.Now().Add(-72*time.Hour), flags)
+	suite.Equal(0.0, outcome.Weight)
+	suite.Equal(3, outcome.Ignored)
+
+	// Old flags on recent content still count
+	for i := range flags {
+		flags[i].CreatedAt = time.Now().Add(-72 * time.Hour)
+	}
+	suite.Equal(3.0, suite.policy.Evaluate(models.FlagTypePost, time.Now().Add(-12*time.Hour), flags).Weight)
+
+	os.Setenv("FLAG_DECAY_HOURS", "0")
+	suite.Equal(3.0, suite.policy.Evaluate(models.FlagTypePost, time.Now().Add(-72*time.Hour), flags).Weight)
+}
+
+func (suite *FlagPolicyServiceTestSuite) TestSetThreshold_OverridesDefaults() {
+	_, err := suite.policy.SetThreshold(models.FlagTypePost, "", 10, 2, "alice")
+	suite.NoError(err)
+	updated, err := suite.policy.SetThreshold(models.FlagTypePost, "", 8, 2, "bob")
+	suite.NoError(err)
+	suite.Equal(8.0, updated.HideAt)
+	suite.Equal("bob", updated.UpdatedBy)
+
+	outcome := suite.policy.Evaluate(models.FlagTypePost, time.Now(), flagsWithReasons("spam", "spam", "spam", "spam", "spam"))
+	suite.False(outcome.Hide)
+	suite.True(outcome.Hold)
+
+	suite.NoError(suite.policy.ResetThreshold(models.FlagTypePost, "", "alice"))
+	suite.Error(suite.policy.ResetThreshold(models.FlagTypePost, "", "alice"))
+
+	_, err = suite.policy.SetThreshold("video", "", 1, 0, "alice")
+	suite.Error(err)
+}
+
+func (suite *FlagPolicyServiceTestSuite) TestFlagPost_HoldsBeforeHiding() {
+	_, err := suite.policy.SetThreshold(models.FlagTypePost, "", 5, 2, "alice")
+	suite.Require().NoError(err)
+
+	post := &models.Post{ID: uuid.New(), Title: "T", Content: "C", IPHash: "author", Status: models.StatusVisible, CreatedAt: time.Now()}
+	suite.Require().NoError(suite.db.Create(post).Error)
+
+	for i := 0; i < 2; i++ {
+		suite.NoError(suite.postService.FlagPost(post.ID, fmt.Sprintf("10.1.0.%d", i+1), "spam", ""))
+	}
+
+	var updated models.Post
+	suite.db.First(&updated, post.ID)
+	suite.Equal(models.StatusHeld, updated.Status)
+	suite.False(updated.Flagged)
+
+	// Held posts remain publicly visible
+	posts, err := suite.postService.GetRecentPosts("10.1.9.9", 10)
+	suite.NoError(err)
+	suite.Len(posts, 1)
+}
+
+func TestFlagPolicyServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(FlagPolicyServiceTestSuite))
+}
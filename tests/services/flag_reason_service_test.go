@@ -0,0 +1,122 @@
+package services_test
+
+import (
+	"os"
+	"testing"
+
+	"reveal/internal/models"
+	"reveal/internal/services"
+	"reveal/tests/testutil"
+
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type FlagReasonServiceTestSuite struct {
+	suite.Suite
+	service *services.FlagReasonService
+	db      *gorm.DB
+}
+
+func (suite *FlagReasonServiceTestSuite) SetupSuite() {
+	database, err := testutil.OpenDB()
+	suite.Require().NoError(err)
+	suite.db = database
+
+	// Reload the taxonomy on every lookup so each test sees its own rows
+	os.Setenv("FILTER_RELOAD_SECONDS", "0")
+
+	suite.service = services.NewFlagReasonService()
+}
+
+func (suite *FlagReasonServiceTestSuite) TearDownSuite() {
+	os.Unsetenv("FILTER_RELOAD_SECONDS")
+}
+
+func (suite *FlagReasonServiceTestSuite) SetupTest() {
+	testutil.Reset(suite.db)
+	suite.db.Exec("DELETE FROM flag_reasons")
+}
+
+// TearDownTest leaves the shared taxonomy cache with the built-in reasons for other suites
+func (suite *FlagReasonServiceTestSuite) TearDownTest() {
+	suite.db.Exec("DELETE FROM flag_reasons")
+	suite.service.List(false)
+}
+
+func (suite *FlagReasonServiceTestSuite) TestList_SeedsBuiltInReasons() {
+	os.Setenv("FLAG_REASON_WEIGHTS", "violence:3")
+	defer os.Unsetenv("FLAG_REASON_WEIGHTS")
+
+	reasons := suite.service.List(false)
+	suite.Len(reasons, len(models.DefaultFlagReasons()))
+	suite.Equal(models.FlagReasonViolence, reasons[0].Key)
+	suite.Equal(3.0, reasons[0].Weight)
+
+	var stored int64
+	suite.db.Model(&models.FlagReason{}).Count(&stored)
+	suite.Equal(int64(len(reasons)), stored)
+}
+
+func (suite *FlagReasonServiceTestSuite) TestResolve() {
+	reason, err := suite.service.Resolve("", "")
+	suite.NoError(err)
+	suite.Equal(models.FlagReasonReported, reason)
+
+	reason, err = suite.service.Resolve("violence", "")
+	suite.NoError(err)
+	suite.Equal("violence", reason)
+
+	_, err = suite.service.Resolve("bogus", "")
+	suite.EqualError(err, "invalid flag reason")
+
+	_, err = suite.service.Resolve("other", "  ")
+	suite.EqualError(err, "details required")
+}
+
+func (suite *FlagReasonServiceTestSuite) TestSetReason() {
+	doxxing := &models.FlagReason{
+		Key:             "doxxing",
+		Labels:          map[string]string{"en": "Shares someone's identity", "DE": "Veröffentlicht persönliche Daten"},
+		Severity:        5,
+		RequiresDetails: true,
+		Weight:          4,
+		Enabled:         true,
+	}
+	_, err := suite.service.SetReason(doxxing, "alice")
+	suite.Require().NoError(err)
+
+	_, err = suite.service.Resolve("doxxing", "")
+	suite.EqualError(err, "details required")
+	_, err = suite.service.Resolve("doxxing", "names the author's employer")
+	suite.NoError(err)
+
+	reasons := suite.service.List(false)
+	suite.Equal("Veröffentlicht persönliche Daten", reasons[0].Label("de-AT"))
+	suite.Equal("Shares someone's identity", reasons[0].Label("ja"))
+
+	// Retiring a reason rejects new flags with it
+	doxxing.Enabled = false
+	_, err = suite.service.SetReason(doxxing, "bob")
+	suite.Require().NoError(err)
+	_, err = suite.service.Resolve("doxxing", "details")
+	suite.EqualError(err, "invalid flag reason")
+	suite.Len(suite.service.List(true), len(suite.service.List(false))+1)
+
+	entries, err := services.NewAuditService().List(services.AuditFilter{TargetID: "doxxing"})
+	suite.NoError(err)
+	suite.Require().Len(entries, 2)
+	suite.Equal(models.AuditFlagReasonUpdate, entries[0].Action)
+	suite.Equal(models.AuditFlagReasonCreate, entries[1].Action)
+
+	_, err = suite.service.SetReason(&models.FlagReason{Key: "Bad Key", Labels: map[string]string{"en": "x"}, Severity: 1}, "alice")
+	suite.EqualError(err, "invalid flag reason key")
+	_, err = suite.service.SetReason(&models.FlagReason{Key: "nolabel", Labels: map[string]string{"fr": "x"}, Severity: 1}, "alice")
+	suite.EqualError(err, "english label required")
+	_, err = suite.service.SetReason(&models.FlagReason{Key: "severe", Labels: map[string]string{"en": "x"}, Severity: 6}, "alice")
+	suite.EqualError(err, "invalid severity")
+}
+
+func TestFlagReasonServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(FlagReasonServiceTestSuite))
+}
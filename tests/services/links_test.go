@@ -0,0 +1,143 @@
+package services_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"reveal/internal/models"
+	"reveal/internal/services"
+	"reveal/tests/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+func TestLinkPolicy_ExtractsAndCleansLinks(t *testing.T) {
+	os.Unsetenv("LINK_POLICY")
+	policy := services.NewLinkPolicy()
+
+	title, content, links, err := policy.Apply("Read this",
+		"Café review at https://Blog.example.com/post?id=7&utm_source=x, more on www.example.org. Mail bob@example.com")
+	require.NoError(t, err)
+
+	assert.Equal(t, "Read this", title)
+	assert.Equal(t, "Café review at https://Blog.example.com/post?id=7, more on www.example.org. Mail bob@example.com", content)
+	require.Len(t, links, 2)
+
+	assert.Equal(t, "https://Blog.example.com/post?id=7", links[0].URL)
+	assert.Equal(t, "blog.example.com", links[0].Domain)
+	assert.Equal(t, "content", links[0].Field)
+	assert.Equal(t, 15, links[0].Start)
+	assert.Equal(t, 15+len(links[0].Text), links[0].End)
+
+	assert.Equal(t, "https://www.example.org", links[1].URL)
+	assert.Equal(t, "www.example.org", links[1].Text)
+	assert.Equal(t, "example.org", links[1].Domain)
+}
+
+func TestLinkPolicy_IgnoresPlainText(t *testing.T) {
+	os.Unsetenv("LINK_POLICY")
+	policy := services.NewLinkPolicy()
+
+	_, _, links, err := policy.Apply("", "I said no, e.g. never.Then I left at 5.30 pm")
+	require.NoError(t, err)
+	assert.Empty(t, links)
+}
+
+func TestLinkPolicy_Allowlist(t *testing.T) {
+	os.Setenv("LINK_POLICY", services.LinkPolicyAllowlist)
+	os.Setenv("LINK_ALLOWED_DOMAINS", "wikipedia.org, example.com")
+	defer os.Unsetenv("LINK_POLICY")
+	defer os.Unsetenv("LINK_ALLOWED_DOMAINS")
+	policy := services.NewLinkPolicy()
+
+	_, _, links, err := policy.Apply("", "See https://en.wikipedia.org/wiki/Secret and example.com")
+	require.NoError(t, err)
+	assert.Len(t, links, 2)
+
+	_, _, _, err = policy.Apply("Visit spam.xyz", "and https://example.com.evil.io/x")
+	var linkErr *services.LinkError
+	require.True(t, errors.As(err, &linkErr))
+	require.Len(t, linkErr.Links, 2)
+	assert.Equal(t, "title", linkErr.Links[0].Field)
+	assert.Equal(t, "spam.xyz", linkErr.Links[0].Text)
+	assert.Equal(t, "example.com.evil.io", linkErr.Links[1].Domain)
+}
+
+func TestLinkPolicy_Disallow(t *testing.T) {
+	os.Setenv("LINK_POLICY", services.LinkPolicyDisallow)
+	defer os.Unsetenv("LINK_POLICY")
+	policy := services.NewLinkPolicy()
+
+	_, _, _, err := policy.Apply("", "go to http://example.com now")
+	assert.EqualError(t, err, "links not allowed")
+
+	_, _, _, err = policy.Apply("", "no links in here")
+	assert.NoError(t, err)
+}
+
+type LinkHoldTestSuite struct {
+	suite.Suite
+	postService    *services.PostService
+	commentService *services.CommentService
+	db             *gorm.DB
+}
+
+func (suite *LinkHoldTestSuite) SetupSuite() {
+	database, err := testutil.OpenDB()
+	suite.Require().NoError(err)
+	suite.db = database
+
+	suite.postService = services.NewPostService()
+	suite.commentService = services.NewCommentService()
+}
+
+func (suite *LinkHoldTestSuite) SetupTest() {
+	testutil.Reset(suite.db)
+	suite.db.Exec("DELETE FROM comments")
+	suite.db.Exec("DELETE FROM posts")
+	suite.db.Exec("DELETE FROM identities")
+	suite.db.Exec("DELETE FROM audit_entries")
+}
+
+const linkHeavy = "Deals at shop.example.com, https://deals.example.net and www.offers.example.org"
+
+func (suite *LinkHoldTestSuite) TestNewIdentityIsHeld() {
+	post, err := suite.postService.CreatePost("Deals", linkHeavy, "10.8.0.1")
+	suite.Require().NoError(err)
+	suite.Equal(models.StatusPending, post.Status)
+	suite.Len(post.Links, 3)
+
+	var stored models.Post
+	suite.Require().NoError(suite.db.First(&stored, post.ID).Error)
+	suite.Len(stored.Links, 3)
+
+	entries, err := services.NewAuditService().List(services.AuditFilter{Action: models.AuditAutoHold})
+	suite.NoError(err)
+	suite.Require().Len(entries, 1)
+	suite.Equal("3 links from a new identity", entries[0].Reason)
+}
+
+func (suite *LinkHoldTestSuite) TestEstablishedIdentityIsNotHeld() {
+	first, err := suite.postService.CreatePost("Hello", "Just a plain secret without links", "10.8.0.2")
+	suite.Require().NoError(err)
+	suite.db.Model(&models.Identity{}).Where("ip_hash = ?", first.IPHash).
+		Update("first_seen_at", time.Now().Add(-7*24*time.Hour))
+
+	post, err := suite.postService.CreatePost("Deals", linkHeavy, "10.8.0.2")
+	suite.Require().NoError(err)
+	suite.Equal(models.StatusVisible, post.Status)
+
+	comment, err := suite.commentService.CreateComment(post.ID, "one link: https://example.com", "10.8.0.3")
+	suite.Require().NoError(err)
+	suite.Equal(models.StatusVisible, comment.Status)
+	suite.Len(comment.Links, 1)
+}
+
+func TestLinkHoldTestSuite(t *testing.T) {
+	suite.Run(t, new(LinkHoldTestSuite))
+}
@@ -0,0 +1,211 @@
+package services_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"reveal/internal/models"
+	"reveal/internal/services"
+	"reveal/tests/testutil"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type ModerationServiceTestSuite struct {
+	suite.Suite
+	service     *services.ModerationService
+	postService *services.PostService
+	db          *gorm.DB
+}
+
+func (suite *ModerationServiceTestSuite) SetupSuite() {
+	database, err := testutil.OpenDB()
+	suite.Require().NoError(err)
+	suite.db = database
+
+	suite.service = services.NewModerationService()
+	suite.postService = services.NewPostService()
+}
+
+func (suite *ModerationServiceTestSuite) SetupTest() {
+	testutil.Reset(suite.db)
+	suite.db.Exec("DELETE FROM flags")
+	suite.db.Exec("DELETE FROM comments")
+	suite.db.Exec("DELETE FROM posts")
+	suite.db.Exec("DELETE FROM identities")
+	suite.db.Exec("DELETE FROM audit_entries")
+}
+
+func (suite *ModerationServiceTestSuite) createPost() *models.Post {
+	post := &models.Post{
+		ID:        uuid.New(),
+		Title:     "Title",
+		Content:   "Some content",
+		IPHash:    "author",
+		Status:    models.StatusVisible,
+		CreatedAt: time.Now(),
+	}
+	suite.Require().NoError(suite.db.Create(post).Error)
+	return post
+}
+
+func (suite *ModerationServiceTestSuite) flagPost(post *models.Post, count int) {
+	for i := 0; i < count; i++ {
+		err := suite.postService.FlagPost(post.ID, fmt.Sprintf("172.16.0.%d", i+1), "spam", "")
+		suite.Require().NoError(err)
+	}
+}
+
+func (suite *ModerationServiceTestSuite) TestGetQueue_IncludesFlaggedAndReported() {
+	hidden := suite.createPost()
+	suite.flagPost(hidden, 5)
+
+	reported := suite.createPost()
+	suite.flagPost(reported, 2)
+
+	quiet := suite.createPost()
+	suite.flagPost(quiet, 1)
+
+	items, err := suite.service.GetQueue("", 50)
+	suite.NoError(err)
+	suite.Len(items, 2)
+
+	ids := []uuid.UUID{items[0].ID, items[1].ID}
+	suite.Contains(ids, hidden.ID)
+	suite.Contains(ids, reported.ID)
+	for _, item := range items {
+		suite.NotEmpty(item.Flags)
+		suite.Equal("spam", item.Flags[0].Reason)
+	}
+}
+
+func (suite *ModerationServiceTestSuite) TestGetQueue_IgnoresDiscountedFlags() {
+	post := suite.createPost()
+	now := time.Now()
+	addFlag := func(ipHash string, rejected int64) {
+		suite.Require().NoError(suite.db.Create(&models.Identity{IPHash: ipHash, FirstSeenAt: now, LastSeenAt: now, FlagsRejected: rejected}).Error)
+		suite.Require().NoError(suite.db.Create(&models.Flag{ID: uuid.New(), FlagType: models.FlagTypePost, PostID: &post.ID,
+			IPHash: ipHash, Reason: "spam", CreatedAt: now}).Error)
+	}
+
+	// Flaggers whose flags are almost always rejected do not fill the queue
+	addFlag("serial-flagger-1", 8)
+	addFlag("serial-flagger-2", 8)
+	items, err := suite.service.GetQueue("", 50)
+	suite.NoError(err)
+	suite.Empty(items)
+
+	addFlag("reader-1", 0)
+	addFlag("reader-2", 0)
+	items, err = suite.service.GetQueue("", 50)
+	suite.NoError(err)
+	suite.Require().Len(items, 1)
+	suite.Equal(post.ID, items[0].ID)
+}
+
+func (suite *ModerationServiceTestSuite) TestGetQueue_IgnoresFlagsOnOldContent() {
+	post := &models.Post{ID: uuid.New(), Title: "Title", Content: "Some content", IPHash: "author",
+		Status: models.StatusVisible, CreatedAt: time.Now().AddDate(-2, 0, 0)}
+	suite.Require().NoError(suite.db.Create(post).Error)
+	for i := 0; i < 5; i++ {
+		suite.Require().NoError(suite.db.Create(&models.Flag{ID: uuid.New(), FlagType: models.FlagTypePost, PostID: &post.ID,
+			IPHash: fmt.Sprintf("reader-%d", i), Reason: "spam", CreatedAt: time.Now()}).Error)
+	}
+
+	items, err := suite.service.GetQueue("", 50)
+	suite.NoError(err)
+	suite.Empty(items)
+}
+
+func (suite *ModerationServiceTestSuite) TestGetQueue_MergesNewestFirstWithinLimit() {
+	base := time.Now().Add(-time.Hour)
+	var expected []uuid.UUID
+	for i := 0; i < 3; i++ {
+		post := &models.Post{ID: uuid.New(), Title: "Title", Content: "Some content", IPHash: "author",
+			Status: models.StatusHeld, CreatedAt: base.Add(time.Duration(2*i) * time.Minute)}
+		suite.Require().NoError(suite.db.Create(post).Error)
+		comment := &models.Comment{ID: uuid.New(), PostID: post.ID, Content: "A comment", IPHash: "author",
+			Status: models.StatusHeld, CreatedAt: base.Add(time.Duration(2*i+1) * time.Minute)}
+		suite.Require().NoError(suite.db.Create(comment).Error)
+		expected = append([]uuid.UUID{comment.ID, post.ID}, expected...)
+	}
+
+	items, err := suite.service.GetQueue("", 3)
+	suite.Require().NoError(err)
+	suite.Require().Len(items, 3)
+	for i, item := range items {
+		suite.Equal(expected[i], item.ID)
+	}
+
+	// The next page picks up where the last one stopped
+	last := items[len(items)-1]
+	items, err = suite.service.GetQueuePage("", &services.QueueCursor{CreatedAt: last.CreatedAt, ID: last.ID}, 3)
+	suite.Require().NoError(err)
+	suite.Require().Len(items, 3)
+	for i, item := range items {
+		suite.Equal(expected[i+3], item.ID)
+	}
+}
+
+func (suite *ModerationServiceTestSuite) TestApprovePost_UnflagsAndDismissesFlags() {
+	post := suite.createPost()
+	suite.flagPost(post, 5)
+
+	suite.NoError(suite.service.ApprovePost(post.ID, "alice", "not spam"))
+
+	var updated models.Post
+	suite.db.First(&updated, post.ID)
+	suite.False(updated.Flagged)
+	suite.Equal(models.StatusVisible, updated.Status)
+
+	var open int64
+	suite.db.Model(&models.Flag{}).Where("post_id = ? AND resolution = ?", post.ID, models.FlagResolutionPending).Count(&open)
+	suite.Equal(int64(0), open)
+
+	items, _ := suite.service.GetQueue(models.FlagTypePost, 50)
+	suite.Len(items, 0)
+
+	posts, _ := suite.postService.GetRecentPosts("10.9.9.9", 10)
+	suite.Len(posts, 1)
+}
+
+func (suite *ModerationServiceTestSuite) TestRemovePost_HidesAndUpholdsFlags() {
+	post := suite.createPost()
+	suite.flagPost(post, 2)
+
+	suite.NoError(suite.service.RemovePost(post.ID, "alice", "abuse"))
+
+	item, err := suite.service.GetPost(post.ID)
+	suite.NoError(err)
+	suite.Equal(models.StatusRemoved, item.Status)
+	for _, flag := range item.Flags {
+		suite.Equal(models.FlagResolutionUpheld, flag.Resolution)
+		suite.Equal("alice", flag.ResolvedBy)
+	}
+
+	posts, _ := suite.postService.GetRecentPosts("10.9.9.9", 10)
+	suite.Len(posts, 0)
+}
+
+func (suite *ModerationServiceTestSuite) TestBulk_ReportsPerItemResults() {
+	post := suite.createPost()
+
+	results, err := suite.service.Bulk(models.ModerationRemove, []services.ModerationTarget{
+		{Type: models.FlagTypePost, ID: post.ID},
+		{Type: models.FlagTypePost, ID: uuid.New()},
+	}, "alice", "")
+	suite.NoError(err)
+	suite.Len(results, 2)
+	suite.True(results[0].OK)
+	suite.False(results[1].OK)
+
+	_, err = suite.service.Bulk("explode", nil, "alice", "")
+	suite.Error(err)
+}
+
+func TestModerationServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(ModerationServiceTestSuite))
+}
@@ -0,0 +1,97 @@
+package services_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"reveal/internal/models"
+	"reveal/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTextNormalizer_StripsInvisibleAndBidi(t *testing.T) {
+	normalizer := services.NewTextNormalizer()
+
+	assert.Equal(t, "free money", normalizer.Normalize("fr\u200bee\u2060 mon\ufeffey"))
+	assert.Equal(t, "invoice.exe", normalizer.Normalize("invoice\u202e.exe\u202c"))
+	// Emoji sequences keep their joiners
+	assert.Equal(t, "\U0001F469\u200d\U0001F4BB", normalizer.Normalize("\U0001F469\u200d\U0001F4BB"))
+	// Decomposed accents are composed
+	assert.Equal(t, "café", normalizer.Normalize("cafe\u0301"))
+}
+
+func TestTextNormalizer_CapsCombiningMarks(t *testing.T) {
+	normalizer := services.NewTextNormalizer()
+
+	zalgo := "h" + strings.Repeat("\u0300\u0301\u0302", 10) + "i"
+	normalized := normalizer.Normalize(zalgo)
+	assert.Equal(t, "h\u0300\u0301\u0302i", normalized)
+	assert.Equal(t, 2, services.GraphemeCount(normalized))
+}
+
+func TestTextNormalizer_NFKC(t *testing.T) {
+	os.Setenv("TEXT_NORMALIZATION", services.NormalizationNFKC)
+	defer os.Unsetenv("TEXT_NORMALIZATION")
+	normalizer := services.NewTextNormalizer()
+
+	assert.Equal(t, "Free money", normalizer.Normalize("Ｆｒｅｅ 𝐦𝐨𝐧𝐞𝐲"))
+}
+
+func TestTextNormalizer_Skeleton(t *testing.T) {
+	normalizer := services.NewTextNormalizer()
+
+	for _, text := range []string{
+		"FREE MONEY",
+		"fr\u0435\u0435 m\u043en\u0435y", // Cyrillic \u0435 and \u043e
+		"ＦＲΕΕ 𝐦𝐨𝐧𝐞𝐲",                     // Fullwidth, Greek and mathematical letters
+		"fr\u200bée mónéy",
+	} {
+		assert.Equal(t, "free money", normalizer.Skeleton(text), text)
+	}
+}
+
+func TestGraphemeCount(t *testing.T) {
+	cases := map[string]int{
+		"":                           0,
+		"hello":                      5,
+		"cafe\u0301":                 4,
+		"\U0001F469\u200d\U0001F4BB": 1, // Woman technologist
+		"\U0001F44D\U0001F3FD":       1, // Thumbs up with skin tone
+		"\U0001F1E9\U0001F1EA\U0001F1EB\U0001F1F7": 2, // Two flags
+		"❤\ufe0f":            1,
+		"한국어":                3,
+		"\u1100\u1161\u11a8": 1, // Decomposed Hangul syllable
+		"line\r\nbreak":      10,
+	}
+	for text, expected := range cases {
+		assert.Equal(t, expected, services.GraphemeCount(text), text)
+	}
+}
+
+func TestContentFilter_KeywordsMatchHomoglyphs(t *testing.T) {
+	filter := services.NewContentFilter()
+	rule := &models.FilterRule{
+		Name:      "scam",
+		MatchType: models.FilterMatchKeyword,
+		Pattern:   "free money, crypto",
+		Action:    models.FilterActionMask,
+	}
+
+	spoofed := "fr\u0435\u0435 m\u043en\u0435y"
+	result, err := filter.DryRun(rule, "", "Get "+spoofed+" and ｃｒｙｐｔｏ now, not cryptography")
+	require.NoError(t, err)
+	require.Len(t, result.Matches, 2)
+	assert.Equal(t, spoofed, result.Matches[0].Text)
+	assert.Equal(t, "ｃｒｙｐｔｏ", result.Matches[1].Text)
+	assert.Equal(t, "Get ********** and ****** now, not cryptography", result.Content)
+}
+
+func TestDuplicateDetector_FingerprintIgnoresHomoglyphs(t *testing.T) {
+	detector := services.NewDuplicateDetector()
+
+	spoofed := strings.NewReplacer("o", "\u043e", "e", "\u0435", " ", " \u200b").Replace(secretText)
+	assert.Equal(t, detector.Fingerprint("Confession", secretText), detector.Fingerprint("Confession", spoofed))
+}
@@ -0,0 +1,84 @@
+package services_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"reveal/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func findingTypes(findings []services.PIIFinding) []string {
+	types := make([]string, len(findings))
+	for i, f := range findings {
+		types[i] = f.Type
+	}
+	return types
+}
+
+func TestPIIScanner_Detects(t *testing.T) {
+	scanner := services.NewPIIScanner()
+
+	cases := map[string]string{
+		"mail me at jane.doe+x@example.co.uk please":      services.PIIEmail,
+		"call +1 (415) 555-0132 tonight":                  services.PIIPhone,
+		"ring 020 7946 0958 after six":                    services.PIIPhone,
+		"text +44 7700 900123":                            services.PIIPhone,
+		"mon numéro: 06 12 34 56 78":                      services.PIIPhone,
+		"my card is 4111 1111 1111 1111":                  services.PIICard,
+		"ssn 123-45-6789":                                 services.PIIGovernment,
+		"NI number AB 12 34 56 C":                         services.PIIGovernment,
+		"see https://shop.example/item?id=4&utm_source=x": services.PIITrackingURL,
+	}
+	for text, expected := range cases {
+		assert.Equal(t, []string{expected}, findingTypes(scanner.Scan("content", text)), text)
+	}
+}
+
+func TestPIIScanner_IgnoresLookalikes(t *testing.T) {
+	scanner := services.NewPIIScanner()
+
+	for _, text := range []string{
+		"it happened on 2023-01-15 10:30",
+		"I owe 1234 5678 9012 3456 dollars", // Fails the Luhn check and has 16 digits
+		"read https://example.com/story?id=42",
+		"I was 25 and she was 31",
+		"we dated in 2019 2020 2021",
+		"100 200 300 likes",
+		"1234 5678 9",
+	} {
+		assert.Empty(t, scanner.Scan("content", text), text)
+	}
+}
+
+func TestPIIScanner_RedactsByDefault(t *testing.T) {
+	os.Unsetenv("PII_MODE")
+	scanner := services.NewPIIScanner()
+
+	title, content, err := scanner.Apply("Write to me@example.com",
+		"Details at https://example.com/a?utm_campaign=x&page=2 or 415-555-0132.")
+	require.NoError(t, err)
+
+	assert.Equal(t, "Write to [email removed]", title)
+	assert.Equal(t, "Details at https://example.com/a?page=2 or [phone removed].", content)
+}
+
+func TestPIIScanner_RejectMode(t *testing.T) {
+	os.Setenv("PII_MODE", "reject")
+	defer os.Unsetenv("PII_MODE")
+	scanner := services.NewPIIScanner()
+
+	_, _, err := scanner.Apply("Hello", "card 4111-1111-1111-1111")
+	var piiErr *services.PIIError
+	require.True(t, errors.As(err, &piiErr))
+	require.Len(t, piiErr.Findings, 1)
+	assert.Equal(t, services.PIICard, piiErr.Findings[0].Type)
+	assert.Equal(t, "content", piiErr.Findings[0].Field)
+	assert.Equal(t, "4111-1111-1111-1111", piiErr.Findings[0].Text)
+
+	_, _, err = scanner.Apply("Hello", "nothing personal here")
+	assert.NoError(t, err)
+}
@@ -3,17 +3,17 @@ package services_test
 import (
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
-	"reveal/internal/db"
 	"reveal/internal/models"
 	"reveal/internal/services"
+	"reveal/tests/testutil"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
@@ -25,28 +25,15 @@ type PostServiceTestSuite struct {
 
 func (suite *PostServiceTestSuite) SetupSuite() {
 	// Use in-memory SQLite for testing
-	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	database, err := testutil.OpenDB()
 	suite.Require().NoError(err)
-	
-	// Set global DB for the service to use
-	db.DB = database
 	suite.db = database
 	
-	// Auto-migrate the schema
-	err = database.AutoMigrate(&models.Post{}, &models.Flag{})
-	suite.Require().NoError(err)
-	
-	// Set test environment variable
-	os.Setenv("SALT_KEY", "test_salt_key")
-	
 	suite.service = services.NewPostService()
 }
 
-func (suite *PostServiceTestSuite) TearDownSuite() {
-	os.Unsetenv("SALT_KEY")
-}
-
 func (suite *PostServiceTestSuite) SetupTest() {
+	testutil.Reset(suite.db)
 	// Clean the database before each test
 	suite.db.Exec("DELETE FROM flags")
 	suite.db.Exec("DELETE FROM posts")
@@ -83,18 +70,36 @@ func (suite *PostServiceTestSuite) TestCreatePost_EmptyContent() {
 	assert.Nil(suite.T(), post)
 }
 
+func (suite *PostServiceTestSuite) TestCreatePost_LengthCheckedAfterNormalizing() {
+	os.Setenv("TEXT_NORMALIZATION", services.NormalizationNFKC)
+	defer os.Unsetenv("TEXT_NORMALIZATION")
+	service := services.NewPostService()
+
+	// Each ligature is one character that NFKC expands to eighteen
+	_, err := service.CreatePost(strings.Repeat("\uFDFA", 20), "Some content", "127.0.0.1")
+	suite.EqualError(err, "title too long")
+
+	// Oversized input is refused before any work is done on it
+	zalgo := "a" + strings.Repeat("\u0301", services.MaxBytesPerCharacter*services.MaxContentLength)
+	_, err = service.CreatePost("Title", zalgo, "127.0.0.1")
+	suite.EqualError(err, "content too long")
+
+	_, err = service.CreatePost("Title", "Too short", "127.0.0.1")
+	suite.EqualError(err, "content too short")
+}
+
 func (suite *PostServiceTestSuite) TestCreatePost_SpamPrevention() {
 	clientIP := "192.168.1.1"
 	
 	// Create 5 posts (should succeed)
 	for i := 0; i < 5; i++ {
-		post, err := suite.service.CreatePost("Title", "Content", clientIP)
+		post, err := suite.service.CreatePost("Title", "Some content", clientIP)
 		assert.NoError(suite.T(), err)
 		assert.NotNil(suite.T(), post)
 	}
 	
 	// 6th post should fail due to spam prevention
-	post, err := suite.service.CreatePost("Title", "Content", clientIP)
+	post, err := suite.service.CreatePost("Title", "Some content", clientIP)
 	assert.Error(suite.T(), err)
 	assert.Nil(suite.T(), post)
 	assert.Contains(suite.T(), err.Error(), "rate limit exceeded")
@@ -154,7 +159,7 @@ func (suite *PostServiceTestSuite) TestFlagPost_Success() {
 	clientIP := "127.0.0.1"
 	
 	// Create test post
-	post, _ := suite.service.CreatePost("Title", "Content", clientIP)
+	post, _ := suite.service.CreatePost("Title", "Some content", clientIP)
 	
 	// Flag the post with different IP
 	flaggingIP := "192.168.1.1"
@@ -174,7 +179,7 @@ func (suite *PostServiceTestSuite) TestFlagPost_DuplicateFlag() {
 	clientIP := "127.0.0.1"
 	
 	// Create test post
-	post, _ := suite.service.CreatePost("Title", "Content", clientIP)
+	post, _ := suite.service.CreatePost("Title", "Some content", clientIP)
 	
 	// Flag the post
 	err := suite.service.FlagPost(post.ID, clientIP, "spam", "")
@@ -198,7 +203,7 @@ func (suite *PostServiceTestSuite) TestFlagPost_NonExistentPost() {
 
 func (suite *PostServiceTestSuite) TestFlagPost_GlobalFlagAfter5Flags() {
 	// Create test post
-	post, _ := suite.service.CreatePost("Title", "Content", "127.0.0.1")
+	post, _ := suite.service.CreatePost("Title", "Some content", "127.0.0.1")
 	
 	// Flag the post with 5 different IPs
 	for i := 0; i < 5; i++ {
@@ -0,0 +1,100 @@
+package services_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"reveal/internal/models"
+	"reveal/internal/services"
+	"reveal/tests/testutil"
+
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type PremoderationTestSuite struct {
+	suite.Suite
+	postService       *services.PostService
+	moderationService *services.ModerationService
+	db                *gorm.DB
+}
+
+func (suite *PremoderationTestSuite) SetupSuite() {
+	database, err := testutil.OpenDB()
+	suite.Require().NoError(err)
+	suite.db = database
+
+	suite.postService = services.NewPostService()
+	suite.moderationService = services.NewModerationService()
+}
+
+func (suite *PremoderationTestSuite) SetupTest() {
+	testutil.Reset(suite.db)
+	suite.db.Exec("DELETE FROM posts")
+	suite.db.Exec("DELETE FROM identities")
+}
+
+func (suite *PremoderationTestSuite) TearDownTest() {
+	os.Unsetenv("PREMODERATION")
+}
+
+func (suite *PremoderationTestSuite) TestAll_HoldsPostsUntilApproved() {
+	os.Setenv("PREMODERATION", services.PremoderationAll)
+
+	post, err := suite.postService.CreatePost("Title", "Some content", "10.6.0.1")
+	suite.Require().NoError(err)
+	suite.Equal(models.StatusPending, post.Status)
+
+	// The author sees it by IP hash, or from elsewhere with the management token
+	own, err := suite.postService.GetRecentPosts("10.6.0.1", 10)
+	suite.NoError(err)
+	suite.Len(own, 1)
+	suite.Equal(models.StatusPending, own[0].Status)
+
+	withToken, err := suite.postService.GetRecentPosts("10.6.0.2", 10, post.ManageToken)
+	suite.NoError(err)
+	suite.Len(withToken, 1)
+
+	others, err := suite.postService.GetRecentPosts("10.6.0.3", 10, "not-a-token")
+	suite.NoError(err)
+	suite.Len(others, 0)
+
+	suite.NoError(suite.moderationService.ApprovePost(post.ID, "alice", "looks fine"))
+
+	others, err = suite.postService.GetRecentPosts("10.6.0.3", 10)
+	suite.NoError(err)
+	suite.Len(others, 1)
+	suite.Equal(models.StatusVisible, others[0].Status)
+}
+
+func (suite *PremoderationTestSuite) TestUntrusted_OnlyHoldsLowTrustIdentities() {
+	os.Setenv("PREMODERATION", services.PremoderationUntrusted)
+
+	// A newcomer's first post waits; once the identity is long-standing and active it does not
+	trustedIP := "10.6.1.1"
+	post, err := suite.postService.CreatePost("Title", "Some content", trustedIP)
+	suite.Require().NoError(err)
+	suite.Equal(models.StatusPending, post.Status)
+
+	suite.Require().NoError(suite.db.Model(&models.Identity{}).Where("ip_hash = ?", post.IPHash).
+		Updates(map[string]interface{}{"first_seen_at": time.Now().Add(-60 * 24 * time.Hour), "vote_count": 100}).Error)
+
+	post, err = suite.postService.CreatePost("Title", "Some content", trustedIP)
+	suite.Require().NoError(err)
+	suite.Equal(models.StatusVisible, post.Status)
+
+	post, err = suite.postService.CreatePost("Title", "Some content", "10.6.1.2")
+	suite.Require().NoError(err)
+	suite.Equal(models.StatusPending, post.Status)
+}
+
+func (suite *PremoderationTestSuite) TestOff_PublishesImmediately() {
+	post, err := suite.postService.CreatePost("Title", "Some content", "10.6.2.1")
+	suite.Require().NoError(err)
+	suite.Equal(models.StatusVisible, post.Status)
+}
+
+func TestPremoderationTestSuite(t *testing.T) {
+	suite.Run(t, new(PremoderationTestSuite))
+}
@@ -0,0 +1,64 @@
+package services_test
+
+import (
+	"testing"
+
+	"reveal/internal/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWilsonLowerBound_NoVotes(t *testing.T) {
+	assert.Equal(t, 0.0, services.WilsonLowerBound(0, 0))
+}
+
+func TestWilsonLowerBound_EstablishedBeatsSingleUpvote(t *testing.T) {
+	fresh := services.WilsonLowerBound(1, 0)
+	established := services.WilsonLowerBound(40, 5)
+
+	assert.Greater(t, established, fresh)
+}
+
+func TestWilsonLowerBound_KnownValue(t *testing.T) {
+	// 40 up / 5 down at 95% confidence
+	assert.InDelta(t, 0.7651, services.WilsonLowerBound(40, 5), 0.0005)
+}
+
+func TestWilsonLowerBound_WithinUnitInterval(t *testing.T) {
+	cases := [][2]float64{{1, 0}, {0, 1}, {100, 0}, {0, 100}, {3, 7}, {500, 499}}
+	for _, c := range cases {
+		score := services.WilsonLowerBound(c[0], c[1])
+		assert.GreaterOrEqual(t, score, 0.0)
+		assert.LessOrEqual(t, score, 1.0)
+	}
+}
+
+func TestWilsonLowerBound_MonotonicInUpvotes(t *testing.T) {
+	assert.Greater(t, services.WilsonLowerBound(11, 5), services.WilsonLowerBound(10, 5))
+	assert.Less(t, services.WilsonLowerBound(10, 6), services.WilsonLowerBound(10, 5))
+}
+
+func TestBayesianAverage_NoVotesReturnsPrior(t *testing.T) {
+	assert.Equal(t, 0.7, services.BayesianAverage(0, 0, 0.7, 10))
+}
+
+func TestBayesianAverage_ShrinksTowardsPrior(t *testing.T) {
+	// A single upvote should stay close to the prior
+	assert.InDelta(t, (10*0.5+1)/11.0, services.BayesianAverage(1, 0, 0.5, 10), 1e-9)
+
+	// Many votes should dominate the prior
+	assert.InDelta(t, 0.9, services.BayesianAverage(900, 100, 0.5, 10), 0.01)
+}
+
+func TestBayesianAverage_ZeroWeightIsRawRatio(t *testing.T) {
+	assert.InDelta(t, 0.75, services.BayesianAverage(3, 1, 0.5, 0), 1e-9)
+}
+
+func TestIsValidSorts(t *testing.T) {
+	assert.True(t, services.IsValidPostSort(services.SortBayesian))
+	assert.True(t, services.IsValidPostSort(services.SortBest))
+	assert.False(t, services.IsValidPostSort(services.SortOld))
+	assert.True(t, services.IsValidCommentSort(services.SortBest))
+	assert.False(t, services.IsValidCommentSort(services.SortBayesian))
+	assert.False(t, services.IsValidCommentSort("random"))
+}
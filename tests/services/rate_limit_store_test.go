@@ -0,0 +1,201 @@
+package services_test
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"reveal/internal/db"
+	"reveal/internal/models"
+	"reveal/internal/services"
+	"reveal/tests/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var fivePerMinute = services.Rate{Requests: 5, Window: time.Minute}
+
+// exerciseStore checks the GCRA behaviour every store must share
+func exerciseStore(t *testing.T, store services.RateLimitStore) {
+	for i := 4; i >= 0; i-- {
+		result, err := store.Allow("api:a", fivePerMinute)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, i, result.Remaining)
+		assert.Equal(t, 5, result.Limit)
+	}
+
+	result, err := store.Allow("api:a", fivePerMinute)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.InDelta(t, 12*time.Second, result.RetryAfter, float64(time.Second))
+	assert.InDelta(t, time.Minute, result.ResetAfter, float64(time.Second))
+
+	// Keys are independent
+	result, err = store.Allow("post:a", fivePerMinute)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	exerciseStore(t, services.NewMemoryRateLimitStore(0))
+}
+
+func TestMemoryRateLimitStore_SweepsFullBuckets(t *testing.T) {
+	store := services.NewMemoryRateLimitStore(0)
+	store.Allow("a", fivePerMinute)
+	store.Allow("b", services.Rate{Requests: 1, Window: time.Hour})
+	assert.Equal(t, 2, store.Len())
+
+	assert.Zero(t, store.Sweep(time.Now()))
+	assert.Equal(t, 1, store.Sweep(time.Now().Add(2*time.Minute)))
+	assert.Equal(t, 1, store.Len())
+	assert.Equal(t, uint64(1), store.Evictions())
+}
+
+func TestMemoryRateLimitStore_EvictsLeastRecentlyUsed(t *testing.T) {
+	store := services.NewMemoryRateLimitStore(2)
+	oneAnHour := services.Rate{Requests: 1, Window: time.Hour}
+
+	store.Allow("a", oneAnHour)
+	store.Allow("b", oneAnHour)
+	store.Allow("a", oneAnHour) // b is now the least recently used
+	store.Allow("c", oneAnHour)
+
+	assert.Equal(t, 2, store.Len())
+	assert.Equal(t, uint64(1), store.Evictions())
+	result, _ := store.Allow("a", oneAnHour)
+	assert.False(t, result.Allowed)
+	result, _ = store.Allow("b", oneAnHour)
+	assert.True(t, result.Allowed)
+}
+
+func TestMemoryRateLimitStore_Janitor(t *testing.T) {
+	store := services.NewMemoryRateLimitStore(0)
+	stop := store.StartJanitor(5 * time.Millisecond)
+	defer stop()
+
+	store.Allow("a", services.Rate{Requests: 1, Window: time.Millisecond})
+	assert.Eventually(t, func() bool { return store.Len() == 0 }, time.Second, 5*time.Millisecond)
+}
+
+type PostgresRateLimitStoreTestSuite struct {
+	suite.Suite
+	db *gorm.DB
+}
+
+func (suite *PostgresRateLimitStoreTestSuite) SetupSuite() {
+	database, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	suite.Require().NoError(err)
+	suite.db = database
+	suite.Require().NoError(database.AutoMigrate(&models.RateLimitBucket{}))
+}
+
+func (suite *PostgresRateLimitStoreTestSuite) SetupTest() {
+	db.DB = suite.db
+	suite.db.Exec("DELETE FROM rate_limit_buckets")
+}
+
+func (suite *PostgresRateLimitStoreTestSuite) TestAllow() {
+	exerciseStore(suite.T(), services.NewPostgresRateLimitStore())
+
+	var bucket models.RateLimitBucket
+	suite.Require().NoError(suite.db.First(&bucket, "key = ?", "api:a").Error)
+	suite.Greater(bucket.TAT, time.Now().Add(50*time.Second).UnixNano())
+}
+
+func (suite *PostgresRateLimitStoreTestSuite) TestInstancesShareBuckets() {
+	// Two stores stand in for two server instances
+	first, second := services.NewPostgresRateLimitStore(), services.NewPostgresRateLimitStore()
+
+	var wg sync.WaitGroup
+	allowed := make(chan bool, 10)
+	for i := 0; i < 10; i++ {
+		store := first
+		if i%2 == 1 {
+			store = second
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := store.Allow("vote:b", fivePerMinute)
+			suite.NoError(err)
+			allowed <- result.Allowed
+		}()
+	}
+	wg.Wait()
+	close(allowed)
+
+	count := 0
+	for ok := range allowed {
+		if ok {
+			count++
+		}
+	}
+	suite.Equal(5, count)
+}
+
+func (suite *PostgresRateLimitStoreTestSuite) TestSweep() {
+	store := services.NewPostgresRateLimitStore()
+	store.Allow("a", fivePerMinute)
+	store.Allow("b", services.Rate{Requests: 1, Window: time.Hour})
+
+	removed, err := store.Sweep(time.Now().Add(2 * time.Minute))
+	suite.NoError(err)
+	suite.Equal(int64(1), removed)
+}
+
+func TestPostgresRateLimitStoreTestSuite(t *testing.T) {
+	suite.Run(t, new(PostgresRateLimitStoreTestSuite))
+}
+
+func TestClientKey(t *testing.T) {
+	assert.Equal(t, "192.0.2.7", services.ClientKey("192.0.2.7"))
+	assert.Equal(t, "192.0.2.7", services.ClientKey("::ffff:192.0.2.7"))
+	assert.Equal(t, "2001:db8:1:2::/64", services.ClientKey("2001:db8:1:2:aaaa:bbbb:cccc:dddd"))
+	assert.Equal(t, services.ClientKey("2001:db8:1:2::1"), services.ClientKey("2001:db8:1:2:ffff::9"))
+	assert.NotEqual(t, services.ClientKey("2001:db8:1:2::1"), services.ClientKey("2001:db8:1:3::1"))
+	assert.Equal(t, "not-an-ip", services.ClientKey("not-an-ip"))
+}
+
+func TestSpamChecksCountInDatabase(t *testing.T) {
+	database, err := testutil.OpenDB()
+	require.NoError(t, err)
+	testutil.Reset(database)
+	os.Setenv("PII_MODE", "reject")
+	defer os.Unsetenv("PII_MODE")
+
+	// Rejected posts do not use up the allowance
+	postService := services.NewPostService()
+	for i := 0; i < 5; i++ {
+		_, err := postService.CreatePost("Secret", "Write to me at someone@example.com", "2001:db8:5::1")
+		require.Error(t, err)
+	}
+
+	// Deleting posts does not reset the limit, and neither does emptying the
+	// in-memory store: the buckets are in the database every instance shares.
+	// Addresses in the same IPv6 /64 share one limit.
+	for i := 0; i < 5; i++ {
+		_, err := postService.CreatePost("Secret", "Something I never told anyone", fmt.Sprintf("2001:db8:5::%d", i+1))
+		require.NoError(t, err)
+		database.Exec("DELETE FROM posts")
+	}
+	services.SharedRateLimitStore().(*services.MemoryRateLimitStore).Reset()
+	_, err = services.NewPostService().CreatePost("Secret", "Something I never told anyone", "2001:db8:5::ffff")
+	assert.EqualError(t, err, "rate limit exceeded")
+
+	_, err = postService.CreatePost("Secret", "Something I never told anyone", "2001:db8:6::1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), services.WriteRateLimitCount())
+
+	services.ResetRateLimits()
+	assert.Equal(t, int64(0), services.WriteRateLimitCount())
+	_, err = postService.CreatePost("Secret", "Something I never told anyone", "2001:db8:5::1")
+	assert.NoError(t, err)
+}
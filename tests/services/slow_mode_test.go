@@ -0,0 +1,156 @@
+package services_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"reveal/internal/models"
+	"reveal/internal/services"
+	"reveal/tests/testutil"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type SlowModeTestSuite struct {
+	suite.Suite
+	postService     *services.PostService
+	commentService  *services.CommentService
+	slowModeService *services.SlowModeService
+	db              *gorm.DB
+}
+
+func (suite *SlowModeTestSuite) SetupSuite() {
+	database, err := testutil.OpenDB()
+	suite.Require().NoError(err)
+	suite.db = database
+
+	os.Setenv("SLOW_MODE_COMMENT_RATE", "3")
+	os.Setenv("SLOW_MODE_MIN_REACTIONS", "4")
+
+	suite.postService = services.NewPostService()
+	suite.commentService = services.NewCommentService()
+	suite.slowModeService = services.NewSlowModeService()
+}
+
+func (suite *SlowModeTestSuite) TearDownSuite() {
+	os.Unsetenv("SLOW_MODE_COMMENT_RATE")
+	os.Unsetenv("SLOW_MODE_MIN_REACTIONS")
+}
+
+func (suite *SlowModeTestSuite) SetupTest() {
+	testutil.Reset(suite.db)
+	suite.db.Exec("DELETE FROM comments")
+	suite.db.Exec("DELETE FROM votes")
+	suite.db.Exec("DELETE FROM posts")
+	suite.db.Exec("DELETE FROM identities")
+	suite.db.Exec("DELETE FROM audit_entries")
+}
+
+func (suite *SlowModeTestSuite) createPost() *models.Post {
+	post, err := suite.postService.CreatePost("Heated", "A secret everyone has opinions about", "10.10.0.1")
+	suite.Require().NoError(err)
+	return post
+}
+
+func (suite *SlowModeTestSuite) comment(postID uuid.UUID, ip string) error {
+	_, err := suite.commentService.CreateComment(postID, "I have thoughts on this", ip)
+	return err
+}
+
+func (suite *SlowModeTestSuite) TestCommentRateStartsSlowMode() {
+	post := suite.createPost()
+	for i := 1; i <= 3; i++ {
+		suite.Require().NoError(suite.comment(post.ID, fmt.Sprintf("10.10.1.%d", i)))
+	}
+
+	// The next comment sees the spike and switches slow mode on
+	suite.Require().NoError(suite.comment(post.ID, "10.10.1.4"))
+
+	var stored models.Post
+	suite.Require().NoError(suite.db.First(&stored, "id = ?", post.ID).Error)
+	suite.Require().NotNil(stored.SlowModeUntil)
+	suite.True(stored.SlowModeUntil.After(time.Now()))
+	suite.Equal(60, stored.SlowModeSeconds)
+
+	entries, err := services.NewAuditService().List(services.AuditFilter{Action: models.AuditSlowModeOn})
+	suite.NoError(err)
+	suite.Require().Len(entries, 1)
+	suite.Equal(models.AuditActorSystem, entries[0].Actor)
+	suite.Equal("3 comments in 10 minutes", entries[0].Reason)
+
+	// An identity that already commented has to wait
+	err = suite.comment(post.ID, "10.10.1.2")
+	var slowErr *services.SlowModeError
+	suite.Require().True(errors.As(err, &slowErr))
+	suite.Greater(slowErr.RetryAfter, 50*time.Second)
+	suite.WithinDuration(*stored.SlowModeUntil, slowErr.Until, time.Second)
+
+	// A newcomer to the thread does not
+	suite.NoError(suite.comment(post.ID, "10.10.1.9"))
+}
+
+func (suite *SlowModeTestSuite) TestDownvotesStartSlowMode() {
+	post := suite.createPost()
+	for i := 0; i < 4; i++ {
+		suite.Require().NoError(suite.db.Create(&models.Vote{
+			PostID:   &post.ID,
+			IPHash:   fmt.Sprintf("voter-%d", i),
+			VoteType: models.VoteTypeDownvote,
+		}).Error)
+	}
+
+	suite.Require().NoError(suite.comment(post.ID, "10.10.2.1"))
+	suite.Error(suite.comment(post.ID, "10.10.2.1"))
+
+	entries, err := services.NewAuditService().List(services.AuditFilter{Action: models.AuditSlowModeOn})
+	suite.NoError(err)
+	suite.Require().Len(entries, 1)
+	suite.Equal("100% of 4 reactions are downvotes or flags", entries[0].Reason)
+}
+
+func (suite *SlowModeTestSuite) TestModeratorToggle() {
+	post := suite.createPost()
+
+	updated, err := suite.slowModeService.SetSlowMode(post.ID, true, 30*time.Second, time.Hour, "mod", "calm down")
+	suite.Require().NoError(err)
+	suite.Equal(30, updated.SlowModeSeconds)
+	suite.Require().NotNil(updated.SlowModeUntil)
+
+	suite.Require().NoError(suite.comment(post.ID, "10.10.3.1"))
+	suite.Error(suite.comment(post.ID, "10.10.3.1"))
+
+	updated, err = suite.slowModeService.SetSlowMode(post.ID, false, 0, 0, "mod", "")
+	suite.Require().NoError(err)
+	suite.Nil(updated.SlowModeUntil)
+	suite.NoError(suite.comment(post.ID, "10.10.3.1"))
+
+	// Switched off by a moderator, the spike does not restart it
+	for i := 1; i <= 3; i++ {
+		suite.Require().NoError(suite.comment(post.ID, fmt.Sprintf("10.10.4.%d", i)))
+	}
+	suite.NoError(suite.comment(post.ID, "10.10.4.1"))
+
+	entries, err := services.NewAuditService().List(services.AuditFilter{Actor: "mod"})
+	suite.NoError(err)
+	suite.Require().Len(entries, 2)
+	suite.Equal(models.AuditSlowModeOff, entries[0].Action)
+	suite.Equal(models.AuditSlowModeOn, entries[1].Action)
+	suite.Equal("calm down", entries[1].Reason)
+}
+
+func (suite *SlowModeTestSuite) TestSetSlowMode_Errors() {
+	_, err := suite.slowModeService.SetSlowMode(uuid.New(), true, 0, time.Hour, "mod", "")
+	suite.EqualError(err, "invalid slow mode")
+
+	_, err = suite.slowModeService.SetSlowMode(uuid.New(), false, 0, 0, "mod", "")
+	suite.EqualError(err, "post not found")
+}
+
+func TestSlowModeTestSuite(t *testing.T) {
+	suite.Run(t, new(SlowModeTestSuite))
+}
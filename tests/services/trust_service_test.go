@@ -0,0 +1,57 @@
+package services_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"reveal/internal/models"
+	"reveal/internal/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrustScore_OlderIdentitiesScoreHigher(t *testing.T) {
+	trust := services.NewTrustService()
+
+	fresh := trust.Score(&models.Identity{FirstSeenAt: time.Now()})
+	established := trust.Score(&models.Identity{FirstSeenAt: time.Now().Add(-60 * 24 * time.Hour)})
+
+	assert.Greater(t, established, fresh)
+}
+
+func TestTrustScore_ActivityAndFlagAccuracy(t *testing.T) {
+	trust := services.NewTrustService()
+	since := time.Now().Add(-24 * time.Hour)
+
+	idle := trust.Score(&models.Identity{FirstSeenAt: since})
+	active := trust.Score(&models.Identity{FirstSeenAt: since, PostCount: 5, CommentCount: 20, VoteCount: 40})
+	assert.Greater(t, active, idle)
+
+	accurate := trust.Score(&models.Identity{FirstSeenAt: since, FlagsUpheld: 10})
+	abusive := trust.Score(&models.Identity{FirstSeenAt: since, FlagsRejected: 10})
+	assert.Greater(t, accurate, abusive)
+}
+
+func TestTrustScore_Bounded(t *testing.T) {
+	trust := services.NewTrustService()
+
+	score := trust.Score(&models.Identity{
+		FirstSeenAt: time.Now().Add(-365 * 24 * time.Hour),
+		PostCount:   1000,
+		FlagsUpheld: 1000,
+	})
+
+	assert.LessOrEqual(t, score, 1.0)
+	assert.GreaterOrEqual(t, score, 0.0)
+}
+
+func TestTrustScore_DisabledTrustsEveryone(t *testing.T) {
+	os.Setenv("TRUST_ENABLED", "false")
+	defer os.Unsetenv("TRUST_ENABLED")
+
+	trust := services.NewTrustService()
+
+	assert.False(t, trust.Enabled())
+	assert.Equal(t, 1.0, trust.TrustScore("never-seen"))
+}
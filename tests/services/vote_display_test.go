@@ -0,0 +1,46 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"reveal/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVoteDisplayPolicy_HidesFreshContent(t *testing.T) {
+	policy := &services.VoteDisplayPolicy{HideFor: time.Hour, FuzzAmount: 2}
+
+	up, down, hidden := policy.Apply(uuid.New(), time.Now().Add(-10*time.Minute), 12, 3)
+
+	assert.True(t, hidden)
+	assert.Equal(t, int64(0), up)
+	assert.Equal(t, int64(0), down)
+}
+
+func TestVoteDisplayPolicy_FuzzIsDeterministicAndBounded(t *testing.T) {
+	policy := &services.VoteDisplayPolicy{HideFor: time.Hour, FuzzAmount: 2}
+	id := uuid.New()
+	createdAt := time.Now().Add(-2 * time.Hour)
+
+	up1, down1, hidden := policy.Apply(id, createdAt, 40, 5)
+	up2, down2, _ := policy.Apply(id, createdAt, 40, 5)
+
+	assert.False(t, hidden)
+	assert.Equal(t, up1, up2)
+	assert.Equal(t, down1, down2)
+	assert.InDelta(t, 40, up1, 2)
+	assert.InDelta(t, 5, down1, 2)
+}
+
+func TestVoteDisplayPolicy_NeverNegative(t *testing.T) {
+	policy := &services.VoteDisplayPolicy{FuzzAmount: 5}
+
+	for i := 0; i < 50; i++ {
+		up, down, _ := policy.Apply(uuid.New(), time.Now(), 0, 0)
+		assert.GreaterOrEqual(t, up, int64(0))
+		assert.GreaterOrEqual(t, down, int64(0))
+	}
+}
@@ -0,0 +1,157 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"reveal/internal/models"
+	"reveal/internal/services"
+	"reveal/tests/testutil"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type VoteServiceTestSuite struct {
+	suite.Suite
+	service *services.VoteService
+	db      *gorm.DB
+}
+
+func (suite *VoteServiceTestSuite) SetupSuite() {
+	database, err := testutil.OpenDB()
+	suite.Require().NoError(err)
+	suite.db = database
+
+	suite.service = services.NewVoteService()
+}
+
+func (suite *VoteServiceTestSuite) SetupTest() {
+	testutil.Reset(suite.db)
+	suite.db.Exec("DELETE FROM post_vote_rollups")
+	suite.db.Exec("DELETE FROM votes")
+	suite.db.Exec("DELETE FROM comments")
+	suite.db.Exec("DELETE FROM posts")
+}
+
+func (suite *VoteServiceTestSuite) createPost() *models.Post {
+	post := &models.Post{
+		ID:        uuid.New(),
+		Title:     "Title",
+		Content:   "Some content",
+		IPHash:    "author",
+		CreatedAt: time.Now(),
+	}
+	suite.Require().NoError(suite.db.Create(post).Error)
+	return post
+}
+
+func (suite *VoteServiceTestSuite) TestSetPostVote_IsIdempotent() {
+	post := suite.createPost()
+	clientIP := "10.0.0.1"
+
+	suite.NoError(suite.service.SetPostVote(post.ID, models.VoteTypeUpvote, clientIP))
+	suite.NoError(suite.service.SetPostVote(post.ID, models.VoteTypeUpvote, clientIP))
+
+	upvotes, downvotes, userVote, err := suite.service.GetPostVotes(post.ID, clientIP)
+	suite.NoError(err)
+	suite.Equal(int64(1), upvotes)
+	suite.Equal(int64(0), downvotes)
+	suite.Equal(models.VoteTypeUpvote, userVote)
+}
+
+func (suite *VoteServiceTestSuite) TestSetPostVote_ChangesAndClears() {
+	post := suite.createPost()
+	clientIP := "10.0.0.2"
+
+	suite.NoError(suite.service.SetPostVote(post.ID, models.VoteTypeUpvote, clientIP))
+	suite.NoError(suite.service.SetPostVote(post.ID, models.VoteTypeDownvote, clientIP))
+
+	upvotes, downvotes, userVote, _ := suite.service.GetPostVotes(post.ID, clientIP)
+	suite.Equal(int64(0), upvotes)
+	suite.Equal(int64(1), downvotes)
+	suite.Equal(models.VoteTypeDownvote, userVote)
+
+	suite.NoError(suite.service.SetPostVote(post.ID, models.VoteTypeNone, clientIP))
+	suite.NoError(suite.service.SetPostVote(post.ID, models.VoteTypeNone, clientIP))
+
+	_, downvotes, userVote, _ = suite.service.GetPostVotes(post.ID, clientIP)
+	suite.Equal(int64(0), downvotes)
+	suite.Equal("", userVote)
+}
+
+func (suite *VoteServiceTestSuite) TestSetPostVote_InvalidType() {
+	post := suite.createPost()
+
+	err := suite.service.SetPostVote(post.ID, "sideways", "10.0.0.3")
+	suite.Error(err)
+	suite.Contains(err.Error(), "invalid vote type")
+}
+
+func (suite *VoteServiceTestSuite) TestRemoveVoteFromPost_NonExistentPost() {
+	err := suite.service.RemoveVoteFromPost(uuid.New(), "10.0.0.4")
+	suite.Error(err)
+	suite.Contains(err.Error(), "post not found")
+}
+
+func (suite *VoteServiceTestSuite) TestVoteOnPost_StillToggles() {
+	post := suite.createPost()
+	clientIP := "10.0.0.5"
+
+	suite.NoError(suite.service.VoteOnPost(post.ID, models.VoteTypeUpvote, clientIP))
+	suite.NoError(suite.service.VoteOnPost(post.ID, models.VoteTypeUpvote, clientIP))
+
+	upvotes, _, userVote, _ := suite.service.GetPostVotes(post.ID, clientIP)
+	suite.Equal(int64(0), upvotes)
+	suite.Equal("", userVote)
+}
+
+func (suite *VoteServiceTestSuite) TestGetPostVoteHistory_TracksVoteChanges() {
+	post := suite.createPost()
+
+	suite.NoError(suite.service.SetPostVote(post.ID, models.VoteTypeUpvote, "10.0.1.1"))
+	suite.NoError(suite.service.SetPostVote(post.ID, models.VoteTypeUpvote, "10.0.1.2"))
+	suite.NoError(suite.service.SetPostVote(post.ID, models.VoteTypeDownvote, "10.0.1.3"))
+	// Switch one upvote to a downvote and remove another entirely
+	suite.NoError(suite.service.VoteOnPost(post.ID, models.VoteTypeDownvote, "10.0.1.2"))
+	suite.NoError(suite.service.RemoveVoteFromPost(post.ID, "10.0.1.3"))
+
+	history, err := suite.service.GetPostVoteHistory(post.ID, models.VoteBucketHour, 7)
+	suite.NoError(err)
+	suite.NotEmpty(history)
+
+	var upvotes, downvotes int64
+	for _, bucket := range history {
+		upvotes += bucket.Upvotes
+		downvotes += bucket.Downvotes
+	}
+	suite.Equal(int64(1), upvotes)
+	suite.Equal(int64(1), downvotes)
+
+	last := history[len(history)-1]
+	suite.Equal(time.Now().UTC().Truncate(time.Hour), last.BucketStart)
+}
+
+func (suite *VoteServiceTestSuite) TestGetPostVoteHistory_DailyBuckets() {
+	post := suite.createPost()
+	suite.db.Model(post).Update("created_at", time.Now().Add(-72*time.Hour))
+
+	suite.NoError(suite.service.SetPostVote(post.ID, models.VoteTypeUpvote, "10.0.2.1"))
+
+	history, err := suite.service.GetPostVoteHistory(post.ID, models.VoteBucketDay, 30)
+	suite.NoError(err)
+	suite.GreaterOrEqual(len(history), 4)
+	suite.Equal(int64(1), history[len(history)-1].Upvotes)
+}
+
+func (suite *VoteServiceTestSuite) TestGetPostVoteHistory_InvalidBucket() {
+	post := suite.createPost()
+
+	_, err := suite.service.GetPostVoteHistory(post.ID, "week", 7)
+	suite.Error(err)
+}
+
+func TestVoteServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(VoteServiceTestSuite))
+}
@@ -0,0 +1,37 @@
+// Package testutil holds the database fixture shared by the test suites
+package testutil
+
+import (
+	"os"
+
+	"reveal/internal/db"
+	"reveal/internal/services"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// OpenDB opens an in-memory SQLite database with the tables of every model
+// db.Migrate knows, installs it as db.DB and sets the SALT_KEY identities are
+// hashed with
+func OpenDB() (*gorm.DB, error) {
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	if err := database.AutoMigrate(db.Models()...); err != nil {
+		return nil, err
+	}
+
+	os.Setenv("SALT_KEY", "test_salt_key")
+	db.DB = database
+	return database, nil
+}
+
+// Reset installs database as db.DB and clears the state the services keep
+// across requests: rate limit buckets and cached filter rules and flag reasons
+func Reset(database *gorm.DB) {
+	db.DB = database
+	services.ResetRateLimits()
+	services.ResetCaches()
+}
@@ -1,5 +1,6 @@
 import { useState, useEffect } from 'react'
 import axios from 'axios'
+import { challengeHeaders } from '@/lib/challenge'
 import { Card, CardContent, CardHeader, CardTitle } from '@/components/ui/card'
 import { Button } from '@/components/ui/button'
 import { Textarea } from '@/components/ui/textarea'
@@ -60,7 +61,7 @@ export default function Comments({ postId }) {
       
       await axios.post(`/api/posts/${postId}/comments`, {
         content: newComment.trim()
-      })
+      }, { headers: await challengeHeaders() })
       
       setNewComment('')
       setMessage({ type: 'success', text: 'Comment posted anonymously!' })
@@ -1,5 +1,6 @@
 import { useState } from 'react'
 import axios from 'axios'
+import { challengeHeaders } from '@/lib/challenge'
 import { Card, CardContent, CardHeader, CardTitle } from '@/components/ui/card'
 import { Button } from '@/components/ui/button'
 import { Input } from '@/components/ui/input'
@@ -54,7 +55,7 @@ export default function CreatePostForm({ onPostCreated }) {
       await axios.post('/api/posts', {
         title: formData.title.trim(),
         content: formData.content.trim()
-      })
+      }, { headers: await challengeHeaders() })
       
       setFormData({ title: '', content: '' })
       setMessage({ type: 'success', text: 'Your secret has been shared anonymously!' })
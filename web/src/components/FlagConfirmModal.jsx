@@ -1,5 +1,6 @@
 import { useState } from 'react'
 import axios from 'axios'
+import { challengeHeaders } from '@/lib/challenge'
 import { Card, CardContent, CardHeader, CardTitle } from '@/components/ui/card'
 import { Button } from '@/components/ui/button'
 import { Flag, AlertTriangle, CheckCircle2, X } from 'lucide-react'
@@ -23,7 +24,7 @@ export default function FlagConfirmModal({ isOpen, onClose, type, itemId, onSucc
       await axios.post(endpoint, {
         reason: 'reported', // Simple default reason
         details: ''
-      })
+      }, { headers: await challengeHeaders() })
       
       setMessage({ type: 'success', text: 'Reported successfully!' })
       
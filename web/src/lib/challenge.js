@@ -0,0 +1,42 @@
+import axios from 'axios'
+
+// Challenges that expire while being solved are replaced this many times
+const MAX_ATTEMPTS = 3
+
+// solve finds a solution in a background worker, or returns null if the
+// challenge expires first
+function solve(challenge) {
+  return new Promise((resolve, reject) => {
+    const worker = new Worker(new URL('./challenge.worker.js', import.meta.url), { type: 'module' })
+    worker.onmessage = ({ data }) => {
+      worker.terminate()
+      resolve(data.expired ? null : data.solution)
+    }
+    worker.onerror = (event) => {
+      worker.terminate()
+      reject(new Error(event.message || 'Failed to solve challenge'))
+    }
+    worker.postMessage({
+      token: challenge.token,
+      difficulty: challenge.difficulty,
+      expiresAt: challenge.expires_at,
+    })
+  })
+}
+
+// Fetches and solves a proof-of-work challenge when the server requires one,
+// returning the headers to send with a post, comment or flag
+export async function challengeHeaders() {
+  for (let attempt = 0; attempt < MAX_ATTEMPTS; attempt++) {
+    const { data } = await axios.get('/api/challenge')
+    if (!data.required) {
+      return {}
+    }
+
+    const solution = await solve(data)
+    if (solution) {
+      return { 'X-Challenge-Solution': solution }
+    }
+  }
+  throw new Error('Challenge expired before it was solved')
+}
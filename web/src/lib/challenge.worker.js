@@ -0,0 +1,96 @@
+// Solves proof-of-work challenges off the main thread. WebCrypto only offers
+// an async digest, which is far too slow per attempt, so SHA-256 is done here
+// synchronously.
+
+const K = new Uint32Array([
+  0x428a2f98, 0x71374491, 0xb5c0fbcf, 0xe9b5dba5, 0x3956c25b, 0x59f111f1, 0x923f82a4, 0xab1c5ed5,
+  0xd807aa98, 0x12835b01, 0x243185be, 0x550c7dc3, 0x72be5d74, 0x80deb1fe, 0x9bdc06a7, 0xc19bf174,
+  0xe49b69c1, 0xefbe4786, 0x0fc19dc6, 0x240ca1cc, 0x2de92c6f, 0x4a7484aa, 0x5cb0a9dc, 0x76f988da,
+  0x983e5152, 0xa831c66d, 0xb00327c8, 0xbf597fc7, 0xc6e00bf3, 0xd5a79147, 0x06ca6351, 0x14292967,
+  0x27b70a85, 0x2e1b2138, 0x4d2c6dfc, 0x53380d13, 0x650a7354, 0x766a0abb, 0x81c2c92e, 0x92722c85,
+  0xa2bfe8a1, 0xa81a664b, 0xc24b8b70, 0xc76c51a3, 0xd192e819, 0xd6990624, 0xf40e3585, 0x106aa070,
+  0x19a4c116, 0x1e376c08, 0x2748774c, 0x34b0bcb5, 0x391c0cb3, 0x4ed8aa4a, 0x5b9cca4f, 0x682e6ff3,
+  0x748f82ee, 0x78a5636f, 0x84c87814, 0x8cc70208, 0x90befffa, 0xa4506ceb, 0xbef9a3f7, 0xc67178f2,
+])
+
+const W = new Uint32Array(64)
+const H = new Uint32Array(8)
+
+// sha256 hashes an ASCII string, leaving the digest in H
+function sha256(text) {
+  const length = text.length
+  const blocks = ((length + 8) >> 6) + 1
+  const words = new Uint32Array(blocks * 16)
+  for (let i = 0; i < length; i++) {
+    words[i >> 2] |= text.charCodeAt(i) << (24 - (i & 3) * 8)
+  }
+  words[length >> 2] |= 0x80 << (24 - (length & 3) * 8)
+  words[blocks * 16 - 1] = length * 8
+
+  H.set([0x6a09e667, 0xbb67ae85, 0x3c6ef372, 0xa54ff53a, 0x510e527f, 0x9b05688c, 0x1f83d9ab, 0x5be0cd19])
+  for (let block = 0; block < blocks; block++) {
+    for (let t = 0; t < 16; t++) {
+      W[t] = words[block * 16 + t]
+    }
+    for (let t = 16; t < 64; t++) {
+      const w15 = W[t - 15]
+      const w2 = W[t - 2]
+      const s0 = ((w15 >>> 7) | (w15 << 25)) ^ ((w15 >>> 18) | (w15 << 14)) ^ (w15 >>> 3)
+      const s1 = ((w2 >>> 17) | (w2 << 15)) ^ ((w2 >>> 19) | (w2 << 13)) ^ (w2 >>> 10)
+      W[t] = W[t - 16] + s0 + W[t - 7] + s1
+    }
+
+    let [a, b, c, d, e, f, g, h] = H
+    for (let t = 0; t < 64; t++) {
+      const S1 = ((e >>> 6) | (e << 26)) ^ ((e >>> 11) | (e << 21)) ^ ((e >>> 25) | (e << 7))
+      const t1 = (h + S1 + ((e & f) ^ (~e & g)) + K[t] + W[t]) | 0
+      const S0 = ((a >>> 2) | (a << 30)) ^ ((a >>> 13) | (a << 19)) ^ ((a >>> 22) | (a << 10))
+      const t2 = (S0 + ((a & b) ^ (a & c) ^ (b & c))) | 0
+      h = g
+      g = f
+      f = e
+      e = (d + t1) | 0
+      d = c
+      c = b
+      b = a
+      a = (t1 + t2) | 0
+    }
+    H[0] += a
+    H[1] += b
+    H[2] += c
+    H[3] += d
+    H[4] += e
+    H[5] += f
+    H[6] += g
+    H[7] += h
+  }
+}
+
+function leadingZeroBits() {
+  let zeros = 0
+  for (const word of H) {
+    if (word !== 0) {
+      return zeros + Math.clz32(word)
+    }
+    zeros += 32
+  }
+  return zeros
+}
+
+// Receives { token, difficulty, expiresAt } and answers with { solution }, or
+// with { expired: true } once the challenge can no longer be used
+self.onmessage = ({ data }) => {
+  const expiresAt = new Date(data.expiresAt).getTime()
+  for (let counter = 0; ; counter++) {
+    if (counter % 4096 === 0 && Date.now() >= expiresAt) {
+      self.postMessage({ expired: true })
+      return
+    }
+    const solution = `${data.token}:${counter}`
+    sha256(solution)
+    if (leadingZeroBits() >= data.difficulty) {
+      self.postMessage({ solution })
+      return
+    }
+  }
+}